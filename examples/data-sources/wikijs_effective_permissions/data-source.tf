@@ -0,0 +1,16 @@
+data "wikijs_group" "hr" {
+  name = "HR"
+}
+
+# Why can't HR edit the policies?
+data "wikijs_effective_permissions" "hr_policies" {
+  group_ids = [data.wikijs_group.hr.id]
+  path      = "hr/policies"
+}
+
+output "hr_policies_write" {
+  value = [
+    for decision in data.wikijs_effective_permissions.hr_policies.decisions :
+    decision.reason if decision.permission == "write:pages"
+  ]
+}
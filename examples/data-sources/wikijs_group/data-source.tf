@@ -0,0 +1,22 @@
+data "wikijs_group" "support" {
+  name = "Support"
+}
+
+# Mirror the page rules of a group owned by another team
+resource "wikijs_group_resource" "support_contractors" {
+  name              = "Support contractors"
+  permissions       = data.wikijs_group.support.permissions
+  redirect_on_login = data.wikijs_group.support.redirect_on_login
+
+  dynamic "page_rules" {
+    for_each = data.wikijs_group.support.page_rules
+    content {
+      id      = page_rules.value.id
+      deny    = page_rules.value.deny
+      match   = page_rules.value.match
+      roles   = page_rules.value.roles
+      path    = page_rules.value.path
+      locales = page_rules.value.locales
+    }
+  }
+}
@@ -0,0 +1,14 @@
+# The ids of the groups allowed to edit pages, e.g. for the visibility of a navigation entry
+data "wikijs_groups" "editors" {
+  is_system      = false
+  has_permission = "write:pages"
+}
+
+# A group looked up by name
+data "wikijs_groups" "support" {
+  name_regex = "^Support$"
+}
+
+output "support_group_id" {
+  value = one(data.wikijs_groups.support.groups[*].id)
+}
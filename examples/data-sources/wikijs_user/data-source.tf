@@ -0,0 +1,8 @@
+data "wikijs_user" "alice" {
+  email = "alice@example.com"
+}
+
+resource "wikijs_group_member" "alice_editor" {
+  group_id = wikijs_group_resource.editors.id
+  user_id  = data.wikijs_user.alice.id
+}
@@ -0,0 +1,20 @@
+# Everyone who logs in with single sign-on is an employee
+data "wikijs_users" "sso" {
+  provider_key = "oidc"
+  is_active    = true
+}
+
+resource "wikijs_group_membership" "employees" {
+  group_id = wikijs_group_resource.employees.id
+  user_ids = data.wikijs_users.sso.ids
+}
+
+# Accounts nobody used for 90 days
+data "wikijs_users" "stale" {
+  last_login_older_than = "2160h"
+  is_system             = false
+}
+
+output "stale_users" {
+  value = [for user in data.wikijs_users.stale.users : user.email]
+}
@@ -1,6 +1,27 @@
 provider "wikijs" {
   host = "https://your-wiki-url.com" # Or pass as env var WIKIJS_HOST
   #    token = wikijs_api_token # or pass as env var WIKIJS_TOKEN
+
+  # Alternatively, log in when the API is disabled. Takes precedence over token.
+  #    username = "terraform@example.com" # or pass as env var WIKIJS_USERNAME
+  #    password = wikijs_password         # or pass as env var WIKIJS_PASSWORD
+  #    strategy = "local"                 # key of the authentication strategy, e.g. an LDAP strategy
+
+  # Optional: connection settings for instances behind a private CA, mutual TLS or a proxy
+  #    ca_cert_file    = "/etc/ssl/certs/internal-ca.pem"
+  #    client_cert     = file("client.pem")
+  #    client_key      = file("client-key.pem")
+  #    proxy_url       = "http://proxy.example.com:3128"
+  #    request_timeout = 30
+  #    headers = {
+  #      "X-Gateway-Key" = var.gateway_key
+  #    }
+
+  # Optional: retry transient failures (HTTP 429/502/503/504) and throttle requests
+  retry_max           = 4
+  retry_wait_min      = 1
+  retry_wait_max      = 30
+  requests_per_second = 10
 }
 
 terraform {
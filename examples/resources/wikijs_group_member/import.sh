@@ -0,0 +1,2 @@
+# A member is imported by the id of the group and the id of the user
+terraform import wikijs_group_member.alice 3:7
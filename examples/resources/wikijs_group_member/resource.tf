@@ -0,0 +1,4 @@
+resource "wikijs_group_member" "alice" {
+  group_id = 3
+  user_id  = 7
+}
@@ -0,0 +1,2 @@
+# The membership is imported by the id of the group
+terraform import wikijs_group_membership.my_group 3
@@ -0,0 +1,10 @@
+resource "wikijs_group_membership" "my_group" {
+  group_id = wikijs_group_resource.my_group.id
+  user_ids = [3, 4, 7]
+
+  # Users are assigned one at a time, large groups may need longer than the defaults.
+  timeouts {
+    create = "30m"
+    update = "30m"
+  }
+}
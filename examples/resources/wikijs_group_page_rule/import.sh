@@ -0,0 +1,2 @@
+# A page rule is imported by the id of the group and the id of the rule
+terraform import wikijs_group_page_rule.payments 3:e3b0c44298fc
@@ -0,0 +1,17 @@
+# The group leaves alone the rules it does not declare itself
+resource "wikijs_group_resource" "engineering" {
+  name                       = "engineering"
+  permissions                = ["read:pages", "write:pages"]
+  redirect_on_login          = "/"
+  ignore_external_page_rules = true
+}
+
+# Each team grants access to its own section of the wiki
+resource "wikijs_group_page_rule" "payments" {
+  group_id = wikijs_group_resource.engineering.id
+  deny     = false
+  match    = "START"
+  roles    = ["read:pages", "write:pages"]
+  path     = "teams/payments"
+  locales  = []
+}
@@ -0,0 +1,5 @@
+# Groups can be imported by id
+terraform import wikijs_group_resource.my_group 3
+
+# or by name. Wiki.js allows several groups with the same name, these must be imported by id.
+terraform import wikijs_group_resource.administrators "name:Administrators"
@@ -0,0 +1,6 @@
+# With Terraform 1.5 or later, groups can also be adopted with an import block. Run
+# `terraform plan -generate-config-out=generated.tf` to write the configuration of the imported group.
+import {
+  to = wikijs_group_resource.guests
+  id = "name:Guests"
+}
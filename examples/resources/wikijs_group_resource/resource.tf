@@ -3,11 +3,17 @@ resource "wikijs_group_resource" "my_group" {
   permissions       = ["read:pages", "write:pages"]
   redirect_on_login = ""
   page_rules {
-    id      = "page_rule_1"
     deny    = false
     match   = "START"
     path    = "my_path"
     roles   = ["read:pages"]
     locales = []
   }
-}
\ No newline at end of file
+}
+# Editors of the wiki, who may also edit the navigation
+resource "wikijs_group_resource" "editors" {
+  name              = "Editors"
+  preset            = "editor"
+  permissions       = ["manage:navigation"]
+  redirect_on_login = "/"
+}
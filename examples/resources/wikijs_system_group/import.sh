@@ -0,0 +1,2 @@
+# System groups are imported by id: 1 for Administrators, 2 for Guests
+terraform import wikijs_system_group.guests 2
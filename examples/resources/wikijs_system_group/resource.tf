@@ -0,0 +1,13 @@
+# Let anonymous visitors read the public part of the wiki only
+resource "wikijs_system_group" "guests" {
+  group_id = 2
+  preset   = "reader"
+
+  page_rules {
+    deny    = false
+    match   = "START"
+    roles   = ["read:pages", "read:assets", "read:comments"]
+    path    = "public"
+    locales = []
+  }
+}
@@ -0,0 +1,5 @@
+# Users can be imported by id
+terraform import wikijs_user.alice 3
+
+# or by email.
+terraform import wikijs_user.alice "email:alice@example.com"
@@ -0,0 +1,17 @@
+resource "wikijs_user" "alice" {
+  email                = "alice@example.com"
+  name                 = "Alice"
+  password             = var.alice_initial_password
+  must_change_password = true
+  group_ids            = [wikijs_group_resource.editors.id]
+  job_title            = "Technical writer"
+  timezone             = "Europe/London"
+}
+
+# A user logging in through an external authentication provider has no password.
+resource "wikijs_user" "bob" {
+  email           = "bob@example.com"
+  name            = "Bob"
+  provider_key    = "oidc"
+  replace_user_id = wikijs_user.alice.id
+}
@@ -37,7 +37,7 @@ func main() {
 
 		ProviderAddr: "terraform.local/local/terraform-provider-wikijs",
 
-		ProviderFunc: wikijs.New(version),
+		GRPCProviderFunc: wikijs.NewGRPCProviderServer(version),
 	}
 
 	plugin.Serve(opts)
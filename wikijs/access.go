@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"fmt"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	"golang.org/x/exp/slices"
+	"strings"
+	"unicode/utf16"
+)
+
+// accessPage is a page whose access is checked.
+type accessPage struct {
+	// path is the path of the page, without a leading /.
+	path   string
+	locale string
+	tags   []string
+}
+
+// accessDecision is the outcome of checkAccess for a permission.
+type accessDecision struct {
+	permission string
+	granted    bool
+	// groupID is the group that decided, with rule when a page rule did. Both are empty when no group has the
+	// permission.
+	groupID GroupID
+	rule    *wjSchema.PageRule
+	reason  string
+}
+
+// pageRuleCheckState is the checkState of Wiki.js: the page rule deciding so far.
+type pageRuleCheckState struct {
+	deny        bool
+	match       string
+	specificity string
+	groupID     GroupID
+	rule        *wjSchema.PageRule
+}
+
+// pageRuleHigherPriority lists for each match the matches its rules cannot override at the same specificity.
+var pageRuleHigherPriority = map[wjSchema.PageRuleMatch][]string{
+	wjSchema.PageRuleMatchStart: {"END", "REGEX", "EXACT", "TAG"},
+	wjSchema.PageRuleMatchEnd:   {"REGEX", "EXACT", "TAG"},
+	wjSchema.PageRuleMatchRegex: {"EXACT", "TAG"},
+	wjSchema.PageRuleMatchTag:   {"EXACT"},
+	wjSchema.PageRuleMatchExact: {},
+}
+
+// checkAccess tells whether a user in groups has permission on page, following WIKI.auth.checkAccess of Wiki.js
+// 2.x: manage:system grants everything; otherwise a permission must be one of the permissions of the groups, and
+// be granted by the page rules that match the page. The rule with the longest path decides, at the same length
+// EXACT wins over TAG, TAG over REGEX, REGEX over END and END over START, and at the same match a deny wins
+// over an allow. Rules restricted to other locales are ignored.
+func checkAccess(groups []wjSchema.Group, permission string, page accessPage) (accessDecision, error) {
+	decision := accessDecision{permission: permission}
+	var granting *wjSchema.Group
+	for i, g := range groups {
+		permissions := gqlcStringArrayToStringArray(g.Permissions)
+		if slices.Contains(permissions, "manage:system") {
+			decision.granted = true
+			decision.groupID = GroupID(g.Id)
+			decision.reason = fmt.Sprintf("granted by the manage:system permission of group %d", g.Id)
+			return decision, nil
+		}
+		if granting == nil && slices.Contains(permissions, permission) {
+			granting = &groups[i]
+		}
+	}
+	if granting == nil {
+		decision.reason = "not in the permissions of the groups"
+		return decision, nil
+	}
+
+	state := pageRuleCheckState{}
+	for _, g := range groups {
+		for i := range g.PageRules {
+			rule := &g.PageRules[i]
+			if len(rule.Locales) > 0 && !slices.Contains(gqlcStringArrayToStringArray(rule.Locales), page.locale) {
+				continue
+			}
+			if !slices.Contains(gqlcStringArrayToStringArray(rule.Roles), permission) {
+				continue
+			}
+			matches, err := pageRuleMatchesPage(*rule, page)
+			if err != nil {
+				return decision, fmt.Errorf("page rule %q of group %d: %w", rule.Id, g.Id, err)
+			}
+			if matches {
+				state = applyPageRuleSpecificity(state, GroupID(g.Id), rule)
+			}
+		}
+	}
+
+	if state.rule == nil {
+		decision.groupID = GroupID(granting.Id)
+		decision.reason = "no page rule of the groups matches the page"
+		return decision, nil
+	}
+	decision.granted = !state.deny
+	decision.groupID = state.groupID
+	decision.rule = state.rule
+	verb := "granted"
+	if state.deny {
+		verb = "denied"
+	}
+	decision.reason = fmt.Sprintf("%s by page rule %q of group %d, %s %q", verb, state.rule.Id, state.groupID,
+		state.rule.Match, state.rule.Path)
+	return decision, nil
+}
+
+// pageRuleMatchesPage tells whether the path of a rule matches a page.
+func pageRuleMatchesPage(rule wjSchema.PageRule, page accessPage) (bool, error) {
+	path := string(rule.Path)
+	switch rule.Match {
+	case wjSchema.PageRuleMatchStart:
+		return strings.HasPrefix("/"+page.path, "/"+path), nil
+	case wjSchema.PageRuleMatchEnd:
+		return strings.HasSuffix(page.path, path), nil
+	case wjSchema.PageRuleMatchRegex:
+		re, err := compileJSRegex(path)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(page.path), nil
+	case wjSchema.PageRuleMatchTag:
+		return slices.Contains(page.tags, path), nil
+	case wjSchema.PageRuleMatchExact:
+		return page.path == path, nil
+	}
+	return false, nil
+}
+
+// applyPageRuleSpecificity is _applyPageRuleSpecificity of Wiki.js: it returns the state once rule, which matches
+// the page, is taken into account.
+func applyPageRuleSpecificity(state pageRuleCheckState, groupID GroupID, rule *wjSchema.PageRule) pageRuleCheckState {
+	// Wiki.js compares the lengths of JavaScript strings, in UTF-16 code units.
+	length := len(utf16.Encode([]rune(string(rule.Path))))
+	specificity := len(utf16.Encode([]rune(state.specificity)))
+	switch {
+	case length == specificity:
+		if slices.Contains(pageRuleHigherPriority[rule.Match], state.match) {
+			return state
+		}
+		if state.match == string(rule.Match) && state.deny && !bool(rule.Deny) {
+			return state
+		}
+	case length < specificity:
+		return state
+	}
+	return pageRuleCheckState{
+		deny:        bool(rule.Deny),
+		match:       string(rule.Match),
+		specificity: string(rule.Path),
+		groupID:     groupID,
+		rule:        rule,
+	}
+}
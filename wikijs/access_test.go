@@ -0,0 +1,335 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"strings"
+	"testing"
+
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+)
+
+// testAccessGroup returns a group with the permissions and the page rules.
+func testAccessGroup(id int, permissions []string, rules ...wjSchema.PageRule) wjSchema.Group {
+	return wjSchema.Group{
+		Id:          gqlc.Int(id),
+		Permissions: stringArrayToGqlcStringArray(permissions),
+		PageRules:   rules,
+	}
+}
+
+// testAccessRule returns a page rule applying to the roles, and to the locales when there are any.
+func testAccessRule(id string, deny bool, match wjSchema.PageRuleMatch, path string, roles []string, locales ...string) wjSchema.PageRule {
+	return wjSchema.PageRule{
+		Id:      gqlc.String(id),
+		Deny:    gqlc.Boolean(deny),
+		Match:   match,
+		Roles:   stringArrayToGqlcStringArray(roles),
+		Path:    gqlc.String(path),
+		Locales: stringArrayToGqlcStringArray(locales),
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	const (
+		start = wjSchema.PageRuleMatchStart
+		end   = wjSchema.PageRuleMatchEnd
+		exact = wjSchema.PageRuleMatchExact
+		regex = wjSchema.PageRuleMatchRegex
+		tag   = wjSchema.PageRuleMatchTag
+	)
+	read := []string{"read:pages"}
+	readWrite := []string{"read:pages", "write:pages"}
+	write := []string{"write:pages"}
+
+	for _, tc := range []struct {
+		name       string
+		groups     []wjSchema.Group
+		permission string
+		page       accessPage
+		granted    bool
+		// rule is the id of the deciding rule, reason a part of the reason.
+		rule   string
+		reason string
+	}{
+		{
+			name:       "manage:system grants everything",
+			groups:     []wjSchema.Group{testAccessGroup(1, []string{"manage:system"}, testAccessRule("no", true, start, "", readWrite))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			reason:     "manage:system permission of group 1",
+		},
+		{
+			name:       "permission missing from the groups",
+			groups:     []wjSchema.Group{testAccessGroup(1, read, testAccessRule("all", false, start, "", readWrite))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			reason:     "not in the permissions of the groups",
+		},
+		{
+			name:       "no rule matches",
+			groups:     []wjSchema.Group{testAccessGroup(1, readWrite, testAccessRule("docs", false, start, "docs", readWrite))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			reason:     "no page rule of the groups matches the page",
+		},
+		{
+			name:       "root START rule",
+			groups:     []wjSchema.Group{testAccessGroup(1, read, testAccessRule("default", false, start, "", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "default",
+			reason:     `granted by page rule "default" of group 1, START ""`,
+		},
+		{
+			name:       "START is a plain prefix, not a folder",
+			groups:     []wjSchema.Group{testAccessGroup(1, read, testAccessRule("hr", false, start, "hr", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr-archive/2019", locale: "en"},
+			granted:    true,
+			rule:       "hr",
+		},
+		{
+			name:       "rule for other roles",
+			groups:     []wjSchema.Group{testAccessGroup(1, readWrite, testAccessRule("read", false, start, "", read))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			reason:     "no page rule",
+		},
+		{
+			name: "longer path wins over deny",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("deny-hr", true, start, "hr", write),
+				testAccessRule("allow-policies", false, start, "hr/policies", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies/leave", locale: "en"},
+			granted:    true,
+			rule:       "allow-policies",
+		},
+		{
+			name: "shorter path after a longer one",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("deny-policies", true, start, "hr/policies", write),
+				testAccessRule("allow-all", false, start, "", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			rule:       "deny-policies",
+			reason:     `denied by page rule "deny-policies" of group 1, START "hr/policies"`,
+		},
+		{
+			name: "deny wins over a later allow of the same match and length",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("deny", true, start, "hr", write),
+				testAccessRule("allow", false, start, "hr", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			rule:       "deny",
+		},
+		{
+			name: "deny overrides an earlier allow of the same match and length",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("allow", false, start, "hr", write),
+				testAccessRule("deny", true, start, "hr", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			rule:       "deny",
+		},
+		{
+			name: "later allow overrides an earlier allow",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("first", false, start, "hr", write),
+				testAccessRule("second", false, start, "hr", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "second",
+		},
+		{
+			name: "EXACT wins over a later START of the same length",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("exact", false, exact, "hr/policies", write),
+				testAccessRule("start", true, start, "hr/policies", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "exact",
+		},
+		{
+			name: "EXACT overrides an earlier START of the same length",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("start", true, start, "hr/policies", write),
+				testAccessRule("exact", false, exact, "hr/policies", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "exact",
+		},
+		{
+			name: "EXACT only matches the page itself",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("exact", false, exact, "hr", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			reason:     "no page rule",
+		},
+		{
+			name: "START does not override an earlier END of the same length",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("end", false, end, "licies", write),
+				testAccessRule("start", true, start, "hr/pol", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "end",
+		},
+		{
+			name: "END overrides an earlier START of the same length",
+			groups: []wjSchema.Group{testAccessGroup(1, write,
+				testAccessRule("start", false, start, "hr/pol", write),
+				testAccessRule("end", true, end, "licies", write))},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			rule:       "end",
+		},
+		{
+			name: "END matches the end of the path",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("drafts", true, end, "/draft", read),
+				testAccessRule("all", false, start, "", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies/draft", locale: "en"},
+			rule:       "drafts",
+		},
+		{
+			name: "REGEX",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("archives", true, regex, `^[a-z]+/archive/\d{4}$`, read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/archive/2019", locale: "en"},
+			rule:       "archives",
+		},
+		{
+			name: "REGEX not matching",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("archives", true, regex, `^[a-z]+/archive/\d{4}$`, read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/archive/latest", locale: "en"},
+			reason:     "no page rule",
+		},
+		{
+			name: "REGEX does not override an earlier TAG of the same length",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("tag", false, tag, "hr", read),
+				testAccessRule("regex", true, regex, "^h", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en", tags: []string{"hr"}},
+			granted:    true,
+			rule:       "tag",
+		},
+		{
+			name: "TAG overrides an earlier REGEX of the same length",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("regex", false, regex, "^h", read),
+				testAccessRule("tag", true, tag, "hr", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en", tags: []string{"hr"}},
+			rule:       "tag",
+		},
+		{
+			name: "TAG without the tag",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("tag", false, tag, "hr", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en", tags: []string{"policies"}},
+			reason:     "no page rule",
+		},
+		{
+			name: "rule of another locale",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("all", false, start, "", read),
+				testAccessRule("fr", true, start, "hr", read, "fr"))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "all",
+		},
+		{
+			name: "rule of the locale",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("all", false, start, "", read),
+				testAccessRule("fr", true, start, "hr", read, "de", "fr"))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "fr"},
+			rule:       "fr",
+		},
+		{
+			name: "rules of every group",
+			groups: []wjSchema.Group{
+				testAccessGroup(3, write, testAccessRule("allow", false, start, "", write)),
+				testAccessGroup(5, read, testAccessRule("deny", true, start, "hr", write)),
+			},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			rule:       "deny",
+			reason:     `denied by page rule "deny" of group 5`,
+		},
+		{
+			name: "permission of a group without rules",
+			groups: []wjSchema.Group{
+				testAccessGroup(3, write),
+				testAccessGroup(5, read, testAccessRule("write", false, start, "hr", write)),
+			},
+			permission: "write:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "write",
+		},
+		{
+			name: "lengths counted in UTF-16 code units",
+			groups: []wjSchema.Group{testAccessGroup(1, read,
+				testAccessRule("exact", false, exact, "éé", read),
+				testAccessRule("regex", true, regex, "^..$", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "éé", locale: "en"},
+			rule:       "regex",
+		},
+		{
+			name: "lookaround of a rule for other roles",
+			groups: []wjSchema.Group{testAccessGroup(1, readWrite,
+				testAccessRule("lookahead", false, regex, "^hr/(?!private)", write),
+				testAccessRule("all", false, start, "", read))},
+			permission: "read:pages",
+			page:       accessPage{path: "hr/policies", locale: "en"},
+			granted:    true,
+			rule:       "all",
+		},
+	} {
+		decision, err := checkAccess(tc.groups, tc.permission, tc.page)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		rule := ""
+		if decision.rule != nil {
+			rule = string(decision.rule.Id)
+		}
+		if decision.granted != tc.granted || rule != tc.rule || !strings.Contains(decision.reason, tc.reason) {
+			t.Errorf("%s: expected granted=%t by %q (%s), got granted=%t by %q (%s)", tc.name, tc.granted, tc.rule,
+				tc.reason, decision.granted, rule, decision.reason)
+		}
+	}
+}
+
+func TestCheckAccessUnsupportedRegex(t *testing.T) {
+	groups := []wjSchema.Group{testAccessGroup(1, []string{"read:pages"},
+		testAccessRule("lookahead", false, wjSchema.PageRuleMatchRegex, "^hr/(?!private)", []string{"read:pages"}))}
+	_, err := checkAccess(groups, "read:pages", accessPage{path: "hr/policies", locale: "en"})
+	if err == nil || !strings.Contains(err.Error(), `page rule "lookahead" of group 1: lookarounds`) {
+		t.Fatalf("expected the rule to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+// Package apierror turns the two ways Wiki.js reports failures, the GraphQL `errors` array and the
+// `responseResult` payload returned by mutations, into typed Go errors.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+)
+
+// Kind classifies an Error by what the caller can do about it.
+type Kind int
+
+const (
+	// ServerError covers everything that is not one of the more precise kinds, including transport failures.
+	ServerError Kind = iota
+	// NotFound means the object does not exist in Wiki.js.
+	NotFound
+	// Forbidden means the credentials are invalid or lack the required permissions.
+	Forbidden
+	// Validation means Wiki.js rejected the input.
+	Validation
+	// Conflict means the request clashes with existing data, e.g. a duplicate.
+	Conflict
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "not found"
+	case Forbidden:
+		return "forbidden"
+	case Validation:
+		return "validation error"
+	case Conflict:
+		return "conflict"
+	default:
+		return "server error"
+	}
+}
+
+// Error is a failure reported by Wiki.js.
+type Error struct {
+	Kind Kind
+	// Code is the Wiki.js error code, or the HTTP status code for transport failures. Zero if unknown.
+	Code int
+	// Slug is the Wiki.js error name, such as "UserNotFound", or the GraphQL error code. Empty if unknown.
+	Slug    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Slug != "" && e.Code != 0:
+		return fmt.Sprintf("wikijs %s: %s (%s, code %d)", e.Kind, e.Message, e.Slug, e.Code)
+	case e.Slug != "":
+		return fmt.Sprintf("wikijs %s: %s (%s)", e.Kind, e.Message, e.Slug)
+	case e.Code != 0:
+		return fmt.Sprintf("wikijs %s: %s (code %d)", e.Kind, e.Message, e.Code)
+	default:
+		return fmt.Sprintf("wikijs %s: %s", e.Kind, e.Message)
+	}
+}
+
+// Is reports whether err is, or wraps, an Error of the given kind.
+func Is(err error, kind Kind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
+
+// New returns an Error of the given kind.
+func New(kind Kind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// FromResponse returns nil if status reports success, and an Error describing the failure otherwise.
+func FromResponse(status schema.ResponseStatus) error {
+	if status.Succeeded {
+		return nil
+	}
+	message := string(status.Message)
+	if message == "" {
+		message = "the request was not successful"
+	}
+	return &Error{
+		Kind:    kindFromSlug(string(status.Slug)),
+		Code:    int(status.ErrorCode),
+		Slug:    string(status.Slug),
+		Message: message,
+	}
+}
+
+// FromGraphQL converts the errors returned by the graphql client into an Error. Errors of other types, such as
+// context cancellation, are returned unchanged.
+func FromGraphQL(err error) error {
+	var gqlErrs gqlc.Errors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) == 0 {
+		return err
+	}
+	first := gqlErrs[0]
+	messages := make([]string, len(gqlErrs))
+	for i, e := range gqlErrs {
+		messages[i] = e.Message
+	}
+	out := &Error{Kind: ServerError, Message: strings.Join(messages, "; ")}
+
+	code, _ := first.Extensions["code"].(string)
+	switch code {
+	case gqlc.ErrRequestError:
+		// Transport failures carry the HTTP status at the start of the message, e.g. "503 Service Unavailable".
+		if status, err := strconv.Atoi(strings.SplitN(first.Message, " ", 2)[0]); err == nil {
+			out.Code = status
+			out.Kind = kindFromHTTPStatus(status)
+		}
+		return out
+	case gqlc.ErrJsonEncode, gqlc.ErrJsonDecode, gqlc.ErrGraphQLEncode, gqlc.ErrGraphQLDecode:
+		return out
+	case "":
+	default:
+		out.Slug = code
+	}
+
+	// Wiki.js errors carry their code and name in the exception, under the generic code of Apollo.
+	if exception, ok := first.Extensions["exception"].(map[string]interface{}); ok {
+		if c, ok := exception["code"].(float64); ok {
+			out.Code = int(c)
+		}
+		if name, ok := exception["name"].(string); ok && name != "" && name != "Error" {
+			out.Slug = name
+		}
+	}
+	out.Kind = kindFromGraphQL(code, first.Message)
+	if out.Kind == ServerError && out.Slug != "" {
+		out.Kind = kindFromSlug(out.Slug)
+	}
+	return out
+}
+
+// kindFromGraphQL classifies a GraphQL error by its Apollo error code, falling back to the exact messages of the
+// errors Wiki.js raises without a code. Other messages are not guessed at: callers remove resources from state
+// on NotFound, which a message about another missing object, such as a locale, must not cause.
+func kindFromGraphQL(code, message string) Kind {
+	switch code {
+	case "FORBIDDEN", "UNAUTHENTICATED":
+		return Forbidden
+	case "BAD_USER_INPUT", "GRAPHQL_VALIDATION_FAILED", "GRAPHQL_PARSE_FAILED":
+		return Validation
+	}
+	switch message {
+	case "Forbidden":
+		// The auth directive of Wiki.js, for a caller lacking a permission.
+		return Forbidden
+	case "Invalid Group ID", "Invalid User ID":
+		// groups.assignUser and groups.unassignUser, for a group or a user that does not exist.
+		return NotFound
+	case "User is already assigned to group.":
+		return Conflict
+	}
+	return ServerError
+}
+
+// kindFromSlug classifies the error names Wiki.js reports in responseResult.slug, such as "UserNotFound",
+// "PageDuplicateCreate" or "InputInvalid".
+func kindFromSlug(slug string) Kind {
+	switch {
+	case strings.HasSuffix(slug, "NotFound"):
+		return NotFound
+	case strings.HasSuffix(slug, "Forbidden") || strings.HasSuffix(slug, "Protected") ||
+		slug == "AuthRequired" || slug == "AuthLoginFailed" || slug == "AuthAccountBanned" ||
+		slug == "AuthAccountNotVerified":
+		return Forbidden
+	case strings.Contains(slug, "Duplicate") || strings.HasSuffix(slug, "Exists") ||
+		strings.HasSuffix(slug, "ForeignConstraint"):
+		return Conflict
+	case strings.HasSuffix(slug, "Invalid") || strings.Contains(slug, "Missing") ||
+		strings.Contains(slug, "Empty") || strings.Contains(slug, "Illegal"):
+		return Validation
+	}
+	return ServerError
+}
+
+// kindFromHTTPStatus classifies transport failures. A 404 means the GraphQL endpoint itself is missing, not an
+// object, so it deliberately maps to ServerError: callers remove resources from state on NotFound.
+func kindFromHTTPStatus(status int) Kind {
+	switch status {
+	case 401, 403:
+		return Forbidden
+	case 400, 422:
+		return Validation
+	case 409:
+		return Conflict
+	}
+	return ServerError
+}
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+)
+
+func TestFromResponse(t *testing.T) {
+	if err := FromResponse(schema.ResponseStatus{Succeeded: true}); err != nil {
+		t.Fatalf("expected nil for a successful response, got %v", err)
+	}
+
+	cases := []struct {
+		slug string
+		kind Kind
+	}{
+		{"UserNotFound", NotFound},
+		{"PageNotFound", NotFound},
+		{"AuthRequired", Forbidden},
+		{"CommentPostForbidden", Forbidden},
+		{"UserDeleteProtected", Forbidden},
+		{"AuthAccountAlreadyExists", Conflict},
+		{"PageDuplicateCreate", Conflict},
+		{"UserDeleteForeignConstraint", Conflict},
+		{"InputInvalid", Validation},
+		{"PageEmptyContent", Validation},
+		{"PageIllegalPath", Validation},
+		{"CommentContentMissing", Validation},
+		{"Error", ServerError},
+		{"", ServerError},
+	}
+	for _, tc := range cases {
+		err := FromResponse(schema.ResponseStatus{Succeeded: false, ErrorCode: 1012, Slug: gqlc.String(tc.slug), Message: "rejected"})
+		if !Is(err, tc.kind) {
+			t.Errorf("slug %q: expected %s, got %v", tc.slug, tc.kind, err)
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Code != 1012 || e.Slug != tc.slug || e.Message != "rejected" {
+			t.Errorf("slug %q: unexpected error %#v", tc.slug, e)
+		}
+	}
+}
+
+func TestFromGraphQL(t *testing.T) {
+	cases := []struct {
+		name string
+		err  gqlc.Error
+		kind Kind
+		code int
+		slug string
+	}{
+		{
+			name: "wikijs auth directive",
+			err:  gqlc.Error{Message: "Forbidden"},
+			kind: Forbidden,
+		},
+		{
+			name: "apollo forbidden",
+			err:  gqlc.Error{Message: "You are not allowed", Extensions: map[string]interface{}{"code": "FORBIDDEN"}},
+			kind: Forbidden,
+			slug: "FORBIDDEN",
+		},
+		{
+			name: "apollo bad input",
+			err:  gqlc.Error{Message: "Variable \"$id\" got invalid value", Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"}},
+			kind: Validation,
+			slug: "BAD_USER_INPUT",
+		},
+		{
+			name: "wikijs error with exception",
+			err: gqlc.Error{Message: "This user does not exist.", Extensions: map[string]interface{}{
+				"code":      "INTERNAL_SERVER_ERROR",
+				"exception": map[string]interface{}{"code": float64(1016), "name": "UserNotFound"},
+			}},
+			kind: NotFound,
+			code: 1016,
+			slug: "UserNotFound",
+		},
+		{
+			name: "wikijs auth required",
+			err: gqlc.Error{Message: "You must be authenticated to access this resource.", Extensions: map[string]interface{}{
+				"code":      "INTERNAL_SERVER_ERROR",
+				"exception": map[string]interface{}{"code": float64(1019), "name": "AuthRequired"},
+			}},
+			kind: Forbidden,
+			code: 1019,
+			slug: "AuthRequired",
+		},
+		{
+			name: "wikijs error classified by exception name",
+			err: gqlc.Error{Message: "Cannot delete user", Extensions: map[string]interface{}{
+				"exception": map[string]interface{}{"code": float64(1018), "name": "UserDeleteProtected"},
+			}},
+			kind: Forbidden,
+			code: 1018,
+			slug: "UserDeleteProtected",
+		},
+		{
+			name: "invalid group id",
+			err:  gqlc.Error{Message: "Invalid Group ID"},
+			kind: NotFound,
+		},
+		{
+			name: "invalid user id",
+			err:  gqlc.Error{Message: "Invalid User ID"},
+			kind: NotFound,
+		},
+		{
+			name: "another missing object",
+			err:  gqlc.Error{Message: "Locale xx not found"},
+			kind: ServerError,
+		},
+		{
+			name: "uncoded message about a missing object",
+			err:  gqlc.Error{Message: "This user does not exist."},
+			kind: ServerError,
+		},
+		{
+			name: "permission message other than the auth directive",
+			err:  gqlc.Error{Message: "You are not authorized to delete this group"},
+			kind: ServerError,
+		},
+		{
+			name: "already assigned",
+			err:  gqlc.Error{Message: "User is already assigned to group."},
+			kind: Conflict,
+		},
+		{
+			name: "http service unavailable",
+			err:  gqlc.Error{Message: "503 Service Unavailable; body: \"\"", Extensions: map[string]interface{}{"code": gqlc.ErrRequestError}},
+			kind: ServerError,
+			code: 503,
+		},
+		{
+			name: "http unauthorized",
+			err:  gqlc.Error{Message: "401 Unauthorized; body: \"\"", Extensions: map[string]interface{}{"code": gqlc.ErrRequestError}},
+			kind: Forbidden,
+			code: 401,
+		},
+		{
+			name: "http not found is not an object lookup",
+			err:  gqlc.Error{Message: "404 Not Found; body: \"\"", Extensions: map[string]interface{}{"code": gqlc.ErrRequestError}},
+			kind: ServerError,
+			code: 404,
+		},
+		{
+			name: "connection refused",
+			err:  gqlc.Error{Message: "Post \"http://localhost\": connection refused", Extensions: map[string]interface{}{"code": gqlc.ErrRequestError}},
+			kind: ServerError,
+		},
+		{
+			name: "decode failure",
+			err:  gqlc.Error{Message: "struct field for \"x\" doesn't exist", Extensions: map[string]interface{}{"code": gqlc.ErrGraphQLDecode}},
+			kind: ServerError,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := FromGraphQL(gqlc.Errors{tc.err})
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected an *Error, got %T", err)
+			}
+			if e.Kind != tc.kind || e.Code != tc.code || e.Slug != tc.slug {
+				t.Fatalf("got kind %s, code %d, slug %q; want %s, %d, %q", e.Kind, e.Code, e.Slug, tc.kind, tc.code, tc.slug)
+			}
+		})
+	}
+}
+
+func TestFromGraphQLPassesThroughOtherErrors(t *testing.T) {
+	if err := FromGraphQL(nil); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	wrapped := fmt.Errorf("interrupted: %w", context.Canceled)
+	if err := FromGraphQL(wrapped); err != wrapped {
+		t.Fatalf("expected the error to be returned unchanged, got %v", err)
+	}
+}
+
+func TestIs(t *testing.T) {
+	err := fmt.Errorf("reading group: %w", New(NotFound, "group %d does not exist", 3))
+	if !Is(err, NotFound) {
+		t.Fatal("expected a wrapped NotFound error to match")
+	}
+	if Is(err, Forbidden) {
+		t.Fatal("did not expect a NotFound error to match Forbidden")
+	}
+	if Is(errors.New("boom"), ServerError) {
+		t.Fatal("did not expect a plain error to match")
+	}
+}
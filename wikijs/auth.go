@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+)
+
+// DefaultAuthStrategy is the key of the built-in Wiki.js local authentication strategy.
+const DefaultAuthStrategy = "local"
+
+// renewedTokenHeader is the response header in which Wiki.js returns a fresh JWT when the one presented has
+// expired but is still within its renewal period.
+const renewedTokenHeader = "new-jwt"
+
+// jwtSession logs in to Wiki.js with a username and password and keeps the JWT it gets back, replacing it
+// whenever Wiki.js renews it and logging in again when Wiki.js rejects it.
+type jwtSession struct {
+	username string
+	password string
+	strategy string
+	// loginClient sends the login mutation. It must not go through a jwtTransport backed by this session.
+	loginClient *gqlc.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// Token returns the cached JWT, logging in first if there is none yet.
+func (s *jwtSession) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		return s.token, nil
+	}
+	token, err := s.login(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+	return token, nil
+}
+
+func (s *jwtSession) login(ctx context.Context) (string, error) {
+	var data schema.LoginData
+	variables := schema.LoginVariables{
+		Username: gqlc.String(s.username),
+		Password: gqlc.String(s.password),
+		Strategy: gqlc.String(s.strategy),
+	}
+	err := s.loginClient.Mutate(withOperationKind(ctx, operationMutation), &data, variables.Map())
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Wiki.js as %s: %w", s.username, operationError(ctx, "login", err))
+	}
+	login := data.Authentication.Login
+	if err := apierror.FromResponse(login.ResponseResult); err != nil {
+		return "", fmt.Errorf("failed to log in to Wiki.js as %s: %w", s.username, err)
+	}
+	switch {
+	case bool(login.MustChangePwd):
+		return "", fmt.Errorf("the Wiki.js account %s must change its password before it can be used by terraform", s.username)
+	case bool(login.MustProvideTFA || login.MustSetupTFA):
+		return "", fmt.Errorf("the Wiki.js account %s requires two-factor authentication, which terraform cannot provide", s.username)
+	case login.Jwt == "":
+		return "", fmt.Errorf("Wiki.js did not return a token when logging in as %s", s.username)
+	}
+	tflog.Debug(ctx, fmt.Sprintf("logged in to Wiki.js as %s using the %s strategy", s.username, s.strategy))
+	return string(login.Jwt), nil
+}
+
+// renew replaces the cached JWT with one Wiki.js handed back in a response.
+func (s *jwtSession) renew(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// invalidate forgets token so that the next call to Token logs in again. A token renewed or replaced meanwhile by
+// a concurrent request is kept, so that a burst of rejected requests only logs in once.
+func (s *jwtSession) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// jwtTransport authenticates requests with the JWT of a jwtSession. A request rejected because the session has
+// expired, e.g. during a long apply, is sent once more with a fresh JWT when its body can be replayed and Wiki.js
+// cannot have run it: after a 401, or after an AuthRequired error for a query. A mutation answered with a GraphQL
+// error is never sent again, the session is only renewed for the next requests.
+type jwtTransport struct {
+	base    http.RoundTripper
+	session *jwtSession
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, token, err := t.send(req)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+	case authRequired(resp):
+		// Wiki.js may have resolved part of a mutation before the field that required authentication.
+		if operationKindFrom(req.Context()) != operationQuery {
+			tflog.Debug(req.Context(), "Wiki.js rejected the session token of a mutation, logging in again for the next requests")
+			t.session.invalidate(token)
+			return resp, nil
+		}
+	default:
+		return resp, nil
+	}
+	body, ok := replayableBody(req)
+	if !ok {
+		return resp, nil
+	}
+	drainBody(resp.Body)
+	tflog.Debug(req.Context(), "Wiki.js rejected the session token, logging in again")
+	t.session.invalidate(token)
+	r := req.Clone(req.Context())
+	r.Body = body
+	resp, _, err = t.send(r)
+	return resp, err
+}
+
+// send authenticates req with the current JWT of the session and returns the response with the JWT used.
+func (t *jwtTransport) send(req *http.Request) (*http.Response, string, error) {
+	token, err := t.session.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, "", err
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if renewed := resp.Header.Get(renewedTokenHeader); renewed != "" {
+		tflog.Debug(req.Context(), "Wiki.js renewed the session token")
+		t.session.renew(renewed)
+	}
+	return resp, token, nil
+}
+
+// replayableBody returns a fresh copy of the body of req, and false when it was already consumed for good.
+func replayableBody(req *http.Request) (body io.ReadCloser, ok bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Body, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// authRequiredCode is the code of the AuthRequired error, which Wiki.js raises for a request whose JWT is missing,
+// invalid or expired past its renewal period. A plain Forbidden error means the user lacks a permission instead.
+const authRequiredCode = 1019
+
+// authRequired reports whether Wiki.js answered resp with an AuthRequired GraphQL error. The body is buffered and
+// put back for the caller.
+func authRequired(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return false
+	}
+	payload, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	if err != nil {
+		return false
+	}
+	var out struct {
+		Errors gqlc.Errors `json:"errors"`
+	}
+	if json.Unmarshal(payload, &out) != nil || len(out.Errors) == 0 {
+		return false
+	}
+	var e *apierror.Error
+	return errors.As(apierror.FromGraphQL(out.Errors), &e) && (e.Code == authRequiredCode || e.Slug == "AuthRequired")
+}
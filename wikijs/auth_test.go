@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// loginServer is a stand-in Wiki.js accepting admin/secret on the local strategy. It issues jwt-1 on login and
+// renews it to jwt-2 on the first authenticated request.
+type loginServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	logins  int
+	renewed bool
+	tokens  []string
+}
+
+func newLoginServer(t *testing.T) *loginServer {
+	s := &loginServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/json")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if strings.Contains(in.Query, "login(") {
+			s.logins++
+			if r.Header.Get("Authorization") != "" {
+				t.Errorf("login request carried an Authorization header")
+			}
+			if in.Variables["username"] != "admin" || in.Variables["password"] != "secret" || in.Variables["strategy"] != "local" {
+				_, _ = w.Write([]byte(`{"data":{"authentication":{"login":{"responseResult":{"succeeded":false,"errorCode":1002,"slug":"AuthLoginFailed","message":"Invalid email / username or password."},"jwt":null,"mustChangePwd":false,"mustProvideTFA":false,"mustSetupTFA":false}}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"authentication":{"login":{"responseResult":{"succeeded":true,"errorCode":0,"slug":"ok","message":""},"jwt":"jwt-1","mustChangePwd":false,"mustProvideTFA":false,"mustSetupTFA":false}}}}`))
+			return
+		}
+
+		s.tokens = append(s.tokens, r.Header.Get("Authorization"))
+		if !s.renewed {
+			s.renewed = true
+			w.Header().Set("new-jwt", "jwt-2")
+		}
+		_, _ = w.Write([]byte(`{"data":{"site":{"config":{"host":"h","title":"t","description":""}}}}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestPasswordLogin(t *testing.T) {
+	srv := newLoginServer(t)
+	c := NewClient(Config{Host: srv.URL, Username: "admin", Password: "secret"})
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetSite(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if srv.logins != 1 {
+		t.Fatalf("expected a single login, got %d", srv.logins)
+	}
+	// The connectivity check, the version detection and three queries.
+	want := []string{"Bearer jwt-1", "Bearer jwt-2", "Bearer jwt-2", "Bearer jwt-2", "Bearer jwt-2"}
+	if strings.Join(srv.tokens, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected tokens %v, got %v", want, srv.tokens)
+	}
+}
+
+func TestPasswordLoginExpiredSession(t *testing.T) {
+	srv := testserver.New(t)
+	c := NewClient(Config{Host: srv.URL, Username: testserver.AdminEmail, Password: testserver.AdminPassword})
+	ctx := context.Background()
+	if _, err := c.CreateGroup(ctx, "before"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireSessions()
+	if _, err := c.GetGroupList(ctx); err != nil {
+		t.Fatalf("expected the query to be replayed after logging in again, got %v", err)
+	}
+	if logins := srv.RequestCount("authentication.login"); logins != 2 {
+		t.Fatalf("expected a second login once the session expired, got %d logins", logins)
+	}
+	if lists := srv.RequestCount("groups.list"); lists != 2 {
+		t.Fatalf("expected the rejected query to be sent twice, got %d", lists)
+	}
+
+	// Wiki.js answers a 401 before running the request, so that even a mutation is sent again.
+	srv.InjectFault(testserver.Fault{Operation: "groups.create", Status: http.StatusUnauthorized, Count: 1})
+	if _, err := c.CreateGroup(ctx, "after"); err != nil {
+		t.Fatalf("expected the mutation to be replayed after a 401, got %v", err)
+	}
+	if logins := srv.RequestCount("authentication.login"); logins != 3 {
+		t.Fatalf("expected a login after the 401, got %d logins", logins)
+	}
+	if creates := srv.RequestCount("groups.create"); creates != 3 {
+		t.Fatalf("expected the mutation rejected with a 401 to be sent twice, got %d group creations", creates)
+	}
+}
+
+func TestPasswordLoginExpiredSessionMutation(t *testing.T) {
+	srv := testserver.New(t)
+	c := NewClient(Config{Host: srv.URL, Username: testserver.AdminEmail, Password: testserver.AdminPassword})
+	ctx := context.Background()
+	if _, err := c.GetGroupList(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireSessions()
+	if _, err := c.CreateGroup(ctx, "rejected"); !apierror.Is(err, apierror.Forbidden) {
+		t.Fatalf("expected the mutation to fail without being replayed, got %v", err)
+	}
+	if creates := srv.RequestCount("groups.create"); creates != 1 {
+		t.Fatalf("expected the mutation to be sent once, got %d group creations", creates)
+	}
+	if _, err := c.CreateGroup(ctx, "after"); err != nil {
+		t.Fatalf("expected the next mutation to log in again, got %v", err)
+	}
+	if logins := srv.RequestCount("authentication.login"); logins != 2 {
+		t.Fatalf("expected a second login once the session expired, got %d logins", logins)
+	}
+}
+
+func TestPasswordLoginForbiddenNotReplayed(t *testing.T) {
+	srv := testserver.New(t)
+	c := NewClient(Config{Host: srv.URL, Username: testserver.AdminEmail, Password: testserver.AdminPassword})
+	ctx := context.Background()
+	srv.InjectFault(testserver.Forbidden("groups.list"))
+	if _, err := c.GetGroupList(ctx); !apierror.Is(err, apierror.Forbidden) {
+		t.Fatalf("expected a forbidden error, got %v", err)
+	}
+	if logins, lists := srv.RequestCount("authentication.login"), srv.RequestCount("groups.list"); logins != 1 || lists != 1 {
+		t.Fatalf("expected a missing permission not to log in again, got %d logins and %d queries", logins, lists)
+	}
+}
+
+func TestPasswordLoginFailure(t *testing.T) {
+	srv := newLoginServer(t)
+	_, err := NewClient(Config{Host: srv.URL, Username: "admin", Password: "wrong"}).GetSite(context.Background())
+	if !apierror.Is(err, apierror.Forbidden) {
+		t.Fatalf("expected a forbidden error, got %v", err)
+	}
+	if len(srv.tokens) != 0 {
+		t.Fatalf("no authenticated request should be sent after a failed login, got %v", srv.tokens)
+	}
+}
+
+func TestStaticTokenFallback(t *testing.T) {
+	srv := newLoginServer(t)
+	c := NewClient(Config{Host: srv.URL, Token: "api-key", Strategy: "local", SkipConnectivityCheck: true})
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.session != nil {
+		t.Fatal("a client without username should not log in")
+	}
+	if srv.logins != 0 || len(srv.tokens) != 1 || srv.tokens[0] != "Bearer api-key" {
+		t.Fatalf("expected the static token to be used, got %d logins and tokens %v", srv.logins, srv.tokens)
+	}
+}
+
+func TestUsernameWithoutPasswordFallsBackToToken(t *testing.T) {
+	srv := newLoginServer(t)
+	c := NewClient(Config{Host: srv.URL, Token: "api-key", Username: "admin", SkipConnectivityCheck: true})
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.session != nil {
+		t.Fatal("a client without password should not log in")
+	}
+	if srv.logins != 0 || len(srv.tokens) != 1 || srv.tokens[0] != "Bearer api-key" {
+		t.Fatalf("expected the static token to be used, got %d logins and tokens %v", srv.logins, srv.tokens)
+	}
+}
@@ -7,115 +7,628 @@ package wikijs
 import (
 	"context"
 	"fmt"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
 	"github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
 	gqlc "github.com/hasura/go-graphql-client"
 	"golang.org/x/oauth2"
 	"net/http"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// DefaultRequestTimeout bounds a single HTTP attempt, retries excluded, when neither Config.RequestTimeout nor
+// the caller's context limits it.
+const DefaultRequestTimeout = 10 * time.Second
+
+// defaultOperationTimeout bounds a single query or mutation when the caller's context carries no deadline.
+const defaultOperationTimeout = 1 * time.Minute
+
+// Config holds the settings a Client is built from.
+type Config struct {
+	Host  string
+	Token string
+	// Username and Password, when both set, are used to log in with the Strategy authentication strategy instead
+	// of authenticating with the static API Token. A Username without a Password falls back to the Token.
+	Username string
+	Password string
+	Strategy string
+	Retry    RetryPolicy
+	// RequestTimeout caps a single HTTP attempt. Zero leaves an attempt bounded by the deadline of the caller's
+	// context, such as the timeouts of a resource, or by DefaultRequestTimeout when the context has none.
+	RequestTimeout time.Duration
+	Transport      TransportConfig
+	// RequestsPerSecond caps the rate of requests sent to Wiki.js. Zero or less means unlimited.
+	RequestsPerSecond float64
+	// SkipConnectivityCheck disables the query that checks the host and credentials before the first request.
+	SkipConnectivityCheck bool
+	// StrictPageRules turns the findings of the page rule linter into plan errors instead of warnings.
+	StrictPageRules bool
+}
+
+// validate checks the settings that can only be checked once every value is known.
+func (config Config) validate() error {
+	if config.Host == "" {
+		return fmt.Errorf("Wikijs HOST not declared. Set the value as an env var WIKIJS_HOST or as `host` in the provider block")
+	}
+	if config.Username != "" && config.Password == "" && config.Token == "" {
+		return fmt.Errorf("Wikijs PASSWORD not declared. Set the value as an env var WIKIJS_PASSWORD or as `password` " +
+			"in the provider block, or remove `username` to use an API token")
+	}
+	if config.Token == "" && config.Username == "" {
+		return fmt.Errorf("Wikijs API TOKEN not declared. Set the value as an env var WIKIJS_TOKEN or as `token` " +
+			"in the provider block, or log in with `username` and `password` instead")
+	}
+	return nil
+}
+
+// logsIn reports whether the client logs in with a username and password rather than using the static token.
+func (config Config) logsIn() bool {
+	return config.Username != "" && config.Password != ""
+}
+
 type Client struct {
-	Host       string
-	Token      string
+	Host  string
+	Token string
+	// HTTPClient is nil until the first request.
 	HTTPClient *http.Client
-	gqlClient  *gqlc.Client
-}
-
-// NewClient creates a new http client with a connection to the provided wikijs endpoint.
-// The client is tested for authentication success on creation.
-func NewClient(host, token string) (*Client, error) {
-	hostGql := host + "/graphql"
-	ctx := context.Background()
-	oauthClient := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{
-		AccessToken: token,
-		TokenType:   "Bearer",
-	}))
-	httpClient := http.Client{Transport: oauthClient.Transport, Timeout: 10 * time.Second}
-	graphqlClient := gqlc.NewClient(hostGql, &httpClient)
-	c := Client{Token: token,
-		Host:       host,
-		HTTPClient: &httpClient,
-		gqlClient:  graphqlClient}
-
-	// Check connection with a simple query
-	_, err := c.GetSite()
-	if err != nil {
-		if strings.Contains(err.Error(), "Message: Forbidden") {
-			return nil, fmt.Errorf("failed to login to Wiki.js API. Check that the host and api token are correct")
+	// OperationTimeout is the deadline applied to the queries and mutations whose context has none. Zero
+	// disables it, leaving only the per-attempt request timeout in charge.
+	OperationTimeout time.Duration
+	// Version is the Wiki.js version, detected along with the connectivity check or by ServerVersion. It stays
+	// nil until then, and when the credentials are not allowed to read it.
+	Version *Version
+
+	config   Config
+	initMu   sync.Mutex
+	initDone bool
+	// gqlClient and session are built on the first request. session is only set when the client logs in with
+	// a username and password.
+	gqlClient *gqlc.Client
+	session   *jwtSession
+	// versionDetected is set once the version has been queried, versionErr holds the reason it is unknown.
+	versionDetected bool
+	versionErr      error
+	// groupLocks holds a lock per group, a channel holding a token while the group is locked, see LockGroup.
+	groupLocksMu sync.Mutex
+	groupLocks   map[GroupID]chan struct{}
+}
+
+// NewClient creates a client for the provided wikijs endpoint. Nothing is sent to Wiki.js, and the
+// configuration is not even checked, until the first request: the provider must be usable while the host is
+// still unknown or unreachable, e.g. during `terraform plan` of a configuration that also creates Wiki.js.
+// Unless disabled, the connection and credentials are checked once before that first request.
+func NewClient(config Config) *Client {
+	return &Client{Token: config.Token,
+		Host:             config.Host,
+		OperationTimeout: defaultOperationTimeout,
+		config:           config}
+}
+
+// init builds the HTTP stack and checks the connection on the first request. A failed check is retried by the
+// next request.
+func (c *Client) init(ctx context.Context) error {
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.initDone {
+		return nil
+	}
+	if c.gqlClient == nil {
+		if err := c.config.validate(); err != nil {
+			return err
 		}
+		if err := c.build(); err != nil {
+			return err
+		}
+	}
+	if !c.config.SkipConnectivityCheck {
+		if err := c.checkConnection(ctx); err != nil {
+			return err
+		}
+		c.detectVersion(ctx)
+	}
+	c.initDone = true
+	return nil
+}
+
+// ServerVersion returns the Wiki.js version, querying it first if the client has not done so yet.
+func (c *Client) ServerVersion(ctx context.Context) (*Version, error) {
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	if err := c.init(ctx); err != nil {
 		return nil, err
 	}
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if !c.versionDetected {
+		c.detectVersion(ctx)
+	}
+	return c.Version, c.versionErr
+}
+
+// detectVersion queries system.info for the Wiki.js version. Failing to detect it is not an error of the
+// request that triggered the detection, so the failure is only recorded, and only retried when it was caused by
+// the context ending. The caller must hold initMu.
+func (c *Client) detectVersion(ctx context.Context) {
+	var data schema.SystemInfoData
+	err := c.authenticate(ctx)
+	if err == nil {
+		err = operationError(ctx, "query", c.gqlClient.Query(withOperationKind(ctx, operationQuery), &data, nil))
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	c.versionDetected = true
+	if err != nil {
+		c.versionErr = fmt.Errorf("failed to detect the Wiki.js version: %w", err)
+		return
+	}
+	version, err := ParseVersion(string(data.System.Info.CurrentVersion))
+	if err != nil {
+		c.versionErr = fmt.Errorf("failed to detect the Wiki.js version: %w", err)
+		return
+	}
+	c.Version = &version
+}
+
+// checkConnection sends a simple query to test the host and credentials.
+func (c *Client) checkConnection(ctx context.Context) error {
+	var data schema.SiteData
+	err := c.authenticate(ctx)
+	if err == nil {
+		err = operationError(ctx, "query", c.gqlClient.Query(withOperationKind(ctx, operationQuery), &data, nil))
+	}
+	if apierror.Is(err, apierror.Forbidden) {
+		return fmt.Errorf("failed to login to Wiki.js API. Check that the host and credentials are correct: %w", err)
+	}
+	return err
+}
+
+func (c *Client) build() error {
+	config := c.config
+	hostGql := config.Host + "/graphql"
+	base, err := newBaseTransport(config.Transport)
+	if err != nil {
+		return err
+	}
+	limiter := newRateLimiter(config.RequestsPerSecond)
+	// The retry transport enforces the timeout per attempt, a Timeout on the http.Client would span all retries.
+	withRetries := func(rt http.RoundTripper) *http.Client {
+		return &http.Client{Transport: &retryTransport{
+			base:           rt,
+			policy:         config.Retry,
+			limiter:        limiter,
+			attemptTimeout: config.RequestTimeout,
+		}}
+	}
+
+	var auth http.RoundTripper
+	if config.logsIn() {
+		strategy := config.Strategy
+		if strategy == "" {
+			strategy = DefaultAuthStrategy
+		}
+		c.session = &jwtSession{
+			username:    config.Username,
+			password:    config.Password,
+			strategy:    strategy,
+			loginClient: gqlc.NewClient(hostGql, withRetries(base)),
+		}
+		auth = &jwtTransport{base: base, session: c.session}
+	} else {
+		auth = &oauth2.Transport{Base: base, Source: oauth2.StaticTokenSource(&oauth2.Token{
+			AccessToken: config.Token,
+			TokenType:   "Bearer",
+		})}
+	}
 
-	return &c, nil
+	c.HTTPClient = withRetries(auth)
+	c.gqlClient = gqlc.NewClient(hostGql, c.HTTPClient)
+	return nil
 }
 
 // query POSTS a graphql query through the hasura go-graphql-client
-func query[T any](c *Client, variables map[string]interface{}) (*T, error) {
+func query[T any](ctx context.Context, c *Client, variables map[string]interface{}) (*T, error) {
 	var data T
-	err := c.gqlClient.Query(context.Background(), &data, variables)
-	return &data, err
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	if err := c.init(ctx); err != nil {
+		return &data, err
+	}
+	if err := c.authenticate(ctx); err != nil {
+		return &data, err
+	}
+	err := c.gqlClient.Query(withOperationKind(ctx, operationQuery), &data, variables)
+	return &data, operationError(ctx, "query", err)
 }
 
 // mutate POSTS a graphql mutation through the hasura go-graphql-client
-func mutate[T any](c *Client, variables map[string]interface{}) (*T, error) {
+func mutate[T any](ctx context.Context, c *Client, variables map[string]interface{}) (*T, error) {
 	var data T
-	err := c.gqlClient.Mutate(context.Background(), &data, variables)
-	return &data, err
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	if err := c.init(ctx); err != nil {
+		return &data, err
+	}
+	if err := c.authenticate(ctx); err != nil {
+		return &data, err
+	}
+	err := c.gqlClient.Mutate(withOperationKind(ctx, operationMutation), &data, variables)
+	return &data, operationError(ctx, "mutation", err)
+}
+
+// authenticate logs in ahead of the request when the client uses a username and password. Logging in lazily
+// from the transport would also work, but the graphql client would flatten a failed login into a string.
+func (c *Client) authenticate(ctx context.Context) error {
+	if c.session == nil {
+		return nil
+	}
+	_, err := c.session.Token(ctx)
+	return err
+}
+
+// operationContext derives the context a single operation runs under. The deadline of the caller, e.g. the
+// timeout of the resource operation, is respected as is, and OperationTimeout only applies when there is none.
+// The timeout spans every retry of the operation.
+func (c *Client) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.OperationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.OperationTimeout)
+}
+
+// operationError converts the error returned by the graphql client into an apierror.Error, or into the
+// context's own error when the operation was cut short by cancellation or a deadline. The graphql client
+// flattens transport errors into strings, so without this callers could not tell an interrupted request apart
+// from a failed one with errors.Is.
+func operationError(ctx context.Context, operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("wikijs %s interrupted: %w", operation, ctxErr)
+	}
+	return apierror.FromGraphQL(err)
 }
 
-func (c *Client) GetSite() (*schema.SiteData, error) {
-	return query[schema.SiteData](c, nil)
+func (c *Client) GetSite(ctx context.Context) (*schema.SiteData, error) {
+	return query[schema.SiteData](ctx, c, nil)
 }
 
-func (c *Client) GetGroup(id string) (*schema.QueryGroupData, error) {
-	idInt, err := strconv.ParseInt(id, 10, 32)
+func (c *Client) GetGroup(ctx context.Context, id GroupID) (*schema.QueryGroupData, error) {
+	variables := schema.QueryGroupVariables{Id: gqlc.Int(id)}
+
+	data, err := query[schema.QueryGroupData](ctx, c, variables.Map())
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	variables := map[string]interface{}{
-		"id": gqlc.Int(idInt),
+	// Wiki.js answers with a null group rather than an error when the id does not exist.
+	if data.Groups.Single.Id == 0 {
+		return nil, apierror.New(apierror.NotFound, "group %s does not exist", id)
 	}
+	return data, nil
+}
 
-	return query[schema.QueryGroupData](c, variables)
+func (c *Client) GetGroupList(ctx context.Context) (*schema.QueryGroupListData, error) {
+	return query[schema.QueryGroupListData](ctx, c, nil)
 }
 
-func (c *Client) GetGroupList() (*schema.QueryGroupListData, error) {
-	return query[schema.QueryGroupListData](c, nil)
+// FindGroupByName returns the id of the group called name. Wiki.js does not require group names to be unique,
+// so a name shared by several groups is reported as a conflict.
+func (c *Client) FindGroupByName(ctx context.Context, name string) (GroupID, error) {
+	data, err := c.GetGroupList(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	var found GroupID
+	for _, g := range data.Groups.List {
+		if string(g.Name) == name {
+			found = GroupID(g.Id)
+			ids = append(ids, found.String())
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return 0, apierror.New(apierror.NotFound, "no group is named %q", name)
+	case 1:
+		return found, nil
+	default:
+		return 0, apierror.New(apierror.Conflict, "%d groups are named %q, with ids %s", len(ids), name, strings.Join(ids, ", "))
+	}
 }
 
-func (c *Client) CreateGroup(name string) (*schema.CreateGroupData, error) {
-	variables := map[string]interface{}{
-		"name": gqlc.String(name),
+func (c *Client) CreateGroup(ctx context.Context, name string) (*schema.CreateGroupData, error) {
+	variables := schema.CreateGroupVariables{Name: gqlc.String(name)}
+	data, err := mutate[schema.CreateGroupData](ctx, c, variables.Map())
+	if err != nil {
+		return nil, err
 	}
-	return mutate[schema.CreateGroupData](c, variables)
+	return data, apierror.FromResponse(data.Groups.Create.ResponseResult)
 }
 
-func (c *Client) DeleteGroup(id string) (*schema.DeleteGroupData, error) {
-	idInt, err := strconv.ParseInt(id, 10, 32)
+func (c *Client) DeleteGroup(ctx context.Context, id GroupID) (*schema.DeleteGroupData, error) {
+	variables := schema.DeleteGroupVariables{Id: gqlc.Int(id)}
+	data, err := mutate[schema.DeleteGroupData](ctx, c, variables.Map())
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	variables := map[string]interface{}{
-		"id": gqlc.Int(idInt),
+	return data, apierror.FromResponse(data.Groups.Delete.ResponseResult)
+}
+
+func (c *Client) UpdateGroup(ctx context.Context, id GroupID, name string, redirectOnLogin string, permissions []string, pageRules []schema.PageRuleInput) (*schema.UpdateGroupData, error) {
+	variables := schema.UpdateGroupVariables{
+		Id:              gqlc.Int(id),
+		Name:            gqlc.String(name),
+		RedirectOnLogin: gqlc.String(redirectOnLogin),
+		Permissions:     stringArrayToGqlcStringArray(permissions),
+		PageRules:       pageRules,
+	}
+
+	data, err := mutate[schema.UpdateGroupData](ctx, c, variables.Map())
+	if err != nil {
+		return nil, err
 	}
-	return mutate[schema.DeleteGroupData](c, variables)
+	return data, apierror.FromResponse(data.Groups.Update.ResponseResult)
 }
 
-func (c *Client) UpdateGroup(id string, name string, redirectOnLogin string, permissions []string, pageRules []schema.PageRuleInput) (*schema.UpdateGroupData, error) {
-	idInt, err := strconv.ParseInt(id, 10, 32)
+// LockGroup waits until no other update of the group is in progress in this provider and returns the function
+// releasing the group. Wiki.js replaces all the page rules of a group at once: an update that only changes some of
+// them reads the group and writes it back, and must hold the lock meanwhile so that a concurrent update of the
+// other rules is not lost. Waiting stops with an error when ctx ends, e.g. at the timeout of the resource.
+func (c *Client) LockGroup(ctx context.Context, id GroupID) (unlock func(), err error) {
+	c.groupLocksMu.Lock()
+	if c.groupLocks == nil {
+		c.groupLocks = make(map[GroupID]chan struct{})
+	}
+	lock, ok := c.groupLocks[id]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		c.groupLocks[id] = lock
+	}
+	c.groupLocksMu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for another update of group %s: %w", id, ctx.Err())
+	}
+}
+
+// UpdateGroupPageRules replaces the page rules of a group by those edit returns given the group as it is,
+// leaving its other settings unchanged. The group is locked until it is written back.
+func (c *Client) UpdateGroupPageRules(ctx context.Context, id GroupID, edit func(group schema.Group) ([]schema.PageRuleInput, error)) error {
+	unlock, err := c.LockGroup(ctx, id)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer unlock()
 
-	variables := map[string]interface{}{
-		"id":              gqlc.Int(idInt),
-		"name":            gqlc.String(name),
-		"redirectOnLogin": gqlc.String(redirectOnLogin),
-		"permissions":     stringArrayToGqlcStringArray(permissions),
-		"pageRules":       pageRules,
+	data, err := c.GetGroup(ctx, id)
+	if err != nil {
+		return err
 	}
+	group := data.Groups.Single
+	pageRules, err := edit(group)
+	if err != nil {
+		return err
+	}
+	_, err = c.UpdateGroup(ctx, id, string(group.Name), string(group.RedirectOnLogin),
+		gqlcStringArrayToStringArray(group.Permissions), pageRules)
+	return err
+}
 
-	return mutate[schema.UpdateGroupData](c, variables)
+// GetGroupMembers returns the ids of the users of a group, in ascending order.
+func (c *Client) GetGroupMembers(ctx context.Context, id GroupID) ([]UserID, error) {
+	variables := schema.QueryGroupMembersVariables{Id: gqlc.Int(id)}
+	data, err := query[schema.QueryGroupMembersData](ctx, c, variables.Map())
+	if err != nil {
+		return nil, err
+	}
+	if data.Groups.Single.Id == 0 {
+		return nil, apierror.New(apierror.NotFound, "group %s does not exist", id)
+	}
+	members := make([]UserID, len(data.Groups.Single.Users))
+	for i, u := range data.Groups.Single.Users {
+		members[i] = UserID(u.Id)
+	}
+	sortUserIDs(members)
+	return members, nil
+}
+
+// AssignUser adds a user to a group. Wiki.js reports a user that is already a member as an apierror.Conflict.
+func (c *Client) AssignUser(ctx context.Context, groupID GroupID, userID UserID) error {
+	variables := schema.AssignGroupUserVariables{GroupId: gqlc.Int(groupID), UserId: gqlc.Int(userID)}
+	data, err := mutate[schema.AssignGroupUserData](ctx, c, variables.Map())
+	if err != nil {
+		return err
+	}
+	return apierror.FromResponse(data.Groups.AssignUser.ResponseResult)
+}
+
+// UnassignUser removes a user from a group. Removing a user that is not a member succeeds.
+func (c *Client) UnassignUser(ctx context.Context, groupID GroupID, userID UserID) error {
+	variables := schema.UnassignGroupUserVariables{GroupId: gqlc.Int(groupID), UserId: gqlc.Int(userID)}
+	data, err := mutate[schema.UnassignGroupUserData](ctx, c, variables.Map())
+	if err != nil {
+		return err
+	}
+	return apierror.FromResponse(data.Groups.UnassignUser.ResponseResult)
+}
+
+// GetUserGroups returns the ids of the groups of a user, in ascending order.
+func (c *Client) GetUserGroups(ctx context.Context, id UserID) ([]GroupID, error) {
+	variables := schema.QueryUserGroupsVariables{Id: gqlc.Int(id)}
+	data, err := query[schema.QueryUserGroupsData](ctx, c, variables.Map())
+	if err != nil {
+		return nil, err
+	}
+	if data.Users.Single.Id == 0 {
+		return nil, apierror.New(apierror.NotFound, "user %s does not exist", id)
+	}
+	groups := make([]GroupID, len(data.Users.Single.Groups))
+	for i, g := range data.Users.Single.Groups {
+		groups[i] = GroupID(g.Id)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+	return groups, nil
+}
+
+// GetInstalledLocales returns the codes of the locales installed in Wiki.js.
+func (c *Client) GetInstalledLocales(ctx context.Context) ([]string, error) {
+	data, err := query[schema.QueryLocalesData](ctx, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	for _, l := range data.Localization.Locales {
+		if l.IsInstalled {
+			codes = append(codes, string(l.Code))
+		}
+	}
+	return codes, nil
+}
+
+// GetUser returns a user with its groups.
+func (c *Client) GetUser(ctx context.Context, id UserID) (*schema.User, error) {
+	variables := schema.QueryUserVariables{Id: gqlc.Int(id)}
+	data, err := query[schema.QueryUserData](ctx, c, variables.Map())
+	if err != nil {
+		return nil, err
+	}
+	if data.Users.Single.Id == 0 {
+		return nil, apierror.New(apierror.NotFound, "user %s does not exist", id)
+	}
+	return &data.Users.Single, nil
+}
+
+// GetUserList returns every user, system users included.
+func (c *Client) GetUserList(ctx context.Context) ([]schema.UserMinimal, error) {
+	data, err := query[schema.QueryUserListData](ctx, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	return data.Users.List, nil
+}
+
+// SearchUsers returns the users whose name or email contains text. Wiki.js returns at most searchUsersLimit
+// of them, see SearchAllUsers.
+func (c *Client) SearchUsers(ctx context.Context, text string) ([]schema.UserMinimal, error) {
+	variables := schema.SearchUsersVariables{Query: gqlc.String(text)}
+	data, err := query[schema.SearchUsersData](ctx, c, variables.Map())
+	if err != nil {
+		return nil, err
+	}
+	return data.Users.Search, nil
+}
+
+// searchUsersLimit is the number of users users.search stops at.
+const searchUsersLimit = 10
+
+// SearchAllUsers returns every user whose name or email contains text, case-insensitively. A search that
+// stopped at searchUsersLimit users is completed by filtering the list of all the users.
+func (c *Client) SearchAllUsers(ctx context.Context, text string) ([]schema.UserMinimal, error) {
+	users, err := c.SearchUsers(ctx, text)
+	if err != nil || len(users) < searchUsersLimit {
+		return users, err
+	}
+	all, err := c.GetUserList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	text = strings.ToLower(text)
+	users = users[:0]
+	for _, u := range all {
+		if strings.Contains(strings.ToLower(string(u.Email)), text) || strings.Contains(strings.ToLower(string(u.Name)), text) {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// FindUserByEmail returns the id of the user with the email, which Wiki.js requires to be unique. Emails are
+// compared case-insensitively, as Wiki.js stores them in lower case.
+func (c *Client) FindUserByEmail(ctx context.Context, email string) (UserID, error) {
+	users, err := c.SearchAllUsers(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return 0, err
+	}
+	if id := findUserByEmail(users, email); id != 0 {
+		return id, nil
+	}
+	return 0, apierror.New(apierror.NotFound, "no user has the email %q", email)
+}
+
+func findUserByEmail(users []schema.UserMinimal, email string) UserID {
+	for _, u := range users {
+		if strings.EqualFold(string(u.Email), strings.TrimSpace(email)) {
+			return UserID(u.Id)
+		}
+	}
+	return 0
+}
+
+// CreateUser creates a user and returns its id. Wiki.js 2.x does not return the new user, so it is looked up by
+// its email.
+func (c *Client) CreateUser(ctx context.Context, user schema.CreateUserVariables) (UserID, error) {
+	data, err := mutate[schema.CreateUserData](ctx, c, user.Map())
+	if err != nil {
+		return 0, err
+	}
+	if err := apierror.FromResponse(data.Users.Create.ResponseResult); err != nil {
+		return 0, err
+	}
+	if id := data.Users.Create.User.Id; id != 0 {
+		return UserID(id), nil
+	}
+	return c.FindUserByEmail(ctx, string(user.Email))
+}
+
+// UpdateUser updates the settings of a user that are set in user, leaving the nil ones unchanged.
+func (c *Client) UpdateUser(ctx context.Context, user schema.UpdateUserVariables) error {
+	data, err := mutate[schema.UpdateUserData](ctx, c, user.Map())
+	if err != nil {
+		return err
+	}
+	return apierror.FromResponse(data.Users.Update.ResponseResult)
+}
+
+// DeleteUser deletes a user, and makes replaceID the author of its content.
+func (c *Client) DeleteUser(ctx context.Context, id UserID, replaceID UserID) error {
+	variables := schema.DeleteUserVariables{Id: gqlc.Int(id), ReplaceId: gqlc.Int(replaceID)}
+	data, err := mutate[schema.DeleteUserData](ctx, c, variables.Map())
+	if err != nil {
+		return err
+	}
+	return apierror.FromResponse(data.Users.Delete.ResponseResult)
+}
+
+// VerifyUser marks the email of a user as verified. Wiki.js cannot undo it.
+func (c *Client) VerifyUser(ctx context.Context, id UserID) error {
+	variables := schema.VerifyUserVariables{Id: gqlc.Int(id)}
+	data, err := mutate[schema.VerifyUserData](ctx, c, variables.Map())
+	if err != nil {
+		return err
+	}
+	return apierror.FromResponse(data.Users.Verify.ResponseResult)
+}
+
+// SetUserActive activates or deactivates a user. A deactivated user cannot log in.
+func (c *Client) SetUserActive(ctx context.Context, id UserID, active bool) error {
+	if active {
+		variables := schema.ActivateUserVariables{Id: gqlc.Int(id)}
+		data, err := mutate[schema.ActivateUserData](ctx, c, variables.Map())
+		if err != nil {
+			return err
+		}
+		return apierror.FromResponse(data.Users.Activate.ResponseResult)
+	}
+	variables := schema.DeactivateUserVariables{Id: gqlc.Int(id)}
+	data, err := mutate[schema.DeactivateUserData](ctx, c, variables.Map())
+	if err != nil {
+		return err
+	}
+	return apierror.FromResponse(data.Users.Deactivate.ResponseResult)
 }
@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// testClient builds a Client that does not check the connection before its first request.
+func testClient(config Config) *Client {
+	config.SkipConnectivityCheck = true
+	return NewClient(config)
+}
+
+// hangingServer starts a stand-in Wiki.js that never answers until the request is abandoned by the client or
+// the test finishes.
+func hangingServer(t *testing.T) *httptest.Server {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	return srv
+}
+
+func TestClientCancellation(t *testing.T) {
+	srv := hangingServer(t)
+	c := testClient(Config{Host: srv.URL, Token: "token"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.GetSite(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("cancellation took %s to reach the request", elapsed)
+	}
+}
+
+func TestClientCallerDeadline(t *testing.T) {
+	srv := hangingServer(t)
+	c := testClient(Config{Host: srv.URL, Token: "token"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.DeleteGroup(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestClientOperationTimeout(t *testing.T) {
+	srv := hangingServer(t)
+	c := testClient(Config{Host: srv.URL, Token: "token"})
+	c.OperationTimeout = 50 * time.Millisecond
+
+	_, err := c.CreateGroup(context.Background(), "test-group")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestClientCallerDeadlineOverridesOperationTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"site":{"config":{"host":"","title":"Wiki","description":""}}}}`))
+	}))
+	defer srv.Close()
+	c := testClient(Config{Host: srv.URL, Token: "token"})
+	c.OperationTimeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := c.GetSite(ctx); err != nil {
+		t.Fatalf("expected the deadline of the caller to replace the operation timeout, got %v", err)
+	}
+}
+
+func TestClientConnectivityCheckCancelled(t *testing.T) {
+	srv := hangingServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewClient(Config{Host: srv.URL, Token: "token"}).GetGroup(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientGetSite(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"site":{"config":{"host":"https://wiki.example.com","title":"Wiki","description":""}}}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Host: srv.URL, Token: "token"})
+	data, err := c.GetSite(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Site.Config.Title != "Wiki" {
+		t.Fatalf("unexpected title %q", data.Site.Config.Title)
+	}
+}
+
+// staticServer answers every request with body.
+func staticServer(t *testing.T, body string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientRejectedMutation(t *testing.T) {
+	srv := staticServer(t, `{"data":{"groups":{"update":{"responseResult":{"succeeded":false,"errorCode":1012,"slug":"InputInvalid","message":"Invalid input"}}}}}`)
+	c := testClient(Config{Host: srv.URL, Token: "token"})
+
+	_, err := c.UpdateGroup(context.Background(), 3, "name", "/", nil, nil)
+	if !apierror.Is(err, apierror.Validation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestClientGroupNotFound(t *testing.T) {
+	srv := staticServer(t, `{"data":{"groups":{"single":null}}}`)
+	c := testClient(Config{Host: srv.URL, Token: "token"})
+
+	_, err := c.GetGroup(context.Background(), 42)
+	if !apierror.Is(err, apierror.NotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestClientConnectivityCheckForbidden(t *testing.T) {
+	srv := staticServer(t, `{"errors":[{"message":"Forbidden"}],"data":{"site":null}}`)
+
+	_, err := NewClient(Config{Host: srv.URL, Token: "token"}).GetGroup(context.Background(), 1)
+	if !apierror.Is(err, apierror.Forbidden) {
+		t.Fatalf("expected a forbidden error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Check that the host and credentials are correct") {
+		t.Fatalf("expected a hint about the credentials, got %v", err)
+	}
+}
+
+func TestClientIsLazy(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "single") {
+			_, _ = w.Write([]byte(`{"data":{"groups":{"single":{"id":1,"name":"Administrators","isSystem":true,"redirectOnLogin":"/","permissions":[],"pageRules":[],"createdAt":"","updatedAt":""}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"site":{"config":{"host":"h","title":"t","description":""}}}}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Host: srv.URL, Token: "token"})
+	if len(requests) != 0 || c.HTTPClient != nil {
+		t.Fatal("NewClient must not contact Wiki.js")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetGroup(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(requests) != 4 || !strings.Contains(requests[0], "site") || !strings.Contains(requests[1], "system") {
+		t.Fatalf("expected a single connectivity check and version detection before the first request, got %v", requests)
+	}
+
+	requests = nil
+	skipping := NewClient(Config{Host: srv.URL, Token: "token", SkipConnectivityCheck: true})
+	if _, err := skipping.GetGroup(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected the connectivity check to be skipped, got %v", requests)
+	}
+}
+
+func TestClientConfigCheckedOnFirstRequest(t *testing.T) {
+	for name, config := range map[string]Config{
+		"host":     {Token: "token"},
+		"token":    {Host: "https://wiki.example.com"},
+		"password": {Host: "https://wiki.example.com", Username: "admin"},
+	} {
+		c := NewClient(config)
+		if _, err := c.GetSite(context.Background()); err == nil || !strings.Contains(err.Error(), "not declared") {
+			t.Errorf("%s: expected a missing setting error, got %v", name, err)
+		}
+	}
+}
+
+func TestClientFindUserByEmail(t *testing.T) {
+	srv, c := testServerClient(t)
+	// users.search stops at 10 users, all of whose emails contain the one looked up.
+	for i := 0; i < 12; i++ {
+		srv.AddUser(testserver.User{Email: fmt.Sprintf("%dann@example.com", i), Name: "Ann"})
+	}
+	ann := srv.AddUser(testserver.User{Email: "ann@example.com", Name: "Ann"})
+
+	id, err := c.FindUserByEmail(context.Background(), "Ann@Example.com")
+	if err != nil || id != UserID(ann.ID) {
+		t.Fatalf("expected user %d, got %s and %v", ann.ID, id, err)
+	}
+	if srv.RequestCount("users.list") != 1 {
+		t.Fatal("expected the users to be listed once the search was truncated")
+	}
+	if _, err := c.FindUserByEmail(context.Background(), "bob@example.com"); !apierror.Is(err, apierror.NotFound) {
+		t.Fatalf("expected apierror.NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func dataSourceEffectivePermissions() *schema.Resource {
+	return &schema.Resource{
+		Description: "Evaluates the permissions of groups, or of the groups of a user, on a page like Wiki.js does, " +
+			"and tells which page rule grants or denies each of them. Answers questions like \"why can't this group " +
+			"edit hr/policies\" without reading the Wiki.js source.",
+
+		ReadContext: dataSourceEffectivePermissionsRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(2 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"group_ids": {
+				Type:         schema.TypeSet,
+				Optional:     true,
+				ExactlyOneOf: []string{"group_ids", "user_id"},
+				Description:  "Ids of the groups whose permissions are evaluated, together as for a member of them all.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeInt,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+			},
+			"user_id": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Id of the user whose groups are evaluated.",
+			},
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Path of the page, e.g. `hr/policies`. Leading and trailing slashes are ignored, and so is a leading installed locale, e.g. `fr` in `fr/hr/policies`, which sets `locale` when it is not set.",
+			},
+			"locale": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "en",
+				Description: "Locale of the page.",
+			},
+			"tags": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Tags of the page, matched by the TAG page rules.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"permissions": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Description: "The page permissions granted on the page.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"decisions": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "How each page permission is decided, in the order of the Wiki.js permissions tab.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"permission": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The permission, e.g. `write:pages`.",
+						},
+						"granted": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the permission is granted on the page.",
+						},
+						"group_id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Id of the group that decided, 0 when none of the groups has the permission.",
+						},
+						"rule_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Id of the page rule that decided, empty when no rule matches the page.",
+						},
+						"reason": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Why the permission is granted or not.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceEffectivePermissionsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+
+	var groupIDs []GroupID
+	if v, ok := d.GetOk("user_id"); ok {
+		userID := UserID(v.(int))
+		ids, err := c.GetUserGroups(ctx, userID)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to read the groups of user %s", userID), err)
+		}
+		groupIDs = ids
+	} else {
+		for _, id := range d.Get("group_ids").(*schema.Set).List() {
+			groupIDs = append(groupIDs, GroupID(id.(int)))
+		}
+		sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+	}
+
+	groups := make([]wjSchema.Group, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		data, err := c.GetGroup(ctx, id)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to read group %s", id), err)
+		}
+		groups = append(groups, data.Groups.Single)
+	}
+
+	page := accessPage{
+		path:   normalizePagePath(d.Get("path").(string)),
+		locale: d.Get("locale").(string),
+	}
+	// A path given with its locale, e.g. fr/docs, is the page docs of that locale unless locale is set.
+	locales, err := pathLocales(ctx, c, page.path)
+	if err != nil {
+		return apiErrorDiagnostics("Failed to read the installed locales", err)
+	}
+	if locale, path := splitLocale(page.path, locales); locale != "" {
+		page.path = path
+		if config := d.GetRawConfig(); config.IsNull() || config.GetAttr("locale").IsNull() {
+			page.locale = locale
+		}
+	}
+	for _, tag := range d.Get("tags").(*schema.Set).List() {
+		// Wiki.js stores tags in lower case.
+		page.tags = append(page.tags, strings.ToLower(tag.(string)))
+	}
+
+	var granted []string
+	var decisions []interface{}
+	for _, permission := range pageRuleRoles() {
+		decision, err := checkAccess(groups, permission, page)
+		if err != nil {
+			return diag.Errorf("Failed to evaluate %s on %s: %s", permission, page.path, err)
+		}
+		if decision.granted {
+			granted = append(granted, permission)
+		}
+		ruleID := ""
+		if decision.rule != nil {
+			ruleID = string(decision.rule.Id)
+		}
+		decisions = append(decisions, map[string]interface{}{
+			"permission": permission,
+			"granted":    decision.granted,
+			"group_id":   int(decision.groupID),
+			"rule_id":    ruleID,
+			"reason":     decision.reason,
+		})
+	}
+	if err := d.Set("permissions", granted); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("decisions", decisions); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+
+	return diags
+}
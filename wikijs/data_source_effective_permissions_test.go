@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// testEffectivePermissions returns the permissions granted in the state of wikijs_effective_permissions.
+func testEffectivePermissions(state *terraform.InstanceState) string {
+	var granted []string
+	for i := 0; ; i++ {
+		prefix := "decisions." + strconv.Itoa(i) + "."
+		permission, ok := state.Attributes[prefix+"permission"]
+		if !ok {
+			return strings.Join(granted, ",")
+		}
+		if state.Attributes[prefix+"granted"] == "true" {
+			granted = append(granted, permission)
+		}
+	}
+}
+
+// testEffectivePermissionsServer returns a testserver with a group of HR editors whose policies are read only.
+func testEffectivePermissionsServer(t *testing.T) (*testserver.Server, *Client, testserver.Group, testserver.User) {
+	srv, c := testServerClient(t)
+	hr := srv.AddGroup(testserver.Group{
+		Name:        "hr",
+		Permissions: []string{"read:pages", "write:pages"},
+		PageRules: []testserver.PageRule{
+			{ID: "hr", Match: "START", Roles: []string{"read:pages", "write:pages"}, Path: "hr", Locales: []string{}},
+			{ID: "frozen", Deny: true, Match: "START", Roles: []string{"write:pages"}, Path: "hr/policies", Locales: []string{}},
+		},
+	})
+	bob := srv.AddUser(testserver.User{Email: "bob@example.com", Name: "Bob"})
+	srv.AssignUser(hr.ID, bob.ID)
+	return srv, c, hr, bob
+}
+
+func TestDataSourceEffectivePermissionsRead(t *testing.T) {
+	_, c, hr, bob := testEffectivePermissionsServer(t)
+
+	state, diags := testDataSourceRead(t, dataSourceEffectivePermissions(), map[string]interface{}{
+		"group_ids": []interface{}{hr.ID},
+		"path":      "/hr/policies/leave",
+	}, c)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if granted := testEffectivePermissions(state); granted != "read:pages" {
+		t.Fatalf("expected only read:pages to be granted, got %s", granted)
+	}
+	if state.Attributes["decisions.6.permission"] != "write:pages" || state.Attributes["decisions.6.rule_id"] != "frozen" ||
+		state.Attributes["decisions.6.group_id"] != strconv.Itoa(hr.ID) ||
+		!strings.Contains(state.Attributes["decisions.6.reason"], `denied by page rule "frozen"`) {
+		t.Fatalf("expected write:pages to be denied by the frozen rule, got %v", state.Attributes)
+	}
+	if state.Attributes["decisions.1.reason"] != "not in the permissions of the groups" {
+		t.Fatalf("expected read:assets not to be granted by the group, got %v", state.Attributes)
+	}
+
+	state, diags = testDataSourceRead(t, dataSourceEffectivePermissions(), map[string]interface{}{
+		"user_id": bob.ID,
+		"path":    "hr/onboarding",
+	}, c)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if granted := testEffectivePermissions(state); granted != "read:pages,write:pages" {
+		t.Fatalf("expected the groups of the user to be evaluated, got %s", granted)
+	}
+
+	// The installed locale en is not part of the page path, hr is not installed and is.
+	state, diags = testDataSourceRead(t, dataSourceEffectivePermissions(), map[string]interface{}{
+		"user_id": bob.ID,
+		"path":    "/EN/hr/policies/leave",
+	}, c)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if granted := testEffectivePermissions(state); granted != "read:pages" {
+		t.Fatalf("expected the locale to be removed from the path, got %s", granted)
+	}
+
+	_, diags = testDataSourceRead(t, dataSourceEffectivePermissions(), map[string]interface{}{
+		"user_id": bob.ID + 10,
+		"path":    "hr",
+	}, c)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "does not exist") {
+		t.Fatalf("expected an unknown user to fail, got %v", diags)
+	}
+}
+
+func TestAccDataSourceEffectivePermissions(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("the group is seeded in the testserver")
+	}
+	testAccServer(t).AddGroup(testserver.Group{
+		Name:        "readers",
+		Permissions: []string{"read:pages"},
+		PageRules:   []testserver.PageRule{{ID: "default", Match: "START", Roles: []string{"read:pages"}, Path: "", Locales: []string{}}},
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceEffectivePermissions,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.wikijs_effective_permissions.readers", "permissions.#", "1"),
+					resource.TestCheckTypeSetElemAttr("data.wikijs_effective_permissions.readers", "permissions.*", "read:pages"),
+					resource.TestCheckResourceAttr("data.wikijs_effective_permissions.readers", "decisions.0.rule_id", "default"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceEffectivePermissions = `
+data "wikijs_groups" "readers" {
+  name_regex = "^readers$"
+}
+
+data "wikijs_effective_permissions" "readers" {
+  group_ids = [data.wikijs_groups.readers.groups[0].id]
+  path      = "hr/policies"
+}
+`
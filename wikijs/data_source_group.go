@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"time"
+)
+
+func dataSourceGroup() *schema.Resource {
+	return &schema.Resource{
+		Description: "Reads a Wiki.js group, found by id or by name, with its permissions, page rules and members.",
+
+		ReadContext: dataSourceGroupRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "name"},
+				Description:  "id of the group to read.",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "name of the group to read. Fails when several groups have this name.",
+			},
+			"is_system": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "isSystem",
+			},
+			"redirect_on_login": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "redirect on login path",
+			},
+			"permissions": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Description: "permissions",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"effective_permissions": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Description: "The permissions of the group in Wiki.js, as `effective_permissions` of `wikijs_group_resource`.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"created_at": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "createdAt",
+			},
+			"updated_at": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "updatedAt",
+			},
+			"page_rules": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "page rules, in the order Wiki.js returns them",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"deny": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"match": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"roles": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"path": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"locales": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+			"user_ids": {
+				Type:        schema.TypeSet,
+				Computed:    true,
+				Description: "Ids of the users of the group.",
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+			},
+		},
+	}
+}
+
+func dataSourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+
+	var id GroupID
+	if v, ok := d.GetOk("id"); ok {
+		parsed, err := ParseGroupID(v.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		id = parsed
+	} else {
+		name := d.Get("name").(string)
+		found, err := c.FindGroupByName(ctx, name)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to find group %s", name), err)
+		}
+		id = found
+	}
+
+	data, err := c.GetGroup(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read group %s", id), err)
+	}
+	members, err := c.GetGroupMembers(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read the members of group %s", id), err)
+	}
+
+	d.SetId(id.String())
+	if diags := flattenGroup(d, data.Groups.Single); diags.HasError() {
+		return diags
+	}
+	if err := d.Set("user_ids", flattenUserIDs(members)); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestDataSourceGroupRead(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{
+		Name:        "writers",
+		Permissions: []string{"read:pages", "write:pages"},
+		PageRules: []testserver.PageRule{
+			{ID: "docs", Match: "START", Roles: []string{"read:pages", "write:pages"}, Path: "docs", Locales: []string{"en"}},
+			{ID: "frozen", Deny: true, Match: "EXACT", Roles: []string{"write:pages"}, Path: "docs/frozen", Locales: []string{}},
+		},
+	})
+	srv.AssignUser(g.ID, 1)
+	srv.AddGroup(testserver.Group{Name: "twin"})
+	srv.AddGroup(testserver.Group{Name: "twin"})
+
+	for _, config := range []map[string]interface{}{{"id": strconv.Itoa(g.ID)}, {"name": "writers"}} {
+		state, diags := testDataSourceRead(t, dataSourceGroup(), config, c)
+		if diags.HasError() {
+			t.Fatalf("%v: unexpected diagnostics: %v", config, diags)
+		}
+		attributes := state.Attributes
+		if state.ID != strconv.Itoa(g.ID) || attributes["name"] != "writers" || attributes["permissions.#"] != "2" ||
+			attributes["page_rules.#"] != "2" || attributes["page_rules.1.deny"] != "true" ||
+			attributes["page_rules.0.locales.0"] != "en" || attributes["user_ids.#"] != "1" ||
+			attributes["effective_permissions.#"] != "2" {
+			t.Errorf("%v: unexpected attributes %v", config, attributes)
+		}
+	}
+
+	for _, tc := range []struct {
+		config   map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"name": "missing"}, "no group is named"},
+		{map[string]interface{}{"name": "twin"}, "2 groups are named"},
+		{map[string]interface{}{"id": "404"}, "does not exist"},
+		{map[string]interface{}{"id": "writers"}, "invalid group id"},
+	} {
+		_, diags := testDataSourceRead(t, dataSourceGroup(), tc.config, c)
+		if !diags.HasError() || !strings.Contains(diags[0].Summary+diags[0].Detail, tc.expected) {
+			t.Errorf("%v: expected an error containing %q, got %v", tc.config, tc.expected, diags)
+		}
+	}
+}
+
+func TestAccDataSourceGroup(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceGroup,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.wikijs_group.admins", "id", "1"),
+					resource.TestCheckResourceAttr("data.wikijs_group.admins", "is_system", "true"),
+					resource.TestCheckResourceAttrPair("data.wikijs_group.mirror", "page_rules.0.path", "wikijs_group_resource.source", "page_rules.0.path"),
+					resource.TestCheckResourceAttrPair("data.wikijs_group.mirror", "name", "wikijs_group_resource.source", "name"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceGroup = `
+data "wikijs_group" "admins" {
+  name = "Administrators"
+}
+
+resource "wikijs_group_resource" "source" {
+  name              = "data-source-group"
+  permissions       = ["read:pages"]
+  redirect_on_login = "/"
+  page_rules {
+    id      = "docs"
+    deny    = false
+    match   = "START"
+    roles   = ["read:pages"]
+    path    = "docs"
+    locales = []
+  }
+}
+
+data "wikijs_group" "mirror" {
+  id = wikijs_group_resource.source.id
+}
+`
@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"golang.org/x/exp/slices"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func dataSourceGroups() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the Wiki.js groups, optionally filtered, e.g. to look up the id of a group by its name.",
+
+		ReadContext: dataSourceGroupsRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(2 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				Description:  "Only return the groups whose name matches this regular expression.",
+			},
+			"is_system": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return the built-in groups, Administrators and Guests, when true, or only the other groups when false.",
+			},
+			"has_permission": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validatePermission,
+				Description: "Only return the groups granted this global permission, e.g. `write:pages`. The list of " +
+					"groups does not include their permissions, so each group left by the other filters is read, " +
+					fmt.Sprintf("%d at a time.", groupPermissionsConcurrency),
+			},
+			"groups": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The groups, ordered by id.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "id",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "name",
+						},
+						"is_system": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "isSystem",
+						},
+						"user_count": {
+							Type:        schema.TypeInt,
+							Computed:    true,
+							Description: "Number of users in the group.",
+						},
+						"created_at": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "createdAt",
+						},
+						"updated_at": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "updatedAt",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceGroupsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+
+	data, err := c.GetGroupList(ctx)
+	if err != nil {
+		return apiErrorDiagnostics("Failed to list groups", err)
+	}
+	list := data.Groups.List
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+
+	var nameRegex *regexp.Regexp
+	if v, ok := d.GetOk("name_regex"); ok {
+		nameRegex = regexp.MustCompile(v.(string))
+	}
+	// GetOk cannot tell false from unset.
+	isSystem := d.GetRawConfig().GetAttr("is_system")
+	permission := d.Get("has_permission").(string)
+
+	filtered := list[:0]
+	for _, g := range list {
+		if nameRegex != nil && !nameRegex.MatchString(string(g.Name)) {
+			continue
+		}
+		if !isSystem.IsNull() && isSystem.True() != bool(g.IsSystem) {
+			continue
+		}
+		filtered = append(filtered, g)
+	}
+	var permissions map[GroupID][]string
+	if permission != "" {
+		// The list does not include the permissions, only the groups left by the other filters are read.
+		ids := make([]GroupID, len(filtered))
+		for i, g := range filtered {
+			ids[i] = GroupID(g.Id)
+		}
+		if permissions, err = getGroupPermissions(ctx, c, ids); err != nil {
+			return apiErrorDiagnostics("Failed to read the permissions of the groups", err)
+		}
+	}
+
+	groups := make([]interface{}, 0, len(filtered))
+	for _, g := range filtered {
+		if permission != "" && !slices.Contains(permissions[GroupID(g.Id)], permission) {
+			continue
+		}
+		groups = append(groups, map[string]interface{}{
+			"id":         int(g.Id),
+			"name":       string(g.Name),
+			"is_system":  bool(g.IsSystem),
+			"user_count": int(g.UserCount),
+			"created_at": string(g.CreatedAt),
+			"updated_at": string(g.UpdatedAt),
+		})
+	}
+	if err := d.Set("groups", groups); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+
+	return diags
+}
+
+// groupPermissionsConcurrency bounds how many groups wikijs_groups reads at once. The rate limit of the provider
+// applies on top of it.
+const groupPermissionsConcurrency = 8
+
+// getGroupPermissions reads the permissions of the given groups, groupPermissionsConcurrency at a time. The groups
+// deleted since they were listed are left out of the result rather than failing the read.
+func getGroupPermissions(ctx context.Context, c *Client, ids []GroupID) (map[GroupID][]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	permissions := make(map[GroupID][]string, len(ids))
+	slots := make(chan struct{}, groupPermissionsConcurrency)
+	for _, id := range ids {
+		id := id
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			group, err := c.GetGroup(ctx, id)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case apierror.Is(err, apierror.NotFound):
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to read group %s: %w", id, err)
+					cancel()
+				}
+			default:
+				permissions[id] = gqlcStringArrayToStringArray(group.Groups.Single.Permissions)
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return permissions, ctx.Err()
+}
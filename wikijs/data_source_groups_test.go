@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// testDataSourceRead reads a data source the way Terraform does, with the raw configuration available to it.
+func testDataSourceRead(t *testing.T, r *schema.Resource, raw map[string]interface{}, meta interface{}) (*terraform.InstanceState, diag.Diagnostics) {
+	t.Helper()
+	diff, err := schema.InternalMap(r.Schema).Diff(context.Background(), nil, terraform.NewResourceConfigRaw(raw), nil, meta, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil {
+		diff = &terraform.InstanceDiff{}
+	}
+	config, _ := json.Marshal(raw)
+	if diff.RawConfig, err = ctyjson.Unmarshal(config, r.CoreConfigSchema().ImpliedType()); err != nil {
+		t.Fatal(err)
+	}
+	return r.ReadDataApply(context.Background(), diff, meta)
+}
+
+// groupNames returns the names of the groups read by the wikijs_groups data source.
+func groupNames(state *terraform.InstanceState) string {
+	var names []string
+	for i := 0; ; i++ {
+		name, ok := state.Attributes["groups."+strconv.Itoa(i)+".name"]
+		if !ok {
+			return strings.Join(names, ",")
+		}
+		names = append(names, name)
+	}
+}
+
+func TestDataSourceGroupsRead(t *testing.T) {
+	srv, c := testServerClient(t)
+	writers := srv.AddGroup(testserver.Group{Name: "writers", Permissions: []string{"read:pages", "write:pages"}})
+	srv.AddGroup(testserver.Group{Name: "readers", Permissions: []string{"read:pages"}})
+	srv.AddGroup(testserver.Group{Name: "reviewers", Permissions: []string{"read:pages", "write:pages"}})
+	srv.AssignUser(writers.ID, 1)
+
+	for _, tc := range []struct {
+		config   map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{}, "Administrators,Guests,writers,readers,reviewers"},
+		{map[string]interface{}{"name_regex": "^re"}, "readers,reviewers"},
+		{map[string]interface{}{"is_system": true}, "Administrators,Guests"},
+		{map[string]interface{}{"is_system": false}, "writers,readers,reviewers"},
+		{map[string]interface{}{"has_permission": "write:pages"}, "writers,reviewers"},
+		{map[string]interface{}{"has_permission": "write:pages", "name_regex": "ers$", "is_system": false}, "writers,reviewers"},
+		{map[string]interface{}{"name_regex": "nothing"}, ""},
+	} {
+		state, diags := testDataSourceRead(t, dataSourceGroups(), tc.config, c)
+		if diags.HasError() {
+			t.Fatalf("%v: unexpected diagnostics: %v", tc.config, diags)
+		}
+		if got := groupNames(state); got != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.config, tc.expected, got)
+		}
+	}
+
+	state, _ := testDataSourceRead(t, dataSourceGroups(), map[string]interface{}{"name_regex": "^writers$"}, c)
+	if state.Attributes["groups.0.id"] != strconv.Itoa(writers.ID) || state.Attributes["groups.0.user_count"] != "1" ||
+		state.Attributes["groups.0.is_system"] != "false" || state.Attributes["groups.0.created_at"] == "" {
+		t.Fatalf("unexpected attributes %v", state.Attributes)
+	}
+	// The permissions are only read for has_permission: the 5 groups when alone, the 3 left by the other filters.
+	if n := srv.RequestCount("groups.single"); n != 8 {
+		t.Fatalf("expected the permissions of the filtered groups only to be read, got %d group reads", n)
+	}
+
+	// A group deleted between the list and its read is left out.
+	srv.InjectFault(testserver.Fault{Operation: "groups.single", Count: 1, Before: func() { srv.DeleteGroup(writers.ID) }})
+	state, diags := testDataSourceRead(t, dataSourceGroups(), map[string]interface{}{"has_permission": "write:pages"}, c)
+	if diags.HasError() || groupNames(state) != "reviewers" {
+		t.Fatalf("expected the deleted group to be left out, got %s, %v", groupNames(state), diags)
+	}
+}
+
+func TestDataSourceGroupsValidate(t *testing.T) {
+	diags := dataSourceGroups().Validate(terraform.NewResourceConfigRaw(map[string]interface{}{"has_permission": "write:page"}))
+	if !diags.HasError() || !strings.Contains(diags[0].Detail+diags[0].Summary, "write:pages") {
+		t.Fatalf("expected an unknown permission to be refused with a suggestion, got %v", diags)
+	}
+}
+
+func TestAccDataSourceGroups(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceGroups,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.wikijs_groups.admins", "groups.#", "1"),
+					resource.TestCheckResourceAttr("data.wikijs_groups.admins", "groups.0.id", "1"),
+					resource.TestMatchResourceAttr("data.wikijs_groups.system", "groups.#", regexp.MustCompile("^2$")),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceGroups = `
+data "wikijs_groups" "admins" {
+  name_regex     = "^Administrators$"
+  has_permission = "manage:system"
+}
+
+data "wikijs_groups" "system" {
+  is_system = true
+}
+`
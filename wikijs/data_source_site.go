@@ -19,6 +19,10 @@ func dataSourceSite() *schema.Resource {
 
 		ReadContext: dataSourceSiteRead,
 
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(1 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"host": {
 				Description: "Wikijs host",
@@ -38,9 +42,9 @@ func dataSourceSiteRead(ctx context.Context, d *schema.ResourceData, meta interf
 	var diags diag.Diagnostics
 	c := meta.(*Client)
 
-	data, err := c.GetSite()
+	data, err := c.GetSite(ctx)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiErrorDiagnostics("Failed to read site configuration", err)
 	}
 	if err := d.Set("host", data.Site.Config.Host); err != nil {
 		return diag.FromErr(err)
@@ -5,14 +5,19 @@
 package wikijs
 
 import (
+	"context"
+	"net/http"
 	"os"
 	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
 )
 
 func TestAccDataSourceSite(t *testing.T) {
+	testAccPreCheck(t)
 	host := os.Getenv("WIKIJS_HOST")
 	resource.UnitTest(t, resource.TestCase{
 		PreCheck:          func() { testAccPreCheck(t) },
@@ -33,3 +38,16 @@ const testAccDataSourceSite = `
 data "wikijs_site_data_source" "test" {
 }
 `
+
+func TestDataSourceSiteRead(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.InjectFault(testserver.Fault{Operation: "site", Status: http.StatusServiceUnavailable, Count: 1})
+
+	d := schema.TestResourceDataRaw(t, dataSourceSite().Schema, map[string]interface{}{})
+	if diags := dataSourceSiteRead(context.Background(), d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if d.Get("host") != srv.URL || d.Get("title") != "Wiki.js" {
+		t.Fatalf("unexpected site %v, %v", d.Get("host"), d.Get("title"))
+	}
+}
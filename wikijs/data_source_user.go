@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/exp/slices"
+	"time"
+)
+
+// userCreateOnlyAttributes are the attributes of wikijs_user that Wiki.js does not return.
+var userCreateOnlyAttributes = []string{"password", "must_change_password", "send_welcome_email", "replace_user_id"}
+
+func dataSourceUser() *schema.Resource {
+	// The attributes are those wikijs_user reads back from Wiki.js.
+	attributes := map[string]*schema.Schema{}
+	for name, s := range resourceUser().Schema {
+		if slices.Contains(userCreateOnlyAttributes, name) {
+			continue
+		}
+		attribute := &schema.Schema{Type: s.Type, Computed: true, Description: s.Description}
+		if elem, ok := s.Elem.(*schema.Schema); ok {
+			attribute.Elem = &schema.Schema{Type: elem.Type}
+		}
+		attributes[name] = attribute
+	}
+	attributes["id"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ExactlyOneOf: []string{"id", "email"},
+		Description:  "Id of the user to read.",
+	}
+	attributes["email"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Computed:     true,
+		ValidateFunc: validation.StringMatch(emailPattern, "must be an email address"),
+		Description:  "Email of the user to read, compared case-insensitively.",
+	}
+
+	return &schema.Resource{
+		Description: "Reads a Wiki.js user, found by id or by email, e.g. to add it to a group.",
+
+		ReadContext: dataSourceUserRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(1 * time.Minute),
+		},
+
+		Schema: attributes,
+	}
+}
+
+func dataSourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+
+	var id UserID
+	if v, ok := d.GetOk("id"); ok {
+		parsed, err := ParseUserID(v.(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		id = parsed
+	} else {
+		email := d.Get("email").(string)
+		found, err := c.FindUserByEmail(ctx, email)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to find user %s", email), err)
+		}
+		id = found
+	}
+
+	user, err := c.GetUser(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read user %s", id), err)
+	}
+	d.SetId(id.String())
+	return flattenUser(d, *user)
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestDataSourceUserRead(t *testing.T) {
+	srv, c := testServerClient(t)
+	alice := srv.AddUser(testserver.User{Email: "alice@example.com", Name: "Alice", JobTitle: "Writer", IsActive: true})
+	editors := srv.AddGroup(testserver.Group{Name: "editors"})
+	srv.AssignUser(editors.ID, alice.ID)
+
+	for _, config := range []map[string]interface{}{
+		{"id": strconv.Itoa(alice.ID)},
+		{"email": "Alice@Example.com"},
+	} {
+		state, diags := testDataSourceRead(t, dataSourceUser(), config, c)
+		if diags.HasError() {
+			t.Fatalf("%v: unexpected diagnostics: %v", config, diags)
+		}
+		if state.ID != strconv.Itoa(alice.ID) || state.Attributes["email"] != "alice@example.com" ||
+			state.Attributes["job_title"] != "Writer" || state.Attributes["group_ids.#"] != "1" ||
+			state.Attributes["is_active"] != "true" || state.Attributes["is_verified"] != "false" {
+			t.Fatalf("%v: unexpected attributes %v", config, state.Attributes)
+		}
+	}
+
+	_, diags := testDataSourceRead(t, dataSourceUser(), map[string]interface{}{"email": "bob@example.com"}, c)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "no user has the email") {
+		t.Fatalf("expected an unknown email to fail, got %v", diags)
+	}
+}
+
+func TestAccDataSourceUser(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceUser,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.wikijs_user.administrator", "email", "admin@example.com"),
+					resource.TestCheckResourceAttr("data.wikijs_user.administrator", "provider_key", "local"),
+					resource.TestCheckTypeSetElemAttr("data.wikijs_user.administrator", "group_ids.*", "1"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceUser = `
+data "wikijs_user" "administrator" {
+  id = "1"
+}
+`
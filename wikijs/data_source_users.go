@@ -0,0 +1,337 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	"golang.org/x/exp/slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func dataSourceUsers() *schema.Resource {
+	return &schema.Resource{
+		Description: "Lists the Wiki.js users, optionally searched and filtered, e.g. to add the users of an " +
+			"authentication provider to a group, or to find the accounts nobody used for a while.",
+
+		ReadContext: dataSourceUsersRead,
+
+		Timeouts: &schema.ResourceTimeout{
+			Read: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"search": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsNotWhiteSpace,
+				Description:  "Only return the users whose name or email contains this text, case-insensitively.",
+			},
+			"provider_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return the users of this authentication provider, e.g. `local`.",
+			},
+			"is_active": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return the active users when true, or only the deactivated ones when false.",
+			},
+			"is_system": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return the system users, such as the guest, when true, or only the other users when false.",
+			},
+			"group_id": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Only return the members of this group.",
+			},
+			"last_login_within": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validateDuration,
+				Description:      "Only return the users who logged in within this duration, e.g. `720h` for 30 days.",
+			},
+			"last_login_older_than": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateDiagFunc: validateDuration,
+				Description:      "Only return the users who did not log in within this duration, e.g. `2160h` for 90 days, including those who never logged in.",
+			},
+			"include_details": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				Description: "Whether to set `is_verified`, `tfa_active` and `group_ids` of the users. The list of users " +
+					"does not include them, so each user left by the filters is read, " +
+					fmt.Sprintf("%d at a time.", userDetailsConcurrency),
+			},
+			"ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Ids of the users, in ascending order.",
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+			},
+			"users": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The users, ordered by id.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"email": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"provider_key": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"is_system": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"is_active": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"is_verified": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the user verified its email, only set with `include_details`.",
+						},
+						"tfa_active": {
+							Type:     schema.TypeBool,
+							Computed: true,
+							Description: "Whether the user logs in with two-factor authentication, only set with " +
+								"`include_details`.",
+						},
+						"group_ids": {
+							Type:        schema.TypeSet,
+							Computed:    true,
+							Description: "Ids of the groups of the user, only set with `include_details`.",
+							Elem: &schema.Schema{
+								Type: schema.TypeInt,
+							},
+						},
+						"created_at": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"last_login_at": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "Time of the last login of the user, empty if it never logged in.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// validateDuration checks a duration in the syntax of Go, e.g. `36h` or `90m`.
+func validateDuration(v interface{}, path cty.Path) diag.Diagnostics {
+	d, err := time.ParseDuration(v.(string))
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       fmt.Sprintf("Invalid duration %q", v),
+			Detail:        fmt.Sprintf("%s. Use hours, minutes or seconds, e.g. `720h` for 30 days.", err),
+			AttributePath: path,
+		}}
+	}
+	return nil
+}
+
+func dataSourceUsersRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+
+	var list []wjSchema.UserMinimal
+	var err error
+	if search, ok := d.GetOk("search"); ok {
+		list, err = c.SearchAllUsers(ctx, search.(string))
+	} else {
+		list, err = c.GetUserList(ctx)
+	}
+	if err != nil {
+		return apiErrorDiagnostics("Failed to list users", err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+
+	var members []UserID
+	if groupID, ok := d.GetOk("group_id"); ok {
+		members, err = c.GetGroupMembers(ctx, GroupID(groupID.(int)))
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to read the members of group %d", groupID), err)
+		}
+	}
+	providerKey := d.Get("provider_key").(string)
+	// GetOk cannot tell false from unset.
+	isActive := d.GetRawConfig().GetAttr("is_active")
+	isSystem := d.GetRawConfig().GetAttr("is_system")
+	now := time.Now()
+	within, _ := time.ParseDuration(d.Get("last_login_within").(string))
+	olderThan, _ := time.ParseDuration(d.Get("last_login_older_than").(string))
+
+	var selected []wjSchema.UserMinimal
+	for _, u := range list {
+		if members != nil && !slices.Contains(members, UserID(u.Id)) {
+			continue
+		}
+		if providerKey != "" && string(u.ProviderKey) != providerKey {
+			continue
+		}
+		if !isActive.IsNull() && isActive.True() != bool(u.IsActive) {
+			continue
+		}
+		if !isSystem.IsNull() && isSystem.True() != bool(u.IsSystem) {
+			continue
+		}
+		if within > 0 || olderThan > 0 {
+			lastLogin, loggedIn := parseLastLogin(u.LastLoginAt)
+			if within > 0 && (!loggedIn || now.Sub(lastLogin) > within) {
+				continue
+			}
+			if olderThan > 0 && loggedIn && now.Sub(lastLogin) <= olderThan {
+				continue
+			}
+		}
+		selected = append(selected, u)
+	}
+
+	// The list does not include the verification, two-factor authentication and groups, they are only read when
+	// asked for, for the users left by the filters.
+	includeDetails := d.Get("include_details").(bool)
+	var details map[UserID]*wjSchema.User
+	if includeDetails {
+		if details, err = getUserDetails(ctx, c, selected); err != nil {
+			return apiErrorDiagnostics("Failed to read users", err)
+		}
+	}
+
+	ids := make([]int, 0, len(selected))
+	users := make([]interface{}, 0, len(selected))
+	for _, u := range selected {
+		user := map[string]interface{}{
+			"id":            int(u.Id),
+			"name":          string(u.Name),
+			"email":         string(u.Email),
+			"provider_key":  string(u.ProviderKey),
+			"is_system":     bool(u.IsSystem),
+			"is_active":     bool(u.IsActive),
+			"created_at":    string(u.CreatedAt),
+			"last_login_at": string(u.LastLoginAt),
+		}
+		if includeDetails {
+			detail, ok := details[UserID(u.Id)]
+			if !ok {
+				// Deleted since it was listed.
+				continue
+			}
+			groupIDs := make([]int, len(detail.Groups))
+			for i, g := range detail.Groups {
+				groupIDs[i] = int(g.Id)
+			}
+			user["is_verified"] = bool(detail.IsVerified)
+			user["tfa_active"] = bool(detail.TfaIsActive)
+			user["group_ids"] = groupIDs
+		}
+		ids = append(ids, int(u.Id))
+		users = append(users, user)
+	}
+	if err := d.Set("ids", ids); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("users", users); err != nil {
+		return diag.FromErr(err)
+	}
+
+	d.SetId(strconv.FormatInt(time.Now().Unix(), 10))
+
+	return diags
+}
+
+// userDetailsConcurrency bounds how many users wikijs_users reads at once. The rate limit of the provider
+// applies on top of it.
+const userDetailsConcurrency = 8
+
+// getUserDetails reads the given users, userDetailsConcurrency at a time. The users deleted since they were
+// listed are left out of the result rather than failing the read.
+func getUserDetails(ctx context.Context, c *Client, users []wjSchema.UserMinimal) (map[UserID]*wjSchema.User, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	details := make(map[UserID]*wjSchema.User, len(users))
+	slots := make(chan struct{}, userDetailsConcurrency)
+	for _, u := range users {
+		id := UserID(u.Id)
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			user, err := c.GetUser(ctx, id)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case apierror.Is(err, apierror.NotFound):
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to read user %s: %w", id, err)
+					cancel()
+				}
+			default:
+				details[id] = user
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return details, ctx.Err()
+}
+
+// parseLastLogin returns the time of the last login of a user, and false if it never logged in.
+func parseLastLogin(lastLoginAt wjSchema.Date) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, string(lastLoginAt))
+	return t, err == nil
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// userNames returns the names of the users read by the wikijs_users data source.
+func userNames(state *terraform.InstanceState) string {
+	var names []string
+	for i := 0; ; i++ {
+		name, ok := state.Attributes["users."+strconv.Itoa(i)+".name"]
+		if !ok {
+			return strings.Join(names, ",")
+		}
+		names = append(names, name)
+	}
+}
+
+func TestDataSourceUsersRead(t *testing.T) {
+	srv, c := testServerClient(t)
+	now := time.Now()
+	yesterday, lastYear := now.Add(-24*time.Hour), now.Add(-365*24*time.Hour)
+	alice := srv.AddUser(testserver.User{Email: "alice@example.com", Name: "Alice", IsActive: true, IsVerified: true,
+		LastLoginAt: &yesterday})
+	srv.AddUser(testserver.User{Email: "bob@corp.example.com", Name: "Bob", ProviderKey: "oidc", IsActive: true,
+		LastLoginAt: &lastYear})
+	srv.AddUser(testserver.User{Email: "carol@corp.example.com", Name: "Carol", ProviderKey: "oidc"})
+	editors := srv.AddGroup(testserver.Group{Name: "editors"})
+	srv.AssignUser(editors.ID, alice.ID)
+	srv.AssignUser(editors.ID, 1)
+
+	for _, tc := range []struct {
+		config   map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{}, "Administrator,Guest,Alice,Bob,Carol"},
+		{map[string]interface{}{"search": "CORP"}, "Bob,Carol"},
+		{map[string]interface{}{"provider_key": "oidc"}, "Bob,Carol"},
+		{map[string]interface{}{"is_active": false}, "Carol"},
+		{map[string]interface{}{"is_active": true, "is_system": false}, "Administrator,Alice,Bob"},
+		{map[string]interface{}{"is_system": true}, "Guest"},
+		{map[string]interface{}{"group_id": editors.ID}, "Administrator,Alice"},
+		{map[string]interface{}{"last_login_within": "720h"}, "Alice"},
+		{map[string]interface{}{"last_login_older_than": "720h"}, "Administrator,Guest,Bob,Carol"},
+		{map[string]interface{}{"last_login_older_than": "720h", "provider_key": "oidc", "is_active": true}, "Bob"},
+		{map[string]interface{}{"search": "nobody"}, ""},
+	} {
+		state, diags := testDataSourceRead(t, dataSourceUsers(), tc.config, c)
+		if diags.HasError() {
+			t.Fatalf("%v: unexpected diagnostics: %v", tc.config, diags)
+		}
+		if got := userNames(state); got != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.config, tc.expected, got)
+		}
+	}
+
+	state, _ := testDataSourceRead(t, dataSourceUsers(), map[string]interface{}{"search": "alice", "include_details": true}, c)
+	if state.Attributes["ids.0"] != strconv.Itoa(alice.ID) || state.Attributes["users.0.is_verified"] != "true" ||
+		state.Attributes["users.0.tfa_active"] != "false" || state.Attributes["users.0.group_ids.#"] != "1" ||
+		state.Attributes["users.0.last_login_at"] == "" {
+		t.Fatalf("unexpected attributes %v", state.Attributes)
+	}
+	// The details are only read with include_details, for alice alone.
+	if n := srv.RequestCount("users.single"); n != 1 {
+		t.Fatalf("expected the details of the users to be read only when asked for, got %d user reads", n)
+	}
+}
+
+func TestDataSourceUsersSearchTruncated(t *testing.T) {
+	srv, c := testServerClient(t)
+	for i := 0; i < 12; i++ {
+		srv.AddUser(testserver.User{Email: fmt.Sprintf("user%d@example.com", i), Name: "Team member"})
+	}
+	state, diags := testDataSourceRead(t, dataSourceUsers(), map[string]interface{}{"search": "team"}, c)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if state.Attributes["ids.#"] != "12" {
+		t.Fatalf("expected the 12 users beyond the limit of users.search, got %s", state.Attributes["ids.#"])
+	}
+}
+
+func TestDataSourceUsersDetails(t *testing.T) {
+	srv, c := testServerClient(t)
+	var last testserver.User
+	for i := 0; i < 20; i++ {
+		last = srv.AddUser(testserver.User{Email: fmt.Sprintf("user%d@example.com", i), Name: fmt.Sprintf("User %02d", i)})
+	}
+
+	// A user deleted between the list and the read of its details is left out. The last user is read once the
+	// first reads are over, userDetailsConcurrency being lower than the number of users.
+	srv.InjectFault(testserver.Fault{Operation: "users.single", Count: 1, Before: func() { srv.DeleteUser(last.ID) }})
+	details := map[string]interface{}{"search": "user", "include_details": true}
+	state, diags := testDataSourceRead(t, dataSourceUsers(), details, c)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if state.Attributes["ids.#"] != "19" {
+		t.Fatalf("expected the deleted user to be skipped, got %s users", state.Attributes["ids.#"])
+	}
+	for i := 1; i < 19; i++ {
+		if state.Attributes[fmt.Sprintf("users.%d.name", i-1)] >= state.Attributes[fmt.Sprintf("users.%d.name", i)] {
+			t.Fatalf("expected the users to stay ordered by id, got %v", userNames(state))
+		}
+	}
+
+	srv.InjectFault(testserver.Forbidden("users.single"))
+	if _, diags := testDataSourceRead(t, dataSourceUsers(), details, c); !diags.HasError() {
+		t.Fatal("expected a failure to read the details of a user to fail the read")
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	for value, valid := range map[string]bool{"720h": true, "90m": true, "30d": false, "-1h": false, "0s": false} {
+		if diags := validateDuration(value, nil); diags.HasError() == valid {
+			t.Errorf("%s: expected valid to be %t, got %v", value, valid, diags)
+		}
+	}
+}
+
+func TestAccDataSourceUsers(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceUsers,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.wikijs_users.administrators", "ids.0", "1"),
+					resource.TestCheckResourceAttr("data.wikijs_users.system", "users.0.is_system", "true"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceUsers = `
+data "wikijs_users" "administrators" {
+  group_id  = 1
+  is_active = true
+}
+
+data "wikijs_users" "system" {
+  is_system = true
+}
+`
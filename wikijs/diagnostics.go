@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"strings"
+)
+
+// apiErrorDiagnostics turns an error returned by the Client into a diagnostic, with a hint on how to fix the
+// failures Wiki.js reports in a typed way.
+func apiErrorDiagnostics(summary string, err error) diag.Diagnostics {
+	detail := err.Error()
+	switch {
+	case apierror.Is(err, apierror.Forbidden):
+		detail += "\n\nCheck that the credentials are valid and grant the permissions needed to manage this object."
+	case apierror.Is(err, apierror.Validation):
+		detail += "\n\nWiki.js rejected the values in the configuration."
+	case apierror.Is(err, apierror.Conflict):
+		detail += "\n\nThe change conflicts with an object that already exists in Wiki.js."
+	case apierror.Is(err, apierror.NotFound):
+		detail += "\n\nThe object does not exist in Wiki.js."
+	}
+	return diag.Diagnostics{{Severity: diag.Error, Summary: summary, Detail: detail}}
+}
+
+// invalidIDDiagnostics reports an id, from the state or an import, that is not a valid Wiki.js id.
+func invalidIDDiagnostics(err error) diag.Diagnostics {
+	return diag.Diagnostics{{Severity: diag.Error, Summary: "Invalid id",
+		Detail: err.Error() + "\n\nWiki.js ids are positive integers, check the id given to `terraform import`."}}
+}
+
+// errorList returns the problems found by a CustomizeDiffFunc as a single error, one problem per line, or nil
+// when there are none.
+func errorList(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "\n"))
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GroupID is the id of a Wiki.js group. Terraform stores it as a string, see ParseGroupID and String.
+type GroupID int
+
+// ParseGroupID parses a group id as stored in the Terraform state or given to `terraform import`.
+func ParseGroupID(s string) (GroupID, error) {
+	id, err := parseID("group", s)
+	return GroupID(id), err
+}
+
+// String formats the id the way it is stored in the Terraform state.
+func (id GroupID) String() string {
+	return strconv.Itoa(int(id))
+}
+
+// UserID is the id of a Wiki.js user.
+type UserID int
+
+// ParseUserID parses a user id as stored in the Terraform state or given to `terraform import`.
+func ParseUserID(s string) (UserID, error) {
+	id, err := parseID("user", s)
+	return UserID(id), err
+}
+
+// String formats the id the way it is stored in the Terraform state.
+func (id UserID) String() string {
+	return strconv.Itoa(int(id))
+}
+
+// parseID parses the id of a Wiki.js object of the given kind. Wiki.js ids are positive 32 bit integers.
+func parseID(kind string, s string) (int, error) {
+	id, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s id %q: expected a positive integer", kind, s)
+	}
+	return int(id), nil
+}
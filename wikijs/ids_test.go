@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"testing"
+)
+
+func TestParseGroupID(t *testing.T) {
+	for _, s := range []string{"1", "42", "2147483647"} {
+		id, err := ParseGroupID(s)
+		if err != nil || id.String() != s {
+			t.Errorf("%q: expected the id to round trip, got %v (%v)", s, id, err)
+		}
+	}
+	for _, s := range []string{"", "abc", "0", "-3", "1.5", " 1", "2147483648", "Administrators"} {
+		if _, err := ParseGroupID(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
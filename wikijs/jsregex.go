@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// jsQuantifierBraces matches the braces JavaScript reads as a quantifier, any other brace is a literal.
+var jsQuantifierBraces = regexp.MustCompile(`^\{\d+(,\d*)?\}`)
+
+// checkPageRuleRegex checks that Wiki.js accepts pattern as the path of a REGEX page rule. Wiki.js compiles it
+// with `new RegExp(path)`, so it must be a valid JavaScript regular expression, and rejects the patterns whose
+// run time can grow exponentially, which are those nesting quantifiers such as `(a+)+`.
+//
+// The pattern is checked by compiling it with Go, after translating the JavaScript syntax RE2 does not support:
+// lookarounds and backreferences only change what matches, not whether the pattern is valid, so they are
+// replaced by plain groups. The RE2 syntax JavaScript does not support, such as inline flags, is rejected.
+func checkPageRuleRegex(pattern string) error {
+	translated, _, err := translateJSRegex(pattern)
+	if err != nil {
+		return err
+	}
+	if _, err := regexp.Compile(translated); err != nil {
+		return fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	return nil
+}
+
+// compileJSRegex compiles the path of a REGEX page rule to match page paths like Wiki.js does. The patterns using
+// lookarounds or backreferences are rejected: their translation only keeps the syntax, not what they match.
+func compileJSRegex(pattern string) (*regexp.Regexp, error) {
+	translated, approximated, err := translateJSRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if approximated {
+		return nil, fmt.Errorf("lookarounds and backreferences cannot be evaluated outside of Wiki.js")
+	}
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+	return re, nil
+}
+
+// translateJSRegex returns the RE2 equivalent of a JavaScript pattern. It is approximated when the pattern uses
+// lookarounds or backreferences, which are replaced by plain groups.
+func translateJSRegex(pattern string) (translated string, approximated bool, err error) {
+	var out strings.Builder
+	// quantified tells for every open group, the outermost pattern included, whether it contains a quantifier.
+	quantified := []bool{false}
+	afterQuantifiedGroup := false
+	for i := 0; i < len(pattern); {
+		closedQuantifiedGroup := afterQuantifiedGroup
+		afterQuantifiedGroup = false
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(pattern) {
+				return "", false, fmt.Errorf("\\ at end of pattern")
+			}
+			next := pattern[i+1]
+			switch {
+			case next >= '1' && next <= '9':
+				j := i + 1
+				for j < len(pattern) && pattern[j] >= '0' && pattern[j] <= '9' {
+					j++
+				}
+				out.WriteString("(?:)")
+				i = j
+			case next == 'k' && strings.HasPrefix(pattern[i+2:], "<"):
+				end := strings.IndexByte(pattern[i:], '>')
+				if end < 0 {
+					return "", false, fmt.Errorf("invalid named reference %s", pattern[i:])
+				}
+				out.WriteString("(?:)")
+				approximated = true
+				i += end + 1
+			default:
+				out.WriteString(pattern[i : i+2])
+				i += 2
+			}
+		case c == '[':
+			end, class, err := translateJSClass(pattern, i)
+			if err != nil {
+				return "", false, err
+			}
+			out.WriteString(class)
+			i = end
+		case c == '(':
+			group, length, err := translateJSGroup(pattern[i:])
+			if err != nil {
+				return "", false, err
+			}
+			approximated = approximated || strings.HasPrefix(pattern[i:], "(?=") || strings.HasPrefix(pattern[i:], "(?!") ||
+				strings.HasPrefix(pattern[i:], "(?<=") || strings.HasPrefix(pattern[i:], "(?<!")
+			out.WriteString(group)
+			quantified = append(quantified, false)
+			i += length
+		case c == ')':
+			if len(quantified) == 1 {
+				return "", false, fmt.Errorf("unmatched ) at position %d", i)
+			}
+			inner := quantified[len(quantified)-1]
+			quantified = quantified[:len(quantified)-1]
+			if inner {
+				quantified[len(quantified)-1] = true
+			}
+			out.WriteByte(')')
+			afterQuantifiedGroup = inner
+			i++
+		case c == '*' || c == '+' || c == '?' || (c == '{' && jsQuantifierBraces.MatchString(pattern[i:])):
+			if closedQuantifiedGroup {
+				return "", false, fmt.Errorf("nested quantifier at position %d: Wiki.js rejects patterns with exponential run time", i)
+			}
+			quantified[len(quantified)-1] = true
+			length := 1
+			if c == '{' {
+				length = len(jsQuantifierBraces.FindString(pattern[i:]))
+			}
+			if i+length < len(pattern) && pattern[i+length] == '?' {
+				length++
+			}
+			out.WriteString(pattern[i : i+length])
+			i += length
+		case c == '{':
+			out.WriteString(`\{`)
+			i++
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	if len(quantified) > 1 {
+		return "", false, fmt.Errorf("missing closing )")
+	}
+	return out.String(), approximated, nil
+}
+
+// translateJSGroup translates the opening of the group at the start of pattern, returning the RE2 opening and
+// the length of the JavaScript one.
+func translateJSGroup(pattern string) (string, int, error) {
+	switch {
+	case !strings.HasPrefix(pattern, "(?"):
+		return "(", 1, nil
+	case strings.HasPrefix(pattern, "(?:"), strings.HasPrefix(pattern, "(?="), strings.HasPrefix(pattern, "(?!"):
+		return "(?:", 3, nil
+	case strings.HasPrefix(pattern, "(?<="), strings.HasPrefix(pattern, "(?<!"):
+		return "(?:", 4, nil
+	case strings.HasPrefix(pattern, "(?<"):
+		end := strings.IndexByte(pattern, '>')
+		if end < 0 {
+			return "", 0, fmt.Errorf("invalid capture group name in %s", pattern)
+		}
+		return "(?P" + pattern[2:end+1], end + 1, nil
+	}
+	end := len(pattern)
+	if end > 6 {
+		end = 6
+	}
+	return "", 0, fmt.Errorf("invalid group %s...: JavaScript only supports (?:, (?=, (?!, (?<=, (?<! and (?<name>", pattern[:end])
+}
+
+// translateJSClass translates the character class starting at start, returning the position following it.
+func translateJSClass(pattern string, start int) (int, string, error) {
+	// JavaScript allows empty classes, [] matches nothing and [^] matches any character.
+	switch {
+	case strings.HasPrefix(pattern[start:], "[]"):
+		return start + 2, `[^\x00-\x{10FFFF}]`, nil
+	case strings.HasPrefix(pattern[start:], "[^]"):
+		return start + 3, `[\x00-\x{10FFFF}]`, nil
+	}
+	for i := start + 1; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i + 1, pattern[start : i+1], nil
+		}
+	}
+	return 0, "", fmt.Errorf("missing closing ] in %s", pattern[start:])
+}
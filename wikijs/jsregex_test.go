@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPageRuleRegex(t *testing.T) {
+	for _, pattern := range []string{
+		`^docs/.*$`,
+		`^(en|fr)/guides?/`,
+		`^team-[a-z]+/(?!private/)`,
+		`(?<=docs/)drafts`,
+		`^(?<section>[a-z]+)/\k<section>$`,
+		`^(a)\1$`,
+		`^docs{1}/[^]*`,
+		`{literal}`,
+		`^x{2,}y{1,3}?`,
+		`^(docs|wiki)+/`,
+		`^[)(]+\)`,
+	} {
+		if err := checkPageRuleRegex(pattern); err != nil {
+			t.Errorf("%s: unexpected error %v", pattern, err)
+		}
+	}
+
+	for pattern, expected := range map[string]string{
+		`(a+)+`:         "nested quantifier",
+		`^((ab)*c)*$`:   "nested quantifier",
+		`(x|y*){2,}`:    "nested quantifier",
+		`(?i)docs`:      "invalid group",
+		`(?P<name>a)`:   "invalid group",
+		`docs/(`:        "missing closing )",
+		`docs)`:         "unmatched )",
+		`[a-`:           "missing closing ]",
+		`*docs`:         "missing argument to repetition operator",
+		`docs\`:         "at end of pattern",
+		`[z-a]`:         "invalid character class range",
+		`a**`:           "invalid nested repetition operator",
+		`(?<name`:       "invalid capture group name",
+		`\k<unfinished`: "invalid named reference",
+	} {
+		if err := checkPageRuleRegex(pattern); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", pattern, expected, err)
+		}
+	}
+}
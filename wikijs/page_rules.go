@@ -0,0 +1,285 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+	"golang.org/x/exp/slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pageRuleMatches are the ways a page rule can match the path of a page.
+var pageRuleMatches = []string{"START", "EXACT", "END", "REGEX", "TAG"}
+
+// plannedPageRule is a page rule of a plan. Attributes whose value is not known yet are left empty.
+type plannedPageRule struct {
+	// address identifies the rule in the error messages, e.g. `page_rules[START "docs"]`: page_rules is a set,
+	// its rules have no position. It is empty for wikijs_group_page_rule, whose attributes are the rule.
+	address string
+	// id is the configured id of a page_rules block, "" when it is derived from the rule.
+	id      string
+	deny    bool
+	match   string
+	path    string
+	roles   []string
+	locales []string
+	// known is set when every attribute of the rule is known.
+	known bool
+}
+
+// key returns the pageRuleKey of the rule.
+func (rule plannedPageRule) key() string {
+	return pageRuleKey(map[string]interface{}{
+		"deny":    rule.deny,
+		"match":   rule.match,
+		"path":    rule.path,
+		"roles":   stringsToInterfaceSlice(rule.roles),
+		"locales": stringsToInterfaceSlice(rule.locales),
+	})
+}
+
+// name returns how the error messages refer to the rule, with its id when it is configured.
+func (rule plannedPageRule) name() string {
+	if rule.id == "" {
+		return rule.address
+	}
+	return fmt.Sprintf("%s (id %q)", rule.address, rule.id)
+}
+
+// attribute returns the address of an attribute of the rule in the error messages.
+func (rule plannedPageRule) attribute(name string) string {
+	if rule.address == "" {
+		return name
+	}
+	return rule.address + "." + name
+}
+
+// pageRule returns the planned rule as read from Wiki.js, without an id.
+func (rule plannedPageRule) pageRule() wjSchema.PageRule {
+	return wjSchema.PageRule{
+		Deny:    gqlc.Boolean(rule.deny),
+		Match:   wjSchema.PageRuleMatch(rule.match),
+		Roles:   stringArrayToGqlcStringArray(rule.roles),
+		Path:    gqlc.String(rule.path),
+		Locales: stringArrayToGqlcStringArray(rule.locales),
+	}
+}
+
+// plannedPageRules returns the rules of the page_rules blocks of a plan. They are read from the configuration:
+// the SDK loses the roles and the locales of the rules added to the page_rules set when reading them from the plan.
+func plannedPageRules(d *schema.ResourceDiff) []plannedPageRule {
+	return configuredPageRules(d.GetRawConfig())
+}
+
+// configuredPageRules returns the rules of the page_rules blocks of the configuration of a group.
+func configuredPageRules(config cty.Value) []plannedPageRule {
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	blocks := config.GetAttr("page_rules")
+	if blocks.IsNull() || !blocks.IsKnown() {
+		return nil
+	}
+	var rules []plannedPageRule
+	for it := blocks.ElementIterator(); it.Next(); {
+		_, block := it.Element()
+		if block.IsNull() || !block.IsKnown() {
+			continue
+		}
+		rule := plannedPageRuleFromConfig(block)
+		path := "(known after apply)"
+		if block.GetAttr("path").IsKnown() {
+			path = strconv.Quote(rule.path)
+		}
+		rule.address = fmt.Sprintf("page_rules[%s %s]", rule.match, path)
+		rule.id = ctyString(block.GetAttr("id"))
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// plannedPageRuleFromConfig returns the rule configured by a page_rules block, or by a wikijs_group_page_rule.
+func plannedPageRuleFromConfig(config cty.Value) plannedPageRule {
+	deny := config.GetAttr("deny")
+	match := ctyString(config.GetAttr("match"))
+	return plannedPageRule{
+		deny:    !deny.IsNull() && deny.IsKnown() && deny.True(),
+		match:   match,
+		path:    normalizePageRulePath(match, ctyString(config.GetAttr("path"))),
+		roles:   ctyStrings(config.GetAttr("roles")),
+		locales: ctyStrings(config.GetAttr("locales")),
+		known:   config.IsWhollyKnown(),
+	}
+}
+
+// ctyString returns the value of a string attribute of the configuration, "" when it is null or unknown.
+func ctyString(v cty.Value) string {
+	if v.IsNull() || !v.IsKnown() {
+		return ""
+	}
+	return v.AsString()
+}
+
+// ctyStrings returns the values of a list of strings of the configuration, the null and unknown ones being "".
+func ctyStrings(v cty.Value) []string {
+	if v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+	var out []string
+	for it := v.ElementIterator(); it.Next(); {
+		_, e := it.Element()
+		out = append(out, ctyString(e))
+	}
+	return out
+}
+
+// pageRuleKey returns what identifies a page rule: everything but its id. The roles and the locales are sorted,
+// their order means nothing to Wiki.js. An unset deny allows, as false does.
+func pageRuleKey(rule map[string]interface{}) string {
+	roles := interfaceSliceToStrings(rule["roles"])
+	locales := interfaceSliceToStrings(rule["locales"])
+	sort.Strings(roles)
+	sort.Strings(locales)
+	deny, _ := rule["deny"].(bool)
+	match, _ := rule["match"].(string)
+	path, _ := rule["path"].(string)
+	key, _ := json.Marshal([]interface{}{deny, match, normalizePageRulePath(match, path), roles, locales})
+	return string(key)
+}
+
+// hashPageRule is the hash of the page_rules set. It leaves the id out, so that a rule whose id is generated
+// matches its configuration, which has none.
+func hashPageRule(v interface{}) int {
+	rule, _ := v.(map[string]interface{})
+	return schema.HashString(pageRuleKey(rule))
+}
+
+// pageRuleID derives the id of a page rule from its key, for the rules whose id is not configured. The same rule
+// always gets the same id, which Terraform then keeps in the state.
+func pageRuleID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// pageRuleIDs returns the ids of the rules of a page_rules set, derived for the rules that have none.
+func pageRuleIDs(rules *schema.Set) map[string]bool {
+	ids := make(map[string]bool, rules.Len())
+	for _, raw := range rules.List() {
+		rule, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := rule["id"].(string)
+		if id == "" {
+			id = pageRuleID(pageRuleKey(rule))
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+// pageRuleInput returns the input writing back a page rule read from Wiki.js unchanged.
+func pageRuleInput(rule wjSchema.PageRule) wjSchema.PageRuleInput {
+	return wjSchema.PageRuleInput{
+		Id:      rule.Id,
+		Deny:    rule.Deny,
+		Match:   rule.Match,
+		Roles:   rule.Roles,
+		Path:    rule.Path,
+		Locales: rule.Locales,
+	}
+}
+
+// checkPageRulesDiff is the CustomizeDiffFunc validating the page_rules blocks.
+func checkPageRulesDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	return checkPageRules(ctx, meta, plannedPageRules(d))
+}
+
+// checkPageRules reports the page rules Wiki.js would reject or silently store: regular expressions it cannot
+// compile or deems unsafe, copies of a rule, roles that are not page permissions, locales that are not installed
+// and paths starting with another locale than those of their rule, see localizePageRule. The installed locales
+// are only queried when a rule restricts locales, and are not checked when they cannot be read, e.g. while the
+// Wiki.js host is not known yet.
+func checkPageRules(ctx context.Context, meta interface{}, rules []plannedPageRule) error {
+	var problems []string
+	roles := pageRuleRoles()
+	restrictsLocales := false
+	// page_rules is a set of the rules without their ids, it keeps a single copy of rules only their ids tell
+	// apart, such as those of a state upgraded from the page_rules list.
+	copied := map[string]plannedPageRule{}
+	for _, rule := range rules {
+		if rule.known {
+			if first, ok := copied[rule.key()]; ok {
+				problems = append(problems, fmt.Sprintf("%s and %s are the same rule, Wiki.js would apply it once: "+
+					"remove one of them", first.name(), rule.name()))
+			} else {
+				copied[rule.key()] = rule
+			}
+		}
+		if rule.match == "REGEX" && rule.path != "" {
+			if err := checkPageRuleRegex(rule.path); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a valid page rule regular expression: %s",
+					rule.attribute("path"), rule.path, err))
+			}
+		}
+		for i, role := range rule.roles {
+			if role != "" && !slices.Contains(roles, role) {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a permission page rules apply to, "+
+					"expected one of %s", rule.attribute(fmt.Sprintf("roles.%d", i)), role, strings.Join(roles, ", ")))
+			}
+		}
+		restrictsLocales = restrictsLocales || len(rule.locales) > 0
+	}
+
+	if c, ok := meta.(*Client); ok && restrictsLocales {
+		installed, err := c.GetInstalledLocales(ctx)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Skipping the validation of the page rule locales: %s", err))
+		}
+		for _, rule := range rules {
+			for i, locale := range rule.locales {
+				if err == nil && locale != "" && !slices.Contains(installed, locale) {
+					problems = append(problems, fmt.Sprintf("%s: locale %q is not installed in Wiki.js, "+
+						"installed locales are %s", rule.attribute(fmt.Sprintf("locales.%d", i)), locale, strings.Join(installed, ", ")))
+				}
+			}
+			if err == nil && rule.known && len(rule.locales) > 0 {
+				if _, _, lerr := localizePageRule(rule.match, rule.path, rule.locales, installed); lerr != nil {
+					problems = append(problems, fmt.Sprintf("%s: %s", rule.attribute("path"), lerr))
+				}
+			}
+		}
+	}
+	return errorList(problems)
+}
+
+func stringsToInterfaceSlice(in []string) []interface{} {
+	out := make([]interface{}, len(in))
+	for i, v := range in {
+		out[i] = v
+	}
+	return out
+}
+
+func interfaceSliceToStrings(in interface{}) []string {
+	values, _ := in.([]interface{})
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		s, _ := v.(string)
+		out = append(out, s)
+	}
+	return out
+}
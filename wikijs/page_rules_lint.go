@@ -0,0 +1,412 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-cty/cty"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/go-cty/cty/msgpack"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	"golang.org/x/exp/slices"
+	"regexp/syntax"
+	"strings"
+)
+
+// lintProbeSegment is a path segment no rule is expected to mention. Pages under a rule are probed with it.
+const lintProbeSegment = "wikijs-lint-probe"
+
+// lintedPageRule is a page rule of a group with the name the findings about it use, e.g. its address in the
+// configuration.
+type lintedPageRule struct {
+	name string
+	rule wjSchema.PageRule
+}
+
+// lintPageRules reports the page rules of a group that are valid but unlikely to do what is meant: rules made
+// redundant by another one, deny rules that more specific allows always override, REGEX rules that cannot match
+// a page, rules matching the same pages with contradictory outcomes and rules granting a write permission without
+// the read permission it goes with. permissions are those of the group, nil when they are not known yet, which
+// skips the last check. The rules are only compared with each other: another group of a user may still grant or
+// deny the same permissions.
+func lintPageRules(rules []lintedPageRule, permissions []string) []string {
+	var findings []string
+	for i, rule := range rules {
+		findings = append(findings, lintRedundantPageRule(rules, i)...)
+		findings = append(findings, lintContradictoryPageRules(rules, i)...)
+		findings = append(findings, lintShadowedDenyPageRule(rules, i)...)
+		findings = append(findings, lintUnreachablePageRule(rule)...)
+		if permissions != nil {
+			findings = append(findings, lintWriteWithoutRead(rules, i, permissions)...)
+		}
+	}
+	return findings
+}
+
+// lintPageRulesDiff is the CustomizeDiffFunc failing the plan on the findings about the page_rules blocks with
+// strict_page_rules. Otherwise they are warnings of the validation of the configuration, see lintingProviderServer.
+func lintPageRulesDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if c, ok := meta.(*Client); !ok || !c.config.StrictPageRules {
+		return nil
+	}
+	return errorList(lintPageRulesConfig(d.GetRawConfig()))
+}
+
+// lintPageRulesConfig lints the page_rules blocks of the configuration of a group. Rules with attributes not known
+// yet are left out, and so is the check of the permissions of the group while they are not known.
+func lintPageRulesConfig(config cty.Value) []string {
+	var rules []lintedPageRule
+	for _, planned := range configuredPageRules(config) {
+		if planned.known {
+			rules = append(rules, lintedPageRule{name: planned.address, rule: planned.pageRule()})
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	var permissions []string
+	if preset, configured := config.GetAttr("preset"), config.GetAttr("permissions"); preset.IsKnown() && configured.IsWhollyKnown() {
+		permissions = append([]string{}, groupPermissions(ctyString(preset), ctyStrings(configured))...)
+	}
+	return lintPageRules(rules, permissions)
+}
+
+// lintingProviderServer reports the findings about the page_rules blocks of the group resources as warnings when
+// Terraform validates their configuration, which it does on every plan. The SDK cannot return warnings from the
+// plan itself.
+type lintingProviderServer struct {
+	tfprotov5.ProviderServer
+	provider *schema.Provider
+}
+
+// lintedResources are the resources whose page_rules blocks lintingProviderServer lints.
+var lintedResources = []string{"wikijs_group_resource", "wikijs_system_group"}
+
+func (s lintingProviderServer) ValidateResourceTypeConfig(ctx context.Context, req *tfprotov5.ValidateResourceTypeConfigRequest) (*tfprotov5.ValidateResourceTypeConfigResponse, error) {
+	resp, err := s.ProviderServer.ValidateResourceTypeConfig(ctx, req)
+	if err != nil || resp == nil || req.Config == nil || !slices.Contains(lintedResources, req.TypeName) {
+		return resp, err
+	}
+	r, ok := s.provider.ResourcesMap[req.TypeName]
+	if !ok {
+		return resp, nil
+	}
+	config, err := decodeDynamicValue(req.Config, r.CoreConfigSchema().ImpliedType())
+	if err != nil {
+		// The SDK has already reported the configuration it cannot decode.
+		return resp, nil
+	}
+	for _, finding := range lintPageRulesConfig(config) {
+		resp.Diagnostics = append(resp.Diagnostics, &tfprotov5.Diagnostic{
+			Severity:  tfprotov5.DiagnosticSeverityWarning,
+			Summary:   "Page rules may not do what is meant",
+			Detail:    finding + ". Set strict_page_rules in the provider to fail the plan instead.",
+			Attribute: tftypes.NewAttributePath().WithAttributeName("page_rules"),
+		})
+	}
+	return resp, nil
+}
+
+// decodeDynamicValue decodes a value Terraform sent, in MessagePack or, failing that, JSON.
+func decodeDynamicValue(v *tfprotov5.DynamicValue, ty cty.Type) (cty.Value, error) {
+	if len(v.MsgPack) > 0 {
+		return msgpack.Unmarshal(v.MsgPack, ty)
+	}
+	return ctyjson.Unmarshal(v.JSON, ty)
+}
+
+// lintRedundantPageRule reports the rule i when another rule with the same outcome applies to the same roles and
+// locales on all of its pages, either with the same match and path or, for START rules, a shorter path. A
+// shorter START only makes a rule redundant when no rule with the opposite outcome shares a role with it, as
+// such a rule may be what the longer one overrides.
+func lintRedundantPageRule(rules []lintedPageRule, i int) []string {
+	rule := rules[i].rule
+	for j, other := range rules {
+		if i == j || other.rule.Deny != rule.Deny || other.rule.Match != rule.Match ||
+			!pageRuleRolesCover(other.rule, rule) || !pageRuleLocalesCover(other.rule, rule) {
+			continue
+		}
+		same := other.rule.Path == rule.Path
+		if same && pageRuleRolesCover(rule, other.rule) && pageRuleLocalesCover(rule, other.rule) && j > i {
+			// Of two identical rules, the second one is reported.
+			continue
+		}
+		if !same && (rule.Match != wjSchema.PageRuleMatchStart ||
+			!strings.HasPrefix("/"+string(rule.Path), "/"+string(other.rule.Path)) || pageRuleOpposed(rules, i)) {
+			continue
+		}
+		return []string{fmt.Sprintf("%s is redundant: %s already %s %s on every page it matches",
+			rules[i].name, other.name, pageRuleVerb(rule), strings.Join(gqlcStringArrayToStringArray(rule.Roles), ", "))}
+	}
+	return nil
+}
+
+// pageRuleOpposed tells whether a rule with the opposite outcome shares a role with the rule i.
+func pageRuleOpposed(rules []lintedPageRule, i int) bool {
+	for j, other := range rules {
+		if j != i && other.rule.Deny != rules[i].rule.Deny && len(pageRuleSharedRoles(rules[i].rule, other.rule)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// lintContradictoryPageRules reports an allow rule and the deny rules with the same match and path that share
+// roles and locales with it: Wiki.js then silently applies the deny.
+func lintContradictoryPageRules(rules []lintedPageRule, i int) []string {
+	rule := rules[i].rule
+	if rule.Deny {
+		return nil
+	}
+	var findings []string
+	for _, other := range rules {
+		if !bool(other.rule.Deny) || other.rule.Match != rule.Match || other.rule.Path != rule.Path ||
+			!pageRuleLocalesOverlap(rule, other.rule) {
+			continue
+		}
+		if shared := pageRuleSharedRoles(rule, other.rule); len(shared) > 0 {
+			findings = append(findings, fmt.Sprintf("%s and %s match the same pages with contradictory outcomes for "+
+				"%s: the deny wins", rules[i].name, other.name, strings.Join(shared, ", ")))
+		}
+	}
+	return findings
+}
+
+// lintShadowedDenyPageRule reports the deny rule i when, for every role, locale and page it was checked against,
+// an allow rule decides instead of it. Only the pages of EXACT, START and END rules can be enumerated: the path
+// of the rule and, below or above it, pages no other rule is expected to mention.
+func lintShadowedDenyPageRule(rules []lintedPageRule, i int) []string {
+	rule := rules[i].rule
+	if !rule.Deny {
+		return nil
+	}
+	var pages []string
+	path := string(rule.Path)
+	switch rule.Match {
+	case wjSchema.PageRuleMatchExact:
+		pages = []string{path}
+	case wjSchema.PageRuleMatchStart:
+		pages = []string{lintProbeSegment, lintProbeSegment + "/" + lintProbeSegment}
+		if path != "" {
+			for k := range pages {
+				pages[k] = strings.TrimSuffix(path, "/") + "/" + pages[k]
+			}
+			pages = append(pages, path)
+		}
+	case wjSchema.PageRuleMatchEnd:
+		pages = []string{path, lintProbeSegment + "/" + path}
+	default:
+		return nil
+	}
+
+	group := lintGroup(rules, gqlcStringArrayToStringArray(rule.Roles))
+	shadowing := map[string]bool{}
+	for _, locale := range pageRuleLintLocales(rule) {
+		for _, role := range gqlcStringArrayToStringArray(rule.Roles) {
+			for _, page := range pages {
+				decision, err := checkAccess([]wjSchema.Group{group}, role, accessPage{path: page, locale: locale})
+				if err != nil || decision.rule == nil || decision.rule.Deny {
+					return nil
+				}
+				shadowing[rules[lintRuleIndex(group, decision.rule)].name] = true
+			}
+		}
+	}
+	var names []string
+	for name := range shadowing {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return []string{fmt.Sprintf("%s never denies anything: more specific rules (%s) allow %s on every page it matches",
+		rules[i].name, strings.Join(names, ", "), strings.Join(gqlcStringArrayToStringArray(rule.Roles), ", "))}
+}
+
+// lintUnreachablePageRule reports a REGEX rule that cannot match the path of a page: page paths never start with
+// a / and are never empty. Expressions the provider cannot evaluate are left alone.
+func lintUnreachablePageRule(rule lintedPageRule) []string {
+	if rule.rule.Match != wjSchema.PageRuleMatchRegex {
+		return nil
+	}
+	translated, approximated, err := translateJSRegex(string(rule.rule.Path))
+	if err != nil || approximated {
+		return nil
+	}
+	re, err := syntax.Parse(translated, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+	if regexMatchesOnlyEmpty(re) {
+		return []string{fmt.Sprintf("%s can never match: %q only matches an empty path, which no page has",
+			rule.name, rule.rule.Path)}
+	}
+	if regexStartsWithSlash(re) {
+		return []string{fmt.Sprintf("%s can never match: %q requires a leading /, but the paths page rules are "+
+			"matched against have none", rule.name, rule.rule.Path)}
+	}
+	return nil
+}
+
+// regexMatchesOnlyEmpty tells whether a regular expression anchored at both ends only matches the empty string.
+// Wiki.js searches the path for a match, so an expression that is not anchored matches every path.
+func regexMatchesOnlyEmpty(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpConcat && len(re.Sub) >= 2 && re.Sub[0].Op == syntax.OpBeginText &&
+		re.Sub[len(re.Sub)-1].Op == syntax.OpEndText && regexZeroWidth(re)
+}
+
+// regexZeroWidth tells whether a regular expression never consumes a character.
+func regexZeroWidth(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	case syntax.OpConcat, syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !regexZeroWidth(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpCapture:
+		return regexZeroWidth(re.Sub[0])
+	}
+	return false
+}
+
+// regexStartsWithSlash tells whether a regular expression anchored at the start of the text requires a / there.
+func regexStartsWithSlash(re *syntax.Regexp) bool {
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 || re.Sub[0].Op != syntax.OpBeginText {
+		return false
+	}
+	next := re.Sub[1]
+	return (next.Op == syntax.OpLiteral && len(next.Rune) > 0 && next.Rune[0] == '/') ||
+		(next.Op == syntax.OpCharClass && len(next.Rune) == 2 && next.Rune[0] == '/' && next.Rune[1] == '/')
+}
+
+// lintWriteWithoutRead reports the allow rule i when it grants write:pages, write:comments or write:assets on a
+// page where the group is not granted the matching read permission. Wiki.js lets nobody edit what they cannot
+// read. REGEX rules are skipped, their pages cannot be enumerated.
+func lintWriteWithoutRead(rules []lintedPageRule, i int, permissions []string) []string {
+	rule := rules[i].rule
+	if bool(rule.Deny) || slices.Contains(permissions, "manage:system") {
+		return nil
+	}
+	page := accessPage{path: string(rule.Path)}
+	switch rule.Match {
+	case wjSchema.PageRuleMatchStart:
+		if page.path == "" {
+			page.path = lintProbeSegment
+		}
+	case wjSchema.PageRuleMatchTag:
+		page = accessPage{path: lintProbeSegment, tags: []string{string(rule.Path)}}
+	case wjSchema.PageRuleMatchRegex:
+		return nil
+	}
+
+	group := lintGroup(rules, permissions)
+	var findings []string
+	for _, role := range gqlcStringArrayToStringArray(rule.Roles) {
+		read := "read:" + strings.TrimPrefix(role, "write:")
+		if !strings.HasPrefix(role, "write:") || !slices.Contains(pageRuleRoles(), read) {
+			continue
+		}
+		for _, locale := range pageRuleLintLocales(rule) {
+			page.locale = locale
+			decision, err := checkAccess([]wjSchema.Group{group}, read, page)
+			if err == nil && !decision.granted {
+				findings = append(findings, fmt.Sprintf("%s grants %s without %s, which the group is not granted "+
+					"on %q", rules[i].name, role, read, page.path))
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// lintGroup returns a group with the rules and the permissions, to evaluate the rules with checkAccess.
+func lintGroup(rules []lintedPageRule, permissions []string) wjSchema.Group {
+	group := wjSchema.Group{Permissions: stringArrayToGqlcStringArray(permissions)}
+	for _, rule := range rules {
+		group.PageRules = append(group.PageRules, rule.rule)
+	}
+	return group
+}
+
+// lintRuleIndex returns the index of a rule of the group, as returned by checkAccess.
+func lintRuleIndex(group wjSchema.Group, rule *wjSchema.PageRule) int {
+	for i := range group.PageRules {
+		if &group.PageRules[i] == rule {
+			return i
+		}
+	}
+	return -1
+}
+
+// pageRuleLintLocales returns the locales of the pages a rule is checked on: its own, or a locale no rule
+// restricts itself to when it applies to every locale.
+func pageRuleLintLocales(rule wjSchema.PageRule) []string {
+	if len(rule.Locales) == 0 {
+		return []string{""}
+	}
+	return gqlcStringArrayToStringArray(rule.Locales)
+}
+
+// pageRuleVerb returns what a rule does to its roles.
+func pageRuleVerb(rule wjSchema.PageRule) string {
+	if rule.Deny {
+		return "denies"
+	}
+	return "allows"
+}
+
+// pageRuleSharedRoles returns the roles of a that b also applies to.
+func pageRuleSharedRoles(a, b wjSchema.PageRule) []string {
+	var shared []string
+	for _, role := range gqlcStringArrayToStringArray(a.Roles) {
+		if slices.Contains(gqlcStringArrayToStringArray(b.Roles), role) {
+			shared = append(shared, role)
+		}
+	}
+	return shared
+}
+
+// pageRuleRolesCover tells whether a applies to every role of b.
+func pageRuleRolesCover(a, b wjSchema.PageRule) bool {
+	return len(pageRuleSharedRoles(b, a)) == len(b.Roles)
+}
+
+// pageRuleLocalesCover tells whether a applies to every locale b applies to. A rule without locales applies to
+// all of them.
+func pageRuleLocalesCover(a, b wjSchema.PageRule) bool {
+	if len(a.Locales) == 0 {
+		return true
+	}
+	if len(b.Locales) == 0 {
+		return false
+	}
+	for _, locale := range b.Locales {
+		if !slices.Contains(a.Locales, locale) {
+			return false
+		}
+	}
+	return true
+}
+
+// pageRuleLocalesOverlap tells whether a locale is applied to by both rules.
+func pageRuleLocalesOverlap(a, b wjSchema.PageRule) bool {
+	if len(a.Locales) == 0 || len(b.Locales) == 0 {
+		return true
+	}
+	for _, locale := range b.Locales {
+		if slices.Contains(a.Locales, locale) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/go-cty/cty/msgpack"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+)
+
+// testLintedRules names the rules after their id.
+func testLintedRules(rules ...wjSchema.PageRule) []lintedPageRule {
+	linted := make([]lintedPageRule, len(rules))
+	for i, rule := range rules {
+		linted[i] = lintedPageRule{name: string(rule.Id), rule: rule}
+	}
+	return linted
+}
+
+func TestLintPageRules(t *testing.T) {
+	const (
+		start = wjSchema.PageRuleMatchStart
+		end   = wjSchema.PageRuleMatchEnd
+		exact = wjSchema.PageRuleMatchExact
+		regex = wjSchema.PageRuleMatchRegex
+		tag   = wjSchema.PageRuleMatchTag
+	)
+	read := []string{"read:pages"}
+	readWrite := []string{"read:pages", "write:pages"}
+	write := []string{"write:pages"}
+	permissions := []string{"read:pages", "write:pages"}
+
+	for _, tc := range []struct {
+		name        string
+		rules       []wjSchema.PageRule
+		permissions []string
+		// findings are parts of the expected findings, in order.
+		findings []string
+	}{
+		{
+			name: "distinct rules",
+			rules: []wjSchema.PageRule{
+				testAccessRule("all", false, start, "", read),
+				testAccessRule("docs", false, start, "docs", write),
+				testAccessRule("secret", true, start, "docs/secret", readWrite),
+				testAccessRule("public", false, exact, "docs/secret/public", read),
+				testAccessRule("drafts", false, regex, "^drafts/", readWrite),
+				testAccessRule("wip", true, tag, "wip", write),
+			},
+			permissions: permissions,
+		},
+		{
+			name: "duplicate rule",
+			rules: []wjSchema.PageRule{
+				testAccessRule("first", false, start, "docs", readWrite),
+				testAccessRule("second", false, start, "docs", read),
+			},
+			findings: []string{"second is redundant: first already allows read:pages on every page it matches"},
+		},
+		{
+			name: "identical rules",
+			rules: []wjSchema.PageRule{
+				testAccessRule("first", false, exact, "docs", read),
+				testAccessRule("second", false, exact, "docs", read),
+			},
+			findings: []string{"second is redundant: first"},
+		},
+		{
+			name: "rule restricted to fewer locales",
+			rules: []wjSchema.PageRule{
+				testAccessRule("all", false, end, "faq", read),
+				testAccessRule("fr", false, end, "faq", read, "fr"),
+			},
+			findings: []string{"fr is redundant: all"},
+		},
+		{
+			name: "rule restricted to fewer locales listed first",
+			rules: []wjSchema.PageRule{
+				testAccessRule("fr", false, end, "faq", read, "fr"),
+				testAccessRule("all", false, end, "faq", read),
+			},
+			findings: []string{"fr is redundant: all"},
+		},
+		{
+			name: "nested START",
+			rules: []wjSchema.PageRule{
+				testAccessRule("docs", false, start, "docs", readWrite),
+				testAccessRule("guides", false, start, "docs/guides", read),
+			},
+			findings: []string{"guides is redundant: docs already allows read:pages"},
+		},
+		{
+			name: "nested START overriding a deny",
+			rules: []wjSchema.PageRule{
+				testAccessRule("docs", false, start, "docs", read),
+				testAccessRule("archive", true, start, "docs/archive", read),
+				testAccessRule("guides", false, start, "docs/archive/guides", read),
+			},
+		},
+		{
+			name: "contradictory outcomes",
+			rules: []wjSchema.PageRule{
+				testAccessRule("allow", false, start, "hr", readWrite),
+				testAccessRule("deny", true, start, "hr", write, "en"),
+			},
+			findings: []string{"allow and deny match the same pages with contradictory outcomes for write:pages: the deny wins"},
+		},
+		{
+			name: "contradictory rules on other locales",
+			rules: []wjSchema.PageRule{
+				testAccessRule("allow", false, start, "hr", read, "fr"),
+				testAccessRule("deny", true, start, "hr", read, "en"),
+			},
+		},
+		{
+			name: "deny shadowed by a longer regex",
+			rules: []wjSchema.PageRule{
+				testAccessRule("deny", true, start, "hr", write),
+				testAccessRule("allow", false, regex, "^hr.*", write),
+			},
+			findings: []string{"deny never denies anything: more specific rules (allow) allow write:pages on every page it matches"},
+		},
+		{
+			name: "EXACT deny shadowed",
+			rules: []wjSchema.PageRule{
+				testAccessRule("deny", true, exact, "hr", read),
+				testAccessRule("allow", false, regex, "^(hr|it)$", read),
+			},
+			findings: []string{"deny never denies anything: more specific rules (allow)"},
+		},
+		{
+			name: "deny partly overridden",
+			rules: []wjSchema.PageRule{
+				testAccessRule("deny", true, start, "hr", write),
+				testAccessRule("allow", false, regex, "^hr/[^/]+$", write),
+			},
+		},
+		{
+			name: "deny in a locale without allows",
+			rules: []wjSchema.PageRule{
+				testAccessRule("deny", true, exact, "hr", read, "en", "fr"),
+				testAccessRule("allow", false, regex, "^hr$", read, "en"),
+			},
+		},
+		{
+			name: "regex with a leading slash",
+			rules: []wjSchema.PageRule{
+				testAccessRule("slash", false, regex, "^/docs", read),
+				testAccessRule("escaped", false, regex, `^\/docs`, read),
+				testAccessRule("unanchored", false, regex, "/docs", read),
+			},
+			findings: []string{`slash can never match: "^/docs" requires a leading /`, `escaped can never match`},
+		},
+		{
+			name: "regex matching the empty path only",
+			rules: []wjSchema.PageRule{
+				testAccessRule("empty", false, regex, "^$", read),
+				testAccessRule("everything", false, regex, "^", read),
+			},
+			findings: []string{`empty can never match: "^$" only matches an empty path`},
+		},
+		{
+			name: "write without read",
+			rules: []wjSchema.PageRule{
+				testAccessRule("read", false, start, "docs", read),
+				testAccessRule("write", false, start, "hr", write),
+				testAccessRule("tagged", false, tag, "draft", write),
+			},
+			permissions: permissions,
+			findings: []string{
+				`write grants write:pages without read:pages, which the group is not granted on "hr"`,
+				`tagged grants write:pages without read:pages`,
+			},
+		},
+		{
+			name: "write without the read permission",
+			rules: []wjSchema.PageRule{
+				testAccessRule("all", false, start, "", readWrite),
+			},
+			permissions: []string{"write:pages"},
+			findings:    []string{`all grants write:pages without read:pages, which the group is not granted on "wikijs-lint-probe"`},
+		},
+		{
+			name: "write without read and unknown permissions",
+			rules: []wjSchema.PageRule{
+				testAccessRule("write", false, start, "hr", write),
+			},
+		},
+		{
+			name: "write without read with manage:system",
+			rules: []wjSchema.PageRule{
+				testAccessRule("write", false, start, "hr", write),
+			},
+			permissions: []string{"manage:system"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			findings := lintPageRules(testLintedRules(tc.rules...), tc.permissions)
+			if len(findings) != len(tc.findings) {
+				t.Fatalf("expected %d findings, got %q", len(tc.findings), findings)
+			}
+			for i, finding := range findings {
+				if !strings.Contains(finding, tc.findings[i]) {
+					t.Errorf("expected finding %d to contain %q, got %q", i, tc.findings[i], finding)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceGroupLintPageRulesDiff(t *testing.T) {
+	config := testGroupConfig(
+		map[string]interface{}{"path": "hr", "roles": []interface{}{"write:pages"}},
+		map[string]interface{}{"path": "docs", "match": "REGEX", "id": "slash", "roles": []interface{}{"read:pages"}},
+	)
+	config["page_rules"].([]interface{})[1].(map[string]interface{})["path"] = "^/docs"
+
+	_, c := testServerClient(t)
+	if err := testResourceDiff(t, resourceGroup(), config, c); err != nil {
+		t.Fatalf("expected the findings not to fail the plan, got %s", err)
+	}
+
+	_, c = testServerClient(t)
+	c.config.StrictPageRules = true
+	err := testResourceDiff(t, resourceGroup(), config, c)
+	if err == nil {
+		t.Fatal("expected strict_page_rules to fail the plan")
+	}
+	for _, want := range []string{
+		`page_rules[START "hr"] grants write:pages without read:pages`,
+		`page_rules[REGEX "^/docs"] can never match`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %s", want, err)
+		}
+	}
+
+	// The rules whose attributes are not known yet are not linted.
+	config["page_rules"].([]interface{})[0].(map[string]interface{})["path"] = testUnknown
+	config["page_rules"].([]interface{})[1].(map[string]interface{})["path"] = testUnknown
+	if err := testResourceDiff(t, resourceGroup(), config, c); err != nil {
+		t.Fatalf("expected unknown rules not to be linted, got %s", err)
+	}
+}
+
+func TestLintingProviderServer(t *testing.T) {
+	server := NewGRPCProviderServer("test")()
+	validate := func(typeName, config string) []*tfprotov5.Diagnostic {
+		t.Helper()
+		ty := New("test")().ResourcesMap[typeName].CoreConfigSchema().ImpliedType()
+		value, err := ctyjson.Unmarshal([]byte(config), ty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packed, err := msgpack.Marshal(value, ty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := server.ValidateResourceTypeConfig(context.Background(), &tfprotov5.ValidateResourceTypeConfigRequest{
+			TypeName: typeName,
+			Config:   &tfprotov5.DynamicValue{MsgPack: packed},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Diagnostics
+	}
+
+	diags := validate("wikijs_group_resource", `{
+		"name": "hr",
+		"redirect_on_login": "/",
+		"permissions": ["read:pages", "write:pages"],
+		"page_rules": [
+			{"deny": false, "match": "START", "path": "hr", "roles": ["write:pages"], "locales": []},
+			{"deny": false, "match": "REGEX", "path": "^/docs", "roles": ["read:pages"], "locales": []}
+		]
+	}`)
+	var findings []string
+	for _, d := range diags {
+		if d.Severity != tfprotov5.DiagnosticSeverityWarning {
+			t.Fatalf("expected warnings only, got %s: %s", d.Summary, d.Detail)
+		}
+		findings = append(findings, d.Detail)
+	}
+	for _, want := range []string{
+		`page_rules[START "hr"] grants write:pages without read:pages`,
+		`page_rules[REGEX "^/docs"] can never match`,
+	} {
+		if !strings.Contains(strings.Join(findings, "\n"), want) {
+			t.Errorf("expected a warning containing %q, got %v", want, findings)
+		}
+	}
+
+	if diags := validate("wikijs_group_resource", `{
+		"name": "docs",
+		"redirect_on_login": "/",
+		"permissions": ["read:pages"],
+		"page_rules": [{"deny": false, "match": "START", "path": "docs", "roles": ["read:pages"], "locales": []}]
+	}`); len(diags) != 0 {
+		t.Fatalf("expected no warnings for valid rules, got %v", diags)
+	}
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// testUnknown stands for a value of the configuration that is not known until apply.
+const testUnknown = "74D93920-ED26-11E3-AC10-0800200C9A66"
+
+// testResourceDiff plans the creation of r from raw, like Terraform does: unlike Resource.Diff alone, the
+// CustomizeDiff functions can read the configuration with GetRawConfig.
+func testResourceDiff(t *testing.T, r *schema.Resource, raw map[string]interface{}, meta interface{}) error {
+	t.Helper()
+	return testResourceDiffState(t, r, &terraform.InstanceState{}, raw, meta)
+}
+
+// testResourceDiffState plans the update of r from state to raw, like testResourceDiff.
+func testResourceDiffState(t *testing.T, r *schema.Resource, state *terraform.InstanceState, raw map[string]interface{}, meta interface{}) error {
+	t.Helper()
+	_, err := testResourcePlan(t, r, state, raw, meta)
+	return err
+}
+
+// testResourcePlan plans the update of r from state to raw, like testResourceDiff, and returns the plan.
+func testResourcePlan(t *testing.T, r *schema.Resource, state *terraform.InstanceState, raw map[string]interface{}, meta interface{}) (*terraform.InstanceDiff, error) {
+	t.Helper()
+	config, _ := json.Marshal(raw)
+	val, err := ctyjson.Unmarshal(config, r.CoreConfigSchema().ImpliedType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err = cty.Transform(val, func(_ cty.Path, v cty.Value) (cty.Value, error) {
+		if v.Type() == cty.String && v.IsKnown() && !v.IsNull() && v.AsString() == testUnknown {
+			return cty.UnknownVal(cty.String), nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.RawConfig = val
+	return r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(raw), meta)
+}
+
+// testGroupConfig returns the configuration of a wikijs_group_resource with the given page_rules blocks.
+func testGroupConfig(rules ...map[string]interface{}) map[string]interface{} {
+	blocks := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		block := map[string]interface{}{
+			"id":      "rule",
+			"deny":    false,
+			"match":   "START",
+			"roles":   []interface{}{"read:pages"},
+			"path":    "docs",
+			"locales": []interface{}{},
+		}
+		for k, v := range rule {
+			block[k] = v
+		}
+		blocks[i] = block
+	}
+	return map[string]interface{}{
+		"name":              "validated",
+		"permissions":       []interface{}{"read:pages", "write:pages"},
+		"redirect_on_login": "/",
+		"page_rules":        blocks,
+	}
+}
+
+func TestResourceGroupPageRulesValidation(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.SetLocales([]testserver.Locale{
+		{Code: "en", Name: "English", IsInstalled: true},
+		{Code: "fr", Name: "French", IsInstalled: true},
+		{Code: "de", Name: "German"},
+	})
+
+	valid := testGroupConfig(
+		map[string]interface{}{"match": "REGEX", "path": `^docs/(?!private)`},
+		map[string]interface{}{"match": "TAG", "path": "public", "locales": []interface{}{"en", "fr"}},
+		map[string]interface{}{"path": "fr/guides", "locales": []interface{}{"fr"}},
+	)
+	if err := testResourceDiff(t, resourceGroup(), valid, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	invalid := testGroupConfig(
+		map[string]interface{}{},
+		map[string]interface{}{"match": "REGEX", "path": "(a+)+"},
+		map[string]interface{}{"path": "private", "roles": []interface{}{"read:pages", "read:page"}, "locales": []interface{}{"de"}},
+		map[string]interface{}{"path": "fr/blog", "locales": []interface{}{"en"}},
+	)
+	err := testResourceDiff(t, resourceGroup(), invalid, c)
+	if err == nil {
+		t.Fatal("expected the invalid page rules to be rejected")
+	}
+	for _, expected := range []string{
+		`page_rules[REGEX "(a+)+"].path: "(a+)+" is not a valid page rule regular expression: nested quantifier`,
+		`page_rules[START "private"].roles.1: "read:page" is not a permission page rules apply to`,
+		`page_rules[START "private"].locales.0: locale "de" is not installed in Wiki.js, installed locales are en, fr`,
+		`page_rules[START "fr/blog"].path: path "fr/blog" starts with locale "fr" but the rule applies to the locales en`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %v", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), `page_rules[START "docs"]`) {
+		t.Errorf("the valid rule was reported: %v", err)
+	}
+
+	diags := resourceGroup().Validate(terraform.NewResourceConfigRaw(testGroupConfig(map[string]interface{}{"match": "BEGIN"})))
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "match to be one of") {
+		t.Fatalf("expected the match to be validated, got %v", diags)
+	}
+}
+
+func TestResourceGroupPageRuleCopies(t *testing.T) {
+	_, c := testServerClient(t)
+	config := testGroupConfig(
+		map[string]interface{}{"id": "docs"},
+		map[string]interface{}{"id": "copy", "path": "/docs"},
+		map[string]interface{}{"id": "blog", "path": "blog"},
+	)
+	err := testResourceDiff(t, resourceGroup(), config, c)
+	expected := `page_rules[START "docs"] (id "copy") and page_rules[START "docs"] (id "docs") are the same rule`
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+	if strings.Contains(err.Error(), "blog") {
+		t.Fatalf("the distinct rule was reported: %v", err)
+	}
+}
+
+func TestResourceGroupLocalesUnknown(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.InjectFault(testserver.Forbidden("localization.locales"))
+
+	config := testGroupConfig(map[string]interface{}{"locales": []interface{}{"xx"}})
+	if err := testResourceDiff(t, resourceGroup(), config, c); err != nil {
+		t.Fatalf("expected the locales to be skipped when they cannot be read, got %v", err)
+	}
+	if err := testResourceDiff(t, resourceGroup(), testGroupConfig(), c); err != nil || srv.RequestCount("localization.locales") != 1 {
+		t.Fatalf("expected the locales to be read only for rules restricting them, got %v", err)
+	}
+}
+
+func TestAccResourceGroupPageRulesValidation(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceGroupInvalidRegex,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`page_rules\[REGEX "\(a\+\)\+"\].path: "\(a\+\)\+" is not a valid page rule`),
+			},
+		},
+	})
+}
+
+const testAccResourceGroupInvalidRegex = `
+resource "wikijs_group_resource" "invalid" {
+    name = "invalid-regex"
+    permissions = ["read:pages"]
+    redirect_on_login = "/"
+    page_rules {
+        id = "unsafe"
+        deny = false
+        match = "REGEX"
+        roles = ["read:pages"]
+        path = "(a+)+"
+        locales = []
+    }
+}
+`
+
+func TestResourceGroupPageRulesUnknown(t *testing.T) {
+	_, c := testServerClient(t)
+	config := testGroupConfig(map[string]interface{}{"match": "REGEX", "path": testUnknown, "roles": []interface{}{"read:page", testUnknown}})
+	err := testResourceDiff(t, resourceGroup(), config, c)
+	if err == nil || !strings.Contains(err.Error(), `page_rules[REGEX (known after apply)].roles.0: "read:page"`) {
+		t.Fatalf("expected only the known values to be checked, got %v", err)
+	}
+}
+
+func TestPageRuleIdentity(t *testing.T) {
+	rule := func(id string, roles ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      id,
+			"deny":    false,
+			"match":   "START",
+			"roles":   roles,
+			"path":    "docs",
+			"locales": []interface{}{"en"},
+		}
+	}
+	a := rule("a", "read:pages", "write:pages")
+	if hashPageRule(a) != hashPageRule(rule("", "write:pages", "read:pages")) {
+		t.Error("expected the id and the order of the roles to be left out of the hash")
+	}
+	if hashPageRule(a) == hashPageRule(rule("a", "read:pages")) {
+		t.Error("expected rules granting other roles to differ")
+	}
+	id := pageRuleID(pageRuleKey(a))
+	if len(id) != 12 || id != pageRuleID(pageRuleKey(rule("", "write:pages", "read:pages"))) {
+		t.Errorf("expected the same 12 characters id for the same rule, got %q", id)
+	}
+}
@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+)
+
+// localeSegment matches the first segment of a path that Wiki.js reads as a locale code rather than as part of
+// the page path, such as en in /en/docs or pt-br in /pt-br/docs, ignoring its case.
+var localeSegment = regexp.MustCompile(`^(?i)[a-z]{2}(-[a-z]{2})?$`)
+
+// normalizePagePath returns the path of a page the way Wiki.js matches it against page rules: without leading or
+// trailing slashes.
+func normalizePagePath(path string) string {
+	return strings.Trim(strings.TrimSpace(path), "/")
+}
+
+// normalizePageRulePath returns the path of a page rule in the form Wiki.js expects. Wiki.js prefixes both the
+// page and the rule path with a / itself, so a leading / is dropped. Page paths never end with a /, so neither
+// do the paths of EXACT and END rules. A START path keeps its trailing /, which stops `docs/` from matching
+// `docs-archive`. Wiki.js stores tags in lower case, so TAG paths are folded to lower case. REGEX paths are an
+// expression, which is left alone.
+func normalizePageRulePath(match, path string) string {
+	switch match {
+	case "START":
+		return strings.TrimLeft(strings.TrimSpace(path), "/")
+	case "EXACT", "END":
+		return normalizePagePath(path)
+	case "TAG":
+		return strings.ToLower(strings.TrimSpace(path))
+	}
+	return path
+}
+
+// localizePageRule returns the path and the locales of a page rule in the form Wiki.js matches them. Wiki.js
+// removes the locale from the path of a page before matching it against the rules, so that a START, EXACT or END
+// path starting with an installed locale, e.g. `en/docs`, would never match: it becomes `docs` restricted to the
+// `en` locale. The path is otherwise normalized as by normalizePageRulePath. It fails when the rule restricts other
+// locales than the one of its path, which no page could match.
+func localizePageRule(match, path string, locales, installed []string) (string, []string, error) {
+	path = normalizePageRulePath(match, path)
+	switch match {
+	case "START", "EXACT", "END":
+	default:
+		return path, locales, nil
+	}
+	locale, rest := splitLocale(path, installed)
+	if locale == "" {
+		return path, locales, nil
+	}
+	if len(locales) == 0 {
+		return rest, []string{locale}, nil
+	}
+	for _, l := range locales {
+		if !strings.EqualFold(l, locale) {
+			return "", nil, fmt.Errorf("path %q starts with locale %q but the rule applies to the locales %s: "+
+				"write the path without its locale, %q, and set the locales instead", path, locale,
+				strings.Join(locales, ", "), rest)
+		}
+	}
+	return rest, locales, nil
+}
+
+// splitLocale splits the leading segment off a path without leading /, e.g. en/docs into en and docs, when it is
+// one of the installed locales, whatever its case. A lone locale, or one followed by a / only, is left in the path:
+// stripping it would turn a rule on the pages of a locale into a rule on every page.
+func splitLocale(path string, locales []string) (locale, rest string) {
+	segment, rest, found := strings.Cut(path, "/")
+	if !found || rest == "" || !localeSegment.MatchString(segment) {
+		return "", path
+	}
+	for _, l := range locales {
+		if strings.EqualFold(l, segment) {
+			return l, rest
+		}
+	}
+	return "", path
+}
+
+// hasLocaleSegment tells whether a path starts with a segment in the form of a locale code. Only such paths need
+// the installed locales to be normalized.
+func hasLocaleSegment(path string) bool {
+	segment, rest, found := strings.Cut(strings.TrimLeft(strings.TrimSpace(path), "/"), "/")
+	return found && rest != "" && localeSegment.MatchString(segment)
+}
+
+// pathLocales returns the locales installed in Wiki.js when one of paths starts with a segment in the form of a
+// locale code, and nil otherwise, so that the usual paths do not cost a query.
+func pathLocales(ctx context.Context, c *Client, paths ...string) ([]string, error) {
+	for _, path := range paths {
+		if hasLocaleSegment(path) {
+			return c.GetInstalledLocales(ctx)
+		}
+	}
+	return nil, nil
+}
+
+// normalizeRedirectPath returns a redirect path the way Wiki.js stores it: with a single leading /, as several
+// would make a link to another host, and without a trailing one. An empty path is the home page.
+func normalizeRedirectPath(path string) string {
+	return "/" + normalizePagePath(path)
+}
+
+// redirectPathKey returns what identifies the page a redirect path leads to, without its installed locale
+// segment: /en/docs and /docs lead to the same page.
+func redirectPathKey(path string, locales []string) string {
+	_, path = splitLocale(strings.TrimPrefix(normalizeRedirectPath(path), "/"), locales)
+	return "/" + path
+}
+
+// redirectPathStateFunc is the StateFunc of the redirect paths.
+func redirectPathStateFunc(v interface{}) string {
+	path, _ := v.(string)
+	return normalizeRedirectPath(path)
+}
+
+// suppressRedirectPathDiff is the DiffSuppressFunc of the redirect paths, which also hides the paths Wiki.js was
+// given in another form outside of Terraform.
+func suppressRedirectPathDiff(_, old, new string, _ *schema.ResourceData) bool {
+	return normalizeRedirectPath(old) == normalizeRedirectPath(new)
+}
+
+// suppressPageRulePathDiff is the DiffSuppressFunc of the path of a page rule, which is normalized according to
+// the match of the same rule.
+func suppressPageRulePathDiff(k, old, new string, d *schema.ResourceData) bool {
+	match, _ := d.Get(strings.TrimSuffix(k, "path") + "match").(string)
+	return normalizePageRulePath(match, old) == normalizePageRulePath(match, new)
+}
+
+// localizePageRuleInputs moves the installed locale segment leading the paths of rules about to be written to
+// Wiki.js to their locales, see localizePageRule.
+func localizePageRuleInputs(ctx context.Context, c *Client, rules []wjSchema.PageRuleInput) error {
+	var paths []string
+	for _, rule := range rules {
+		paths = append(paths, string(rule.Path))
+	}
+	installed, err := pathLocales(ctx, c, paths...)
+	if err != nil || installed == nil {
+		return err
+	}
+	for i, rule := range rules {
+		path, locales, err := localizePageRule(string(rule.Match), string(rule.Path),
+			gqlcStringArrayToStringArray(rule.Locales), installed)
+		if err != nil {
+			return apierror.New(apierror.Validation, "page rule %s: %s", rule.Id, err)
+		}
+		rules[i].Path = gqlc.String(path)
+		rules[i].Locales = stringArrayToGqlcStringArray(locales)
+	}
+	return nil
+}
+
+// keepConfiguredGroupPaths replaces the redirect path and the page rules read from Wiki.js by those of the state
+// when they only differ by an installed locale segment, e.g. /en/docs in Wiki.js for docs in the configuration, or
+// a rule on `docs` restricted to `en` in Wiki.js for a rule on `en/docs` in the configuration, so that the other
+// form is not reported as a change. The locales are only queried for paths starting with a segment in the form
+// of a locale code, and failing to query them leaves the paths unchanged.
+func keepConfiguredGroupPaths(ctx context.Context, c *Client, d *schema.ResourceData, group *wjSchema.Group) {
+	redirect := d.Get("redirect_on_login").(string)
+	paths := []string{redirect, string(group.RedirectOnLogin)}
+	configured := map[string]map[string]interface{}{}
+	for _, raw := range d.Get("page_rules").(*schema.Set).List() {
+		rule, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := rule["id"].(string)
+		if id == "" {
+			id = pageRuleID(pageRuleKey(rule))
+		}
+		configured[id] = rule
+		path, _ := rule["path"].(string)
+		paths = append(paths, path)
+	}
+	for _, rule := range group.PageRules {
+		paths = append(paths, string(rule.Path))
+	}
+	installed, err := pathLocales(ctx, c, paths...)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Comparing the paths of group %d without their locale: %s", group.Id, err))
+		return
+	}
+	if installed == nil {
+		return
+	}
+
+	if redirect != "" && redirectPathKey(redirect, installed) == redirectPathKey(string(group.RedirectOnLogin), installed) {
+		group.RedirectOnLogin = gqlc.String(redirect)
+	}
+	for i, rule := range group.PageRules {
+		prior, ok := configured[string(rule.Id)]
+		if !ok {
+			continue
+		}
+		match, _ := prior["match"].(string)
+		path, _ := prior["path"].(string)
+		locales := interfaceSliceToStrings(prior["locales"])
+		if match == string(rule.Match) && samePageRule(match, path, locales, string(rule.Path),
+			gqlcStringArrayToStringArray(rule.Locales), installed) {
+			group.PageRules[i].Path = gqlc.String(path)
+			group.PageRules[i].Locales = stringArrayToGqlcStringArray(locales)
+		}
+	}
+}
+
+// samePageRule tells whether two paths and locales of a page rule with the given match select the same pages.
+func samePageRule(match, pathA string, localesA []string, pathB string, localesB []string, installed []string) bool {
+	pathA, localesA, errA := localizePageRule(match, pathA, localesA, installed)
+	pathB, localesB, errB := localizePageRule(match, pathB, localesB, installed)
+	if errA != nil || errB != nil || pathA != pathB || len(localesA) != len(localesB) {
+		return false
+	}
+	localesA = append([]string(nil), localesA...)
+	localesB = append([]string(nil), localesB...)
+	sort.Strings(localesA)
+	sort.Strings(localesB)
+	for i := range localesA {
+		if !strings.EqualFold(localesA[i], localesB[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestNormalizePageRulePath(t *testing.T) {
+	for _, tc := range []struct {
+		match, path, want string
+	}{
+		{match: "START", path: "docs", want: "docs"},
+		{match: "START", path: "/docs", want: "docs"},
+		{match: "START", path: "//docs/", want: "docs/"},
+		{match: "START", path: "/", want: ""},
+		{match: "START", path: " docs ", want: "docs"},
+		{match: "EXACT", path: "/docs/guide/", want: "docs/guide"},
+		{match: "END", path: "/guide/", want: "guide"},
+		{match: "REGEX", path: "^/docs/$", want: "^/docs/$"},
+		{match: "TAG", path: " Draft ", want: "draft"},
+		{match: "EXACT", path: "/en/docs", want: "en/docs"},
+		{match: "", path: "/docs", want: "/docs"},
+	} {
+		if got := normalizePageRulePath(tc.match, tc.path); got != tc.want {
+			t.Errorf("%s %q: expected %q, got %q", tc.match, tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestNormalizeRedirectPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"":               "/",
+		"/":              "/",
+		"docs":           "/docs",
+		"/docs/":         "/docs",
+		"//evil.example": "/evil.example",
+		" /en/docs ":     "/en/docs",
+	} {
+		if got := normalizeRedirectPath(path); got != want {
+			t.Errorf("%q: expected %q, got %q", path, want, got)
+		}
+		if !suppressRedirectPathDiff("redirect_on_login", want, path, nil) {
+			t.Errorf("expected %q and %q not to differ", want, path)
+		}
+	}
+	if suppressRedirectPathDiff("redirect_on_login", "/docs", "/blog", nil) {
+		t.Error("expected different paths to differ")
+	}
+}
+
+func TestLocalizePageRule(t *testing.T) {
+	installed := []string{"en", "pt-br"}
+	for _, tc := range []struct {
+		match, path string
+		locales     []string
+		want        string
+		wantLocales []string
+		wantErr     bool
+	}{
+		{match: "START", path: "/en/docs", want: "docs", wantLocales: []string{"en"}},
+		{match: "START", path: "EN/docs/", want: "docs/", wantLocales: []string{"en"}},
+		{match: "EXACT", path: "/pt-BR/docs/guide/", want: "docs/guide", wantLocales: []string{"pt-br"}},
+		{match: "END", path: "en/guide", locales: []string{"en"}, want: "guide", wantLocales: []string{"en"}},
+		// The path cannot match pages of another locale than its own.
+		{match: "START", path: "en/docs", locales: []string{"en", "pt-br"}, wantErr: true},
+		{match: "START", path: "en/docs", locales: []string{"pt-br"}, wantErr: true},
+		// A lone locale stays, stripping it would match every page.
+		{match: "START", path: "en/", want: "en/"},
+		{match: "EXACT", path: "/en", want: "en"},
+		// hr looks like a locale but is not installed, it is a folder.
+		{match: "START", path: "hr/policies", want: "hr/policies"},
+		{match: "START", path: "docs/en/guide", locales: []string{"pt-br"}, want: "docs/en/guide", wantLocales: []string{"pt-br"}},
+		{match: "REGEX", path: "en/docs", want: "en/docs"},
+		{match: "TAG", path: "en/Docs", want: "en/docs"},
+	} {
+		path, locales, err := localizePageRule(tc.match, tc.path, tc.locales, installed)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s %q %v: expected an error, got %q %v", tc.match, tc.path, tc.locales, path, locales)
+			}
+			continue
+		}
+		if err != nil || path != tc.want || strings.Join(locales, ",") != strings.Join(tc.wantLocales, ",") {
+			t.Errorf("%s %q %v: expected %q %v, got %q %v %v", tc.match, tc.path, tc.locales, tc.want, tc.wantLocales,
+				path, locales, err)
+		}
+	}
+}
+
+func TestRedirectPathKey(t *testing.T) {
+	installed := []string{"en", "fr"}
+	for _, tc := range []struct {
+		a, b string
+		same bool
+	}{
+		{a: "/en/docs", b: "docs", same: true},
+		{a: "/EN/docs/", b: "/docs", same: true},
+		{a: "fr/docs", b: "/en/docs", same: true},
+		{a: "/en", b: "/", same: false},
+		{a: "/hr/docs", b: "/docs", same: false},
+		{a: "/en/docs", b: "/en/blog", same: false},
+	} {
+		if same := redirectPathKey(tc.a, installed) == redirectPathKey(tc.b, installed); same != tc.same {
+			t.Errorf("%q and %q: expected same to be %v", tc.a, tc.b, tc.same)
+		}
+	}
+}
+
+func TestHasLocaleSegment(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/en/docs": true,
+		"pt-br/x":  true,
+		"hr/x":     true,
+		"docs/x":   false,
+		"en":       false,
+		"/en/":     false,
+		"e/docs":   false,
+	} {
+		if got := hasLocaleSegment(path); got != want {
+			t.Errorf("%q: expected %v, got %v", path, want, got)
+		}
+	}
+}
+
+func TestResourceGroupLocalePaths(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.SetLocales([]testserver.Locale{
+		{Code: "en", Name: "English", IsInstalled: true},
+		{Code: "fr", Name: "French", IsInstalled: true},
+	})
+	ctx := context.Background()
+	rule := func(path string, locales ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"deny": true, "match": "START", "roles": []interface{}{"read:pages"},
+			"path": path, "locales": locales}
+	}
+	d := schema.TestResourceDataRaw(t, resourceGroup().Schema, map[string]interface{}{
+		"name":              "docs",
+		"permissions":       []interface{}{"read:pages"},
+		"redirect_on_login": "docs",
+		"page_rules":        []interface{}{rule("/en/docs", "en"), rule("fr/drafts"), rule("hr/policies")},
+	})
+	if diags := resourceGroupCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	id, _ := strconv.Atoi(d.Id())
+	group, _ := srv.Group(id)
+	written := map[string]string{}
+	for _, r := range group.PageRules {
+		written[r.Path] = strings.Join(r.Locales, ",")
+	}
+	// The deny on the French drafts must not apply to the drafts of every locale.
+	if len(written) != 3 || written["docs"] != "en" || written["drafts"] != "fr" || written["hr/policies"] != "" {
+		t.Fatalf("expected the installed locales to move from the rule paths to their locales, got %v", written)
+	}
+	rules := d.Get("page_rules").(*schema.Set)
+
+	// Wiki.js was given the redirect path with its locale outside of Terraform.
+	srv.UpdateGroup(id, func(g *testserver.Group) { g.RedirectOnLogin = "/en/docs" })
+	if diags := resourceGroupRead(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if normalizeRedirectPath(d.Get("redirect_on_login").(string)) != "/docs" {
+		t.Fatalf("expected the configured redirect path to be kept, got %q", d.Get("redirect_on_login"))
+	}
+	if !d.Get("page_rules").(*schema.Set).Equal(rules) {
+		t.Fatalf("expected the configured rules to be kept, got %v", d.Get("page_rules").(*schema.Set).List())
+	}
+
+	srv.UpdateGroup(id, func(g *testserver.Group) { g.RedirectOnLogin = "/en/blog" })
+	if diags := resourceGroupRead(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if d.Get("redirect_on_login") != "/en/blog" {
+		t.Fatalf("expected a different redirect path to be read, got %q", d.Get("redirect_on_login"))
+	}
+
+	conflicting := schema.TestResourceDataRaw(t, resourceGroup().Schema, map[string]interface{}{
+		"name":        "conflicting",
+		"permissions": []interface{}{"read:pages"},
+		"page_rules":  []interface{}{rule("fr/drafts", "en")},
+	})
+	if diags := resourceGroupCreate(ctx, conflicting, c); !diags.HasError() || !strings.Contains(diags[0].Detail, `starts with locale "fr"`) {
+		t.Fatalf("expected the path to conflict with the locales of the rule, got %v", diags)
+	}
+}
+
+func TestPageRuleKeyNormalizesPath(t *testing.T) {
+	rule := func(match, path string) map[string]interface{} {
+		return map[string]interface{}{"deny": false, "match": match, "path": path,
+			"roles": []interface{}{"read:pages"}, "locales": []interface{}{}}
+	}
+	if pageRuleKey(rule("START", "/docs")) != pageRuleKey(rule("START", "docs")) {
+		t.Error("expected a leading / not to change the rule")
+	}
+	if pageRuleKey(rule("EXACT", "docs/")) != pageRuleKey(rule("EXACT", "docs")) {
+		t.Error("expected a trailing / not to change an EXACT rule")
+	}
+	if pageRuleKey(rule("START", "docs/")) == pageRuleKey(rule("START", "docs")) {
+		t.Error("expected a trailing / to change a START rule")
+	}
+	unset := rule("START", "docs")
+	delete(unset, "deny")
+	if pageRuleKey(unset) != pageRuleKey(rule("START", "docs")) || pageRuleID(pageRuleKey(unset)) != pageRuleID(pageRuleKey(rule("START", "docs"))) {
+		t.Error("expected an unset deny to identify the same rule as false")
+	}
+}
+
+func TestAccPathNormalization(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("the paths are checked in the testserver")
+	}
+	srv := testAccServer(t)
+	group := func() testserver.Group {
+		groups := srv.Groups()
+		return groups[len(groups)-1]
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPathNormalization,
+				Check: func(*terraform.State) error {
+					g := group()
+					if g.RedirectOnLogin != "/docs" {
+						return fmt.Errorf("expected the redirect path to be normalized, got %q", g.RedirectOnLogin)
+					}
+					if paths := pageRulePaths(t, srv, g.ID); paths != "blog,docs/,faq" {
+						return fmt.Errorf("expected the rule paths to be normalized, got %s", paths)
+					}
+					return nil
+				},
+			},
+			{
+				// Wiki.js was given the same paths in another form outside of Terraform.
+				PreConfig: func() {
+					srv.UpdateGroup(group().ID, func(g *testserver.Group) {
+						g.RedirectOnLogin = "/docs/"
+					})
+				},
+				Config:   testAccPathNormalization,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+const testAccPathNormalization = `
+resource "wikijs_group_resource" "docs" {
+  name                       = "docs"
+  permissions                = ["read:pages", "write:pages"]
+  redirect_on_login          = "docs/"
+  ignore_external_page_rules = true
+
+  page_rules {
+    deny    = false
+    match   = "START"
+    roles   = ["read:pages", "write:pages"]
+    path    = "/docs/"
+    locales = []
+  }
+
+  page_rules {
+    deny    = true
+    match   = "EXACT"
+    roles   = ["write:pages"]
+    path    = "/blog/"
+    locales = []
+  }
+}
+
+resource "wikijs_group_page_rule" "faq" {
+  group_id = wikijs_group_resource.docs.id
+  deny     = false
+  match    = "END"
+  roles    = ["read:pages"]
+  path     = "/faq/"
+  locales  = []
+}
+`
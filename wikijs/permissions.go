@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"fmt"
+	"github.com/agnivade/levenshtein"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"golang.org/x/exp/slices"
+	"sort"
+	"strings"
+)
+
+// permission is an entry of the Wiki.js permission catalog.
+type permission struct {
+	name string
+	// pageRule is set for the permissions that page rules can grant or deny on a path.
+	pageRule bool
+}
+
+// permissionCatalog lists the permissions of Wiki.js 2.x, as shown in the permissions tab of a group.
+var permissionCatalog = []permission{
+	{name: "read:pages", pageRule: true},
+	{name: "read:assets", pageRule: true},
+	{name: "read:comments", pageRule: true},
+	{name: "write:comments", pageRule: true},
+	{name: "read:source", pageRule: true},
+	{name: "read:history", pageRule: true},
+	{name: "write:pages", pageRule: true},
+	{name: "manage:pages", pageRule: true},
+	{name: "delete:pages", pageRule: true},
+	{name: "write:styles", pageRule: true},
+	{name: "write:scripts", pageRule: true},
+	{name: "write:assets", pageRule: true},
+	{name: "manage:assets", pageRule: true},
+	{name: "manage:comments", pageRule: true},
+	{name: "write:users"},
+	{name: "manage:users"},
+	{name: "write:groups"},
+	{name: "manage:groups"},
+	{name: "manage:navigation"},
+	{name: "manage:theme"},
+	{name: "manage:api"},
+	{name: "manage:system"},
+}
+
+// pageRuleRoles returns the permissions page rules can apply to.
+func pageRuleRoles() []string {
+	var roles []string
+	for _, p := range permissionCatalog {
+		if p.pageRule {
+			roles = append(roles, p.name)
+		}
+	}
+	return roles
+}
+
+// permissionPreset is a named set of permissions, from the least to the most privileged.
+type permissionPreset struct {
+	name        string
+	permissions []string
+}
+
+// permissionPresets are the presets of wikijs_group_resource. Each one grants the permissions of the previous one.
+var permissionPresets = []permissionPreset{
+	{name: "reader", permissions: []string{"read:pages", "read:assets", "read:comments"}},
+	{name: "commenter", permissions: []string{"write:comments"}},
+	{name: "editor", permissions: []string{"read:source", "read:history", "write:pages", "delete:pages", "write:assets"}},
+	{name: "moderator", permissions: []string{"manage:pages", "manage:assets", "manage:comments"}},
+	{name: "admin", permissions: []string{"write:styles", "write:scripts", "write:users", "manage:users",
+		"write:groups", "manage:groups", "manage:navigation", "manage:theme", "manage:api", "manage:system"}},
+}
+
+// permissionPresetNames returns the names of the presets.
+func permissionPresetNames() []string {
+	names := make([]string, len(permissionPresets))
+	for i, preset := range permissionPresets {
+		names[i] = preset.name
+	}
+	return names
+}
+
+// presetPermissions returns the permissions of a preset, none for an unknown or empty one.
+func presetPermissions(name string) []string {
+	var permissions []string
+	for _, preset := range permissionPresets {
+		permissions = append(permissions, preset.permissions...)
+		if preset.name == name {
+			return permissions
+		}
+	}
+	return nil
+}
+
+// groupPermissions returns the permissions of the preset and the permissions together, sorted and without
+// duplicates.
+func groupPermissions(preset string, permissions []string) []string {
+	all := append(presetPermissions(preset), permissions...)
+	sort.Strings(all)
+	return slices.Compact(all)
+}
+
+// permissionNames returns the names of the permissions of the catalog.
+func permissionNames() []string {
+	names := make([]string, len(permissionCatalog))
+	for i, p := range permissionCatalog {
+		names[i] = p.name
+	}
+	return names
+}
+
+// validatePermission is the ValidateDiagFunc of a permission. It suggests the closest one of the catalog.
+func validatePermission(v interface{}, path cty.Path) diag.Diagnostics {
+	name, _ := v.(string)
+	names := permissionNames()
+	if slices.Contains(names, name) {
+		return nil
+	}
+	detail := fmt.Sprintf("Expected one of %s.", strings.Join(names, ", "))
+	if suggestion := closestString(name, names); suggestion != "" {
+		detail = fmt.Sprintf("Did you mean %q? %s", suggestion, detail)
+	}
+	return diag.Diagnostics{{
+		Severity:      diag.Error,
+		Summary:       fmt.Sprintf("%q is not a Wiki.js permission", name),
+		Detail:        detail,
+		AttributePath: path,
+	}}
+}
+
+// closestString returns the candidate closest to s, as long as it is close enough to be a typo: at most a third
+// of its characters differ. It returns "" otherwise.
+func closestString(s string, candidates []string) string {
+	closest, best := "", len(s)/3+1
+	for _, candidate := range candidates {
+		if distance := levenshtein.ComputeDistance(s, candidate); distance < best {
+			closest, best = candidate, distance
+		}
+	}
+	return closest
+}
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+)
+
+func TestPresetPermissions(t *testing.T) {
+	previous := []string{}
+	for _, name := range permissionPresetNames() {
+		permissions := presetPermissions(name)
+		for _, p := range previous {
+			if !strings.Contains(strings.Join(permissions, ","), p) {
+				t.Errorf("expected preset %s to grant %s, granted by the previous preset", name, p)
+			}
+		}
+		for _, p := range permissions {
+			if diags := validatePermission(p, nil); diags.HasError() {
+				t.Errorf("preset %s grants %s, which is not in the catalog", name, p)
+			}
+		}
+		previous = permissions
+	}
+	if len(previous) != len(permissionCatalog) {
+		t.Errorf("expected admin to have every permission, got %v", previous)
+	}
+	if got := presetPermissions("commenter"); !reflect.DeepEqual(got, []string{"read:pages", "read:assets", "read:comments", "write:comments"}) {
+		t.Errorf("unexpected commenter permissions %v", got)
+	}
+	if got := presetPermissions(""); got != nil {
+		t.Errorf("expected no permissions without a preset, got %v", got)
+	}
+}
+
+func TestGroupPermissions(t *testing.T) {
+	got := groupPermissions("reader", []string{"write:pages", "read:pages"})
+	want := []string{"read:assets", "read:comments", "read:pages", "write:pages"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestConfiguredPermissions(t *testing.T) {
+	effective := []string{"read:assets", "read:comments", "read:pages", "write:pages"}
+	for _, tc := range []struct {
+		preset     string
+		configured []string
+		want       []string
+	}{
+		{preset: "", configured: nil, want: effective},
+		{preset: "reader", configured: []string{"write:pages"}, want: []string{"write:pages"}},
+		{preset: "reader", configured: []string{"read:pages", "write:pages"}, want: []string{"read:pages", "write:pages"}},
+		{preset: "editor", configured: nil, want: nil},
+	} {
+		if got := configuredPermissions(tc.preset, tc.configured, effective); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("preset %q and %v: expected %v, got %v", tc.preset, tc.configured, tc.want, got)
+		}
+	}
+}
+
+func TestValidatePermission(t *testing.T) {
+	path := cty.GetAttrPath("permissions")
+	for _, tc := range []struct {
+		permission string
+		// detail is a part of the detail of the error, empty when the permission is valid.
+		detail string
+	}{
+		{permission: "read:pages"},
+		{permission: "manage:system"},
+		{permission: "read:page", detail: `Did you mean "read:pages"?`},
+		{permission: "wrtie:pages", detail: `Did you mean "write:pages"?`},
+		{permission: "Manage:System", detail: `Did you mean "manage:system"?`},
+		{permission: "delete:everything", detail: "Expected one of read:pages"},
+	} {
+		diags := validatePermission(tc.permission, path)
+		if tc.detail == "" {
+			if diags.HasError() {
+				t.Errorf("expected %s to be valid, got %v", tc.permission, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || !strings.Contains(diags[0].Detail, tc.detail) || !diags[0].AttributePath.Equals(path) {
+			t.Errorf("expected %s to be invalid with %q, got %v", tc.permission, tc.detail, diags)
+		}
+		if strings.HasPrefix(tc.detail, "Expected") && strings.Contains(diags[0].Detail, "Did you mean") {
+			t.Errorf("expected no suggestion for %s, got %s", tc.permission, diags[0].Detail)
+		}
+	}
+}
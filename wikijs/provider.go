@@ -6,14 +6,26 @@ package wikijs
 
 import (
 	"context"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"time"
 )
 
 func init() {
 	schema.DescriptionKind = schema.StringMarkdown
 }
 
+// NewGRPCProviderServer returns the server of the provider, which reports the findings of the page rule linter as
+// warnings of the validation of the configuration.
+func NewGRPCProviderServer(version string) func() tfprotov5.ProviderServer {
+	return func() tfprotov5.ProviderServer {
+		p := New(version)()
+		return lintingProviderServer{ProviderServer: schema.NewGRPCProviderServer(p), provider: p}
+	}
+}
+
 func New(_ string) func() *schema.Provider {
 	return func() *schema.Provider {
 		p := &schema.Provider{
@@ -29,12 +41,132 @@ func New(_ string) func() *schema.Provider {
 					Sensitive:   true,
 					DefaultFunc: schema.EnvDefaultFunc("WIKIJS_TOKEN", nil),
 				},
+				"username": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("WIKIJS_USERNAME", nil),
+					Description: "Username to log in with, for instances where the API is disabled. Takes precedence over `token` when set together with `password`, otherwise `token` is used. Can also be set with the `WIKIJS_USERNAME` environment variable.",
+				},
+				"password": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Sensitive:   true,
+					DefaultFunc: schema.EnvDefaultFunc("WIKIJS_PASSWORD", nil),
+					Description: "Password to log in with. Can also be set with the `WIKIJS_PASSWORD` environment variable.",
+				},
+				"strategy": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("WIKIJS_AUTH_STRATEGY", DefaultAuthStrategy),
+					Description: "Key of the Wiki.js authentication strategy used with `username` and `password`, e.g. the key of an LDAP strategy. Defaults to `local`. Can also be set with the `WIKIJS_AUTH_STRATEGY` environment variable.",
+				},
+				"ca_cert_pem": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "PEM encoded certificate authorities trusted in addition to the system ones, e.g. a private CA signing the Wiki.js certificate.",
+				},
+				"ca_cert_file": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("WIKIJS_CA_CERT_FILE", nil),
+					Description: "Path to a file of PEM encoded certificate authorities trusted in addition to the system ones. Can also be set with the `WIKIJS_CA_CERT_FILE` environment variable.",
+				},
+				"client_cert": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					RequiredWith: []string{"client_key"},
+					Description:  "PEM encoded client certificate presented to Wiki.js for mutual TLS.",
+				},
+				"client_key": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Sensitive:    true,
+					RequiredWith: []string{"client_cert"},
+					Description:  "PEM encoded private key of `client_cert`.",
+				},
+				"insecure_skip_verify": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Skip the verification of the Wiki.js TLS certificate. Only use this for testing.",
+				},
+				"proxy_url": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "URL of the HTTP proxy used to reach Wiki.js. Defaults to the `HTTPS_PROXY`, `HTTP_PROXY` and `NO_PROXY` environment variables.",
+				},
+				"request_timeout": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(0),
+					Description:  "Timeout in seconds of a single HTTP request to Wiki.js, retries excluded. By default a request may take as long as the `timeouts` of the resource or data source allow.",
+				},
+				"headers": {
+					Type:        schema.TypeMap,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "Additional headers sent with every request, e.g. for an SSO gateway in front of Wiki.js. Headers set by the provider itself, such as `Authorization`, are not replaced.",
+				},
+				"skip_connectivity_check": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     false,
+					Description: "Skip the query that checks the host and credentials before the first request to Wiki.js.",
+				},
+				"retry_max": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      DefaultRetryPolicy.RetryMax,
+					ValidateFunc: validation.IntAtLeast(0),
+					Description:  "Maximum number of retries for a request that failed transiently (connection errors, HTTP 429, 502, 503 or 504). Mutations are only retried when Wiki.js cannot have processed them. Set to 0 to disable retries.",
+				},
+				"retry_wait_min": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      int(DefaultRetryPolicy.RetryWaitMin / time.Second),
+					ValidateFunc: validation.IntAtLeast(0),
+					Description:  "Minimum time in seconds to wait before retrying a request. The wait doubles with every attempt, unless Wiki.js sends a `Retry-After` header.",
+				},
+				"retry_wait_max": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					Default:      int(DefaultRetryPolicy.RetryWaitMax / time.Second),
+					ValidateFunc: validation.IntAtLeast(0),
+					Description:  "Maximum time in seconds to wait before retrying a request, including the wait asked for by a `Retry-After` header. A request is not retried when the wait would exceed the timeout of the operation.",
+				},
+				"requests_per_second": {
+					Type:         schema.TypeFloat,
+					Optional:     true,
+					Default:      0,
+					ValidateFunc: validation.FloatAtLeast(0),
+					Description:  "Maximum number of requests per second sent to Wiki.js. Defaults to 0, which means unlimited.",
+				},
+				"strict_page_rules": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("WIKIJS_STRICT_PAGE_RULES", false),
+					Description: "Fail the plan of a `wikijs_group_resource` whose page rules are valid but likely mistaken: " +
+						"redundant rules, deny rules more specific allows always override, REGEX rules that cannot match a page, " +
+						"rules with contradictory outcomes on the same pages and rules granting a write permission without the " +
+						"matching read one. Otherwise they are reported as warnings when Terraform validates the configuration. Can also be set with " +
+						"the `WIKIJS_STRICT_PAGE_RULES` environment variable.",
+				},
 			},
 			DataSourcesMap: map[string]*schema.Resource{
-				"wikijs_site_data_source": dataSourceSite(),
+				"wikijs_site_data_source":      dataSourceSite(),
+				"wikijs_groups":                dataSourceGroups(),
+				"wikijs_group":                 dataSourceGroup(),
+				"wikijs_effective_permissions": dataSourceEffectivePermissions(),
+				"wikijs_user":                  dataSourceUser(),
+				"wikijs_users":                 dataSourceUsers(),
 			},
 			ResourcesMap: map[string]*schema.Resource{
-				"wikijs_group_resource": resourceGroup(),
+				"wikijs_group_resource":   resourceGroup(),
+				"wikijs_group_membership": resourceGroupMembership(),
+				"wikijs_group_member":     resourceGroupMember(),
+				"wikijs_group_page_rule":  resourceGroupPageRule(),
+				"wikijs_system_group":     resourceSystemGroup(),
+				"wikijs_user":             resourceUser(),
 			},
 		}
 
@@ -44,30 +176,51 @@ func New(_ string) func() *schema.Provider {
 	}
 }
 
+// configure only collects the provider settings. Values such as the host may still be unknown at this point,
+// so they are checked by the client before its first request.
 func configure() func(_ context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 	return func(_ context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
 		var diags diag.Diagnostics
 
 		host := d.Get("host").(string)
 		token := d.Get("token").(string)
-		if host == "" {
-			diags = append(diags, diag.Diagnostic{Severity: diag.Error,
-				Summary: "Wikijs HOST not declared.",
-				Detail:  "Set the value as an env var WIKIJS_HOST or as `host` in the provider block."})
-			return nil, diags
-		}
+		username := d.Get("username").(string)
+		password := d.Get("password").(string)
 
-		if token == "" {
+		retryWaitMin := time.Duration(d.Get("retry_wait_min").(int)) * time.Second
+		retryWaitMax := time.Duration(d.Get("retry_wait_max").(int)) * time.Second
+		if retryWaitMin > retryWaitMax {
 			diags = append(diags, diag.Diagnostic{Severity: diag.Error,
-				Summary: "Wikijs API TOKEN not declared.",
-				Detail:  "Set the value as an env var WIKIJS_TOKEN or as `token` in the provider block."})
+				Summary: "Invalid retry configuration.",
+				Detail:  "`retry_wait_min` must not be greater than `retry_wait_max`."})
 			return nil, diags
 		}
 
-		client, err := NewClient(host, token)
-		if err != nil {
-			return nil, diag.FromErr(err)
-		}
+		client := NewClient(Config{
+			Host:     host,
+			Token:    token,
+			Username: username,
+			Password: password,
+			Strategy: d.Get("strategy").(string),
+			Retry: RetryPolicy{
+				RetryMax:     d.Get("retry_max").(int),
+				RetryWaitMin: retryWaitMin,
+				RetryWaitMax: retryWaitMax,
+			},
+			RequestsPerSecond: d.Get("requests_per_second").(float64),
+			RequestTimeout:    time.Duration(d.Get("request_timeout").(int)) * time.Second,
+			Transport: TransportConfig{
+				CACertPEM:          d.Get("ca_cert_pem").(string),
+				CACertFile:         d.Get("ca_cert_file").(string),
+				ClientCert:         d.Get("client_cert").(string),
+				ClientKey:          d.Get("client_key").(string),
+				InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+				ProxyURL:           d.Get("proxy_url").(string),
+				Headers:            expandStringMap(d.Get("headers").(map[string]interface{})),
+			},
+			SkipConnectivityCheck: d.Get("skip_connectivity_check").(bool),
+			StrictPageRules:       d.Get("strict_page_rules").(bool),
+		})
 
 		return client, nil
 	}
@@ -5,10 +5,16 @@
 package wikijs
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"os/exec"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
 )
 
 // providerFactories are used to instantiate a provider during acceptance testing.
@@ -26,7 +32,49 @@ func TestProvider(t *testing.T) {
 	}
 }
 
+func TestProviderDataSourceTimeouts(t *testing.T) {
+	// request_timeout defaults to the read timeout of the data sources.
+	for name, ds := range New("dev")().DataSourcesMap {
+		if ds.Timeouts == nil || ds.Timeouts.Read == nil {
+			t.Errorf("%s does not declare a read timeout", name)
+		}
+	}
+}
+
+func TestProviderConfigureOffline(t *testing.T) {
+	// The host is unknown or unreachable during an offline plan, configuring must not contact Wiki.js.
+	p := New("dev")()
+	diags := p.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"host":  "https://wiki.example.invalid",
+		"token": "token",
+	}))
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if _, ok := p.Meta().(*Client); !ok {
+		t.Fatalf("expected a *Client, got %T", p.Meta())
+	}
+}
+
+func TestProviderValidateNegative(t *testing.T) {
+	for _, name := range []string{"request_timeout", "retry_max", "retry_wait_min", "retry_wait_max", "requests_per_second"} {
+		diags := New("dev")().Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+			"host":  "https://wiki.example.invalid",
+			"token": "token",
+			name:    -1,
+		}))
+		if !diags.HasError() {
+			t.Errorf("expected a negative %s to be refused", name)
+		}
+	}
+}
+
+// testAccPreCheck points the acceptance tests at the Wiki.js given by WIKIJS_HOST and WIKIJS_TOKEN, or at an
+// in-process testserver when WIKIJS_HOST is not set.
 func testAccPreCheck(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") == "" {
+		testAccServer(t)
+	}
 	testEnvVars := []string{"WIKIJS_HOST", "WIKIJS_TOKEN"}
 	for _, env := range testEnvVars {
 		if os.Getenv(env) == "" {
@@ -34,3 +82,57 @@ func testAccPreCheck(t *testing.T) {
 		}
 	}
 }
+
+// testAccServer points the acceptance tests at a new testserver, for the tests that seed it with data.
+func testAccServer(t *testing.T) *testserver.Server {
+	t.Helper()
+	// Without a local Terraform CLI the SDK downloads one, and exits the whole test binary when offline.
+	if os.Getenv("TF_ACC_TERRAFORM_PATH") == "" && os.Getenv("TF_ACC_TERRAFORM_VERSION") == "" {
+		if _, err := exec.LookPath("terraform"); err != nil {
+			testAccSkip(t, "terraform CLI not found, install it or set TF_ACC_TERRAFORM_PATH to run acceptance tests against the testserver")
+		}
+	}
+	srv := testserver.New(t)
+	t.Setenv("WIKIJS_HOST", srv.URL)
+	t.Setenv("WIKIJS_TOKEN", testserver.Token)
+	return srv
+}
+
+// testAccSkip skips an acceptance test that cannot run on this machine, and fails it in CI, where the CI
+// environment variable is set and the Terraform CLI must be installed so that no acceptance test goes unnoticed.
+func testAccSkip(t *testing.T, reason string) {
+	t.Helper()
+	if os.Getenv("CI") != "" {
+		t.Fatal(reason)
+	}
+	t.Skip(reason)
+}
+
+// testAccSkipTerraformBefore skips the test when the Terraform CLI running the acceptance tests is older than
+// minimum, e.g. for configurations using import blocks.
+func testAccSkipTerraformBefore(t *testing.T, minimum Version) {
+	version := os.Getenv("TF_ACC_TERRAFORM_VERSION")
+	if version == "" {
+		path := os.Getenv("TF_ACC_TERRAFORM_PATH")
+		if path == "" {
+			path = "terraform"
+		}
+		out, err := exec.Command(path, "version", "-json").Output()
+		var parsed struct {
+			TerraformVersion string `json:"terraform_version"`
+		}
+		if err != nil || json.Unmarshal(out, &parsed) != nil {
+			testAccSkip(t, fmt.Sprintf("cannot determine the Terraform version: %v", err))
+		}
+		version = parsed.TerraformVersion
+	}
+	if v, err := ParseVersion(version); err != nil || !v.AtLeast(minimum) {
+		t.Skipf("Terraform %s or later is required, found %s", minimum, version)
+	}
+}
+
+// testServerClient starts a testserver and returns a client authenticated against it.
+func testServerClient(t *testing.T) (*testserver.Server, *Client) {
+	srv := testserver.New(t)
+	return srv, NewClient(Config{Host: srv.URL, Token: testserver.Token, Retry: testRetryPolicy})
+}
@@ -9,11 +9,14 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
 	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
 	"github.com/mitchellh/mapstructure"
 	"golang.org/x/exp/slices"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -27,6 +30,23 @@ func resourceGroup() *schema.Resource {
 		UpdateContext: resourceGroupUpdate,
 		DeleteContext: resourceGroupDelete,
 
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceGroupImport,
+		},
+
+		Timeouts: groupTimeouts(),
+
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceGroupV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceGroupStateUpgradeV0,
+			},
+		},
+
+		CustomizeDiff: customdiff.All(append(groupCustomizeDiff(), checkSystemGroupDiff)...),
+
 		Schema: map[string]*schema.Schema{
 			"id": {
 				Type:        schema.TypeString,
@@ -44,14 +64,36 @@ func resourceGroup() *schema.Resource {
 				Description: "isSystem",
 			},
 			"redirect_on_login": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "redirect on login path",
+				Type:             schema.TypeString,
+				Required:         true,
+				StateFunc:        redirectPathStateFunc,
+				DiffSuppressFunc: suppressRedirectPathDiff,
+				Description:      "Path users of the group are redirected to after logging in, e.g. `/docs`. A missing leading / is added. A leading installed locale, e.g. `/en/docs` for `/docs`, is not reported as a change.",
+			},
+			"preset": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				AtLeastOneOf: []string{"preset", "permissions"},
+				ValidateFunc: validation.StringInSlice(permissionPresetNames(), false),
+				Description: "Named set of permissions granted in addition to `permissions`: `reader` reads pages, " +
+					"assets and comments, `commenter` also writes comments, `editor` also reads the source and history " +
+					"of pages, writes and deletes them and uploads assets, `moderator` also manages pages, assets and " +
+					"comments, and `admin` has every permission.",
 			},
 			"permissions": {
+				Type:         schema.TypeSet,
+				Optional:     true,
+				AtLeastOneOf: []string{"preset", "permissions"},
+				Description:  "Permissions of the group, in addition to those of `preset`.",
+				Elem: &schema.Schema{
+					Type:             schema.TypeString,
+					ValidateDiagFunc: validatePermission,
+				},
+			},
+			"effective_permissions": {
 				Type:        schema.TypeSet,
-				Required:    true,
-				Description: "permissions",
+				Computed:    true,
+				Description: "The permissions of the group in Wiki.js: those of `preset` and `permissions` together.",
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -67,21 +109,28 @@ func resourceGroup() *schema.Resource {
 				Description: "updatedAt",
 			},
 			"page_rules": {
-				Type:     schema.TypeList,
-				Required: true,
+				Type:     schema.TypeSet,
+				Optional: true,
+				Set:      hashPageRule,
+				Description: "Page rules, identified by their content: the order of the blocks does not matter and " +
+					"editing a rule replaces it.",
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"id": {
-							Type:     schema.TypeString,
-							Required: true,
+							Type:        schema.TypeString,
+							Optional:    true,
+							Computed:    true,
+							Description: "Id of the rule in Wiki.js. Derived from the other attributes of the rule when not set.",
 						},
 						"deny": {
 							Type:     schema.TypeBool,
 							Required: true,
 						},
 						"match": {
-							Type:     schema.TypeString,
-							Required: true,
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice(pageRuleMatches, false),
+							Description:  "How `path` is matched: `START`, `EXACT`, `END`, `REGEX` or `TAG`. `TAG` requires Wiki.js 2.5 or later.",
 						},
 						"roles": {
 							Type:     schema.TypeList,
@@ -91,8 +140,13 @@ func resourceGroup() *schema.Resource {
 							},
 						},
 						"path": {
-							Type:     schema.TypeString,
-							Required: true,
+							Type:             schema.TypeString,
+							Required:         true,
+							DiffSuppressFunc: suppressPageRulePathDiff,
+							Description: "Path, or tag, of the pages the rule applies to. A leading / is ignored, and so is a " +
+								"trailing / with `EXACT` and `END`. Wiki.js matches the paths of pages without their locale, " +
+								"so a leading installed locale, e.g. `en` in `en/docs`, moves from `START`, `EXACT` and " +
+								"`END` paths to `locales`, which must then be empty or that locale. Tags are matched in lower case.",
 						},
 						"locales": {
 							Type:     schema.TypeList,
@@ -104,6 +158,13 @@ func resourceGroup() *schema.Resource {
 					},
 				},
 			},
+			"ignore_external_page_rules": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Description: "Leave alone the rules of the group that are not in `page_rules`, such as those managed by " +
+					"`wikijs_group_page_rule` resources, instead of deleting them. The rules imported with the group " +
+					"are managed by it.",
+			},
 			"last_updated": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -113,36 +174,121 @@ func resourceGroup() *schema.Resource {
 	}
 }
 
+// groupCustomizeDiff returns the CustomizeDiffFuncs shared by wikijs_group_resource and wikijs_system_group.
+func groupCustomizeDiff() []schema.CustomizeDiffFunc {
+	return []schema.CustomizeDiffFunc{
+		checkVersionRequirements(versionRequirement{
+			feature: "page_rules with match TAG",
+			minimum: minVersionPageRuleTag,
+			used:    pageRulesMatch("TAG"),
+		}),
+		checkPageRulesDiff,
+		lintPageRulesDiff,
+		effectivePermissionsDiff,
+	}
+}
+
+// pageRulesMatch returns whether a page rule of the planned group uses the match type.
+func pageRulesMatch(match string) func(d *schema.ResourceDiff) bool {
+	return func(d *schema.ResourceDiff) bool {
+		for _, rule := range plannedPageRules(d) {
+			if rule.match == match {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// groupTimeouts are the default timeouts of the group resources. Writing a group rewrites all its page rules,
+// and waits for the page rule resources of the same group.
+func groupTimeouts() *schema.ResourceTimeout {
+	return &schema.ResourceTimeout{
+		Create: schema.DefaultTimeout(5 * time.Minute),
+		Read:   schema.DefaultTimeout(2 * time.Minute),
+		Update: schema.DefaultTimeout(5 * time.Minute),
+		Delete: schema.DefaultTimeout(5 * time.Minute),
+	}
+}
+
 func resourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 	c := meta.(*Client)
-	id := d.Id()
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
 	name := d.Get("name")
-	data, err := c.GetGroup(id)
-	if data.Groups.Single.Name == "" && data.Groups.Single.Id == 0 {
+	data, err := c.GetGroup(ctx, id)
+	if apierror.Is(err, apierror.NotFound) {
 		d.SetId("")
 		diags = append(diags, diag.Diagnostic{Severity: diag.Warning, Summary: fmt.Sprintf("group with id %s "+
 			"and name %s no longer exists due to a change outside of terraform. it has been deleted from the state", id, name)})
 		return diags
 	}
-
 	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read group %s", id), err)
+	}
+	group := data.Groups.Single
+	keepConfiguredGroupPaths(ctx, c, d, &group)
+	if d.Get("ignore_external_page_rules").(bool) {
+		group.PageRules = managedPageRules(group.PageRules, pageRuleIDs(d.Get("page_rules").(*schema.Set)))
+	}
+	effective := gqlcStringArrayToStringArray(group.Permissions)
+	configured := interfaceSliceToStrings(d.Get("permissions").(*schema.Set).List())
+	diags = append(diags, flattenGroup(d, group)...)
+	if diags.HasError() {
+		return diags
+	}
+	if err := d.Set("permissions", configuredPermissions(d.Get("preset").(string), configured, effective)); err != nil {
 		return diag.FromErr(err)
 	}
-	if err := d.Set("name", data.Groups.Single.Name); err != nil {
+	return diags
+}
+
+// managedPageRules returns the rules of a group whose id is managed.
+func managedPageRules(rules []wjSchema.PageRule, managed map[string]bool) []wjSchema.PageRule {
+	var kept []wjSchema.PageRule
+	for _, rule := range rules {
+		if managed[string(rule.Id)] {
+			kept = append(kept, rule)
+		}
+	}
+	return kept
+}
+
+// flattenGroup sets the attributes shared by wikijs_group_resource and the wikijs_group data source.
+func flattenGroup(d *schema.ResourceData, group wjSchema.Group) diag.Diagnostics {
+	if err := d.Set("name", group.Name); err != nil {
 		return diag.FromErr(err)
 	}
-	if err := d.Set("is_system", data.Groups.Single.IsSystem); err != nil {
+	if err := d.Set("is_system", group.IsSystem); err != nil {
 		return diag.FromErr(err)
 	}
-	if err := d.Set("redirect_on_login", data.Groups.Single.RedirectOnLogin); err != nil {
+	if err := d.Set("redirect_on_login", group.RedirectOnLogin); err != nil {
 		return diag.FromErr(err)
 	}
-	if err := d.Set("permissions", data.Groups.Single.Permissions); err != nil {
+	if err := d.Set("permissions", group.Permissions); err != nil {
 		return diag.FromErr(err)
 	}
-	flattenedPageRules := make([]interface{}, len(data.Groups.Single.PageRules))
-	for i, pr := range data.Groups.Single.PageRules {
+	if err := d.Set("effective_permissions", group.Permissions); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("page_rules", flattenPageRules(group.PageRules)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("created_at", group.CreatedAt); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("updated_at", group.UpdatedAt); err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func flattenPageRules(pageRules []wjSchema.PageRule) []interface{} {
+	flattenedPageRules := make([]interface{}, len(pageRules))
+	for i, pr := range pageRules {
 		oi := make(map[string]interface{})
 		oi["id"] = string(pr.Id)
 		oi["deny"] = bool(pr.Deny)
@@ -152,17 +298,7 @@ func resourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interfa
 		oi["locales"] = gqlcStringArrayToStringArray(pr.Locales)
 		flattenedPageRules[i] = oi
 	}
-	if err := d.Set("page_rules", flattenedPageRules); err != nil {
-		return diag.FromErr(err)
-	}
-	if err := d.Set("created_at", data.Groups.Single.CreatedAt); err != nil {
-		return diag.FromErr(err)
-	}
-	if err := d.Set("updated_at", data.Groups.Single.UpdatedAt); err != nil {
-		return diag.FromErr(err)
-	}
-
-	return diags
+	return flattenedPageRules
 }
 
 func resourceGroupCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -171,12 +307,28 @@ func resourceGroupCreate(ctx context.Context, d *schema.ResourceData, meta inter
 
 	name := d.Get("name").(string)
 
-	data, err := c.CreateGroup(name)
+	// Wiki.js would happily create a second Guests group, which nothing uses.
+	list, err := c.GetGroupList(ctx)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to list the groups before creating group %s", name), err)
+	}
+	for _, g := range list.Groups.List {
+		if bool(g.IsSystem) && strings.EqualFold(string(g.Name), name) {
+			return diag.Diagnostics{{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("%s is the name of a Wiki.js system group", g.Name),
+				Detail: fmt.Sprintf("Creating another group with this name would not change system group %d. "+
+					"Adopt the system group with a wikijs_system_group resource instead.", g.Id),
+			}}
+		}
+	}
+
+	data, err := c.CreateGroup(ctx, name)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to create group %s", name), err)
 	}
 
-	d.SetId(strconv.Itoa(int(data.Groups.Create.Group.Id)))
+	d.SetId(GroupID(data.Groups.Create.Group.Id).String())
 
 	tflog.Trace(ctx, fmt.Sprintf("created a resource with name %s", name))
 
@@ -194,12 +346,12 @@ func resourceGroupCreate(ctx context.Context, d *schema.ResourceData, meta inter
 
 func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	c := meta.(*Client)
-	id := d.Id()
-	name := d.Get("name").(string)
-	redirectOnLogin := d.Get("redirect_on_login").(string)
-	if !strings.HasPrefix(redirectOnLogin, "/") {
-		return diag.FromErr(fmt.Errorf("redirectOnLogin must start with /"))
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
 	}
+	name := d.Get("name").(string)
+	redirectOnLogin := normalizeRedirectPath(d.Get("redirect_on_login").(string))
 
 	globalPermissions := getGlobalPermissions(d)
 
@@ -207,15 +359,33 @@ func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, meta inter
 	if diags != nil {
 		return diags
 	}
+	if err := localizePageRuleInputs(ctx, c, pageRules); err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to prepare the page rules of group %s", name), err)
+	}
 
 	validationError := validatePageRules(pageRules, globalPermissions)
 	if validationError != nil {
 		return validationError
 	}
 
-	_, err := c.UpdateGroup(id, name, redirectOnLogin, globalPermissions, pageRules)
+	// The rules are written all at once, wikijs_group_page_rule resources must not update them meanwhile.
+	unlock, err := c.LockGroup(ctx, id)
 	if err != nil {
-		return diag.FromErr(err)
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to update group %s", name), err)
+	}
+	defer unlock()
+	// A new group only has the default rule of Wiki.js, which is replaced. An adopted system group keeps its rules.
+	if d.Get("ignore_external_page_rules").(bool) && (!d.IsNewResource() || d.Get("is_system").(bool)) {
+		external, err := externalPageRules(ctx, c, d, id)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to read the page rules of group %s", name), err)
+		}
+		pageRules = append(pageRules, external...)
+	}
+
+	_, err = c.UpdateGroup(ctx, id, name, redirectOnLogin, globalPermissions, pageRules)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to update group %s", name), err)
 	}
 	if err := d.Set("last_updated", time.Now().Format(time.RFC850)); err != nil {
 		return diag.FromErr(err)
@@ -226,32 +396,101 @@ func resourceGroupUpdate(ctx context.Context, d *schema.ResourceData, meta inter
 	return resourceGroupRead(ctx, d, meta)
 }
 
+// externalPageRules returns the rules of the group that neither were nor are in page_rules.
+func externalPageRules(ctx context.Context, c *Client, d *schema.ResourceData, id GroupID) ([]wjSchema.PageRuleInput, error) {
+	data, err := c.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before, after := d.GetChange("page_rules")
+	managed := pageRuleIDs(before.(*schema.Set))
+	for ruleID := range pageRuleIDs(after.(*schema.Set)) {
+		managed[ruleID] = true
+	}
+	var external []wjSchema.PageRuleInput
+	for _, rule := range data.Groups.Single.PageRules {
+		if !managed[string(rule.Id)] {
+			external = append(external, pageRuleInput(rule))
+		}
+	}
+	return external, nil
+}
+
+// getGlobalPermissions returns the permissions of the preset and the permissions of the group together.
 func getGlobalPermissions(d *schema.ResourceData) []string {
-	_globalPermissions := d.Get("permissions").(*schema.Set).List()
-	globalPermissions := make([]string, len(_globalPermissions))
-	for i, arg := range _globalPermissions {
-		globalPermissions[i] = arg.(string)
+	return groupPermissions(d.Get("preset").(string), interfaceSliceToStrings(d.Get("permissions").(*schema.Set).List()))
+}
+
+// configuredPermissions returns the permissions of a group read from Wiki.js as the permissions attribute: those
+// the preset does not grant, and those configured even though the preset grants them.
+func configuredPermissions(preset string, configured, effective []string) []string {
+	granted := presetPermissions(preset)
+	var permissions []string
+	for _, p := range effective {
+		if !slices.Contains(granted, p) || slices.Contains(configured, p) {
+			permissions = append(permissions, p)
+		}
 	}
-	return globalPermissions
+	return permissions
+}
+
+// checkSystemGroupDiff refuses to rename a system group, which Wiki.js and its users rely on, and checks its
+// permissions like wikijs_system_group does.
+func checkSystemGroupDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if d.Id() == "" || !d.Get("is_system").(bool) {
+		return nil
+	}
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return err
+	}
+	var problems []string
+	if d.HasChange("name") {
+		old, _ := d.GetChange("name")
+		problems = append(problems, fmt.Sprintf("%s is a Wiki.js system group, it cannot be renamed", old))
+	}
+	if err := checkSystemGroupPermissionsDiff(d, id); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return errorList(problems)
+}
+
+// effectivePermissionsDiff is the CustomizeDiffFunc planning effective_permissions from the preset and the
+// permissions.
+func effectivePermissionsDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if !d.NewValueKnown("preset") || !d.NewValueKnown("permissions") {
+		return d.SetNewComputed("effective_permissions")
+	}
+	return d.SetNew("effective_permissions", groupPermissions(d.Get("preset").(string),
+		interfaceSliceToStrings(d.Get("permissions").(*schema.Set).List())))
 }
 
 func getPageRules(d *schema.ResourceData) ([]wjSchema.PageRuleInput, diag.Diagnostics) {
+	return expandPageRules(d.Get("page_rules").(*schema.Set).List())
+}
+
+// expandPageRules converts the page_rules blocks into the input of the groups update mutation, deriving the ids
+// that are not configured.
+func expandPageRules(_pageRules []interface{}) ([]wjSchema.PageRuleInput, diag.Diagnostics) {
 	var diags diag.Diagnostics
-	_pageRules := d.Get("page_rules").([]interface{})
 	pageRules := make([]wjSchema.PageRuleInput, len(_pageRules))
 	for i, arg := range _pageRules {
 		var p wjSchema.PageRuleInput
 		err := mapstructure.Decode(arg, &p)
 		if err != nil {
-			panic(err)
-		}
-		if strings.HasPrefix(string(p.Path), "/") {
 			diags = append(diags, diag.Diagnostic{
 				Severity: diag.Error,
-				Summary:  fmt.Sprintf("page_rules.path \"%s\" must not start with /", p.Path),
-				Detail:   fmt.Sprintf("Remove the / from the start of the page_rule path. This is added automatically by wikijs."),
+				Summary:  fmt.Sprintf("Invalid page_rules block %d", i),
+				Detail:   err.Error(),
 			})
+			continue
+		}
+		if p.Id == "" {
+			if rule, ok := arg.(map[string]interface{}); ok {
+				p.Id = gqlc.String(pageRuleID(pageRuleKey(rule)))
+			}
 		}
+		p.Path = gqlc.String(normalizePageRulePath(string(p.Match), string(p.Path)))
 		pageRules[i] = p
 	}
 	if len(diags) > 0 {
@@ -270,7 +509,7 @@ func validatePageRules(pageRules []wjSchema.PageRuleInput, globalPermissions []s
 					Severity: diag.Error,
 					Summary:  fmt.Sprintf("Tried to set PageRule role for unallowed global permission '%s' in the pagerule block of id: %s", role, rule.Id),
 					Detail: fmt.Sprintf("In order to set a role for a page rule, that role must first be enabled under global permissions." +
-						" Add the permission to wikijs_group_resource.permissions."),
+						" Add the permission to wikijs_group_resource.permissions, or choose a preset granting it."),
 				})
 			}
 		}
@@ -282,14 +521,51 @@ func validatePageRules(pageRules []wjSchema.PageRuleInput, globalPermissions []s
 	}
 }
 
+// groupImportNamePrefix marks an import id as the name of the group rather than its id.
+const groupImportNamePrefix = "name:"
+
+// resourceGroupImport resolves the id given to `terraform import`, or to an import block, into the id of the
+// group: either the id itself or `name:<group name>`. Read then fills in every attribute.
+func resourceGroupImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	c := meta.(*Client)
+	importID := d.Id()
+	var id GroupID
+	if name := strings.TrimPrefix(importID, groupImportNamePrefix); name != importID {
+		found, err := c.FindGroupByName(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the group to import: %w", err)
+		}
+		id = found
+	} else {
+		parsed, err := ParseGroupID(importID)
+		if err != nil {
+			return nil, fmt.Errorf("%w, import a group by id or by name with %s<group name>", err, groupImportNamePrefix)
+		}
+		id = parsed
+	}
+	d.SetId(id.String())
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceGroupDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
 	c := meta.(*Client)
-	id := d.Id()
-	name := d.Get("name").(string)
-	_, err := c.DeleteGroup(id)
+	id, err := ParseGroupID(d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return invalidIDDiagnostics(err)
+	}
+	name := d.Get("name").(string)
+	if d.Get("is_system").(bool) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Group %s is a Wiki.js system group, which cannot be deleted", name),
+			Detail: "Remove it from the state with `terraform state rm`, or adopt it with a wikijs_system_group " +
+				"resource, which resets it to the Wiki.js defaults when destroyed.",
+		}}
+	}
+	_, err = c.DeleteGroup(ctx, id)
+	if err != nil && !apierror.Is(err, apierror.NotFound) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to delete group %s", name), err)
 	}
 	d.SetId("")
 	tflog.Trace(ctx, fmt.Sprintf("Deleted resource name %s", name))
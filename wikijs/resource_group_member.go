@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"golang.org/x/exp/slices"
+	"strings"
+	"time"
+)
+
+func resourceGroupMember() *schema.Resource {
+	return &schema.Resource{
+		Description: "Adds a single user to a Wiki.js group, leaving the other members alone, so that several " +
+			"configurations can add users to the same group. Must not be used together with `wikijs_group_membership` " +
+			"for the same group.",
+
+		CreateContext: resourceGroupMemberCreate,
+		ReadContext:   resourceGroupMemberRead,
+		DeleteContext: resourceGroupMemberDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceGroupMemberImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(2 * time.Minute),
+			Read:   schema.DefaultTimeout(1 * time.Minute),
+			Delete: schema.DefaultTimeout(2 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"group_id": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Id of the group.",
+			},
+			"user_id": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Id of the user added to the group.",
+			},
+		},
+	}
+}
+
+// groupMemberID is the id of a wikijs_group_member, `<group id>:<user id>`.
+func groupMemberID(groupID GroupID, userID UserID) string {
+	return groupID.String() + ":" + userID.String()
+}
+
+func parseGroupMemberID(id string) (GroupID, UserID, error) {
+	parts := strings.Split(id, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid group member id %q: expected <group id>:<user id>", id)
+	}
+	groupID, err := ParseGroupID(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	userID, err := ParseUserID(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return groupID, userID, nil
+}
+
+func resourceGroupMemberRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	groupID, userID, err := parseGroupMemberID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	members, err := c.GetGroupMembers(ctx, groupID)
+	if err != nil && !apierror.Is(err, apierror.NotFound) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read the members of group %s", groupID), err)
+	}
+	if err != nil || !slices.Contains(members, userID) {
+		d.SetId("")
+		diags = append(diags, diag.Diagnostic{Severity: diag.Warning, Summary: fmt.Sprintf("user %s is no longer a member "+
+			"of group %s due to a change outside of terraform. it has been deleted from the state", userID, groupID)})
+		return diags
+	}
+	if err := d.Set("group_id", int(groupID)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("user_id", int(userID)); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourceGroupMemberCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	groupID := GroupID(d.Get("group_id").(int))
+	userID := UserID(d.Get("user_id").(int))
+
+	// A user that already is a member is adopted.
+	err := c.AssignUser(ctx, groupID, userID)
+	if err != nil && !apierror.Is(err, apierror.Conflict) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to add user %s to group %s", userID, groupID), err)
+	}
+	d.SetId(groupMemberID(groupID, userID))
+
+	tflog.Trace(ctx, fmt.Sprintf("added user %s to group %s", userID, groupID))
+
+	return resourceGroupMemberRead(ctx, d, meta)
+}
+
+func resourceGroupMemberDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	groupID, userID, err := parseGroupMemberID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	err = c.UnassignUser(ctx, groupID, userID)
+	if err != nil && !apierror.Is(err, apierror.NotFound) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to remove user %s from group %s", userID, groupID), err)
+	}
+	d.SetId("")
+	tflog.Trace(ctx, fmt.Sprintf("Removed user %s from group %s", userID, groupID))
+
+	return diags
+}
+
+// resourceGroupMemberImport imports a member given as `<group id>:<user id>`.
+func resourceGroupMemberImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, _, err := parseGroupMemberID(d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestResourceGroupMember(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	g := srv.AddGroup(testserver.Group{Name: "editors"})
+	alice := srv.AddUser(testserver.User{Email: "alice@example.com", Name: "Alice"})
+	srv.AssignUser(g.ID, 1)
+
+	d := schema.TestResourceDataRaw(t, resourceGroupMember().Schema, map[string]interface{}{"group_id": g.ID, "user_id": alice.ID})
+	if diags := resourceGroupMemberCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if d.Id() != fmt.Sprintf("%d:%d", g.ID, alice.ID) {
+		t.Fatalf("unexpected id %q", d.Id())
+	}
+	if group, _ := srv.Group(g.ID); !reflect.DeepEqual(group.UserIDs, []int{1, alice.ID}) {
+		t.Fatalf("expected the other members to be kept, got %v", group.UserIDs)
+	}
+
+	// Adding a user that already is a member adopts it.
+	admin := schema.TestResourceDataRaw(t, resourceGroupMember().Schema, map[string]interface{}{"group_id": g.ID, "user_id": 1})
+	if diags := resourceGroupMemberCreate(ctx, admin, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if diags := resourceGroupMemberDelete(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if group, _ := srv.Group(g.ID); !reflect.DeepEqual(group.UserIDs, []int{1}) {
+		t.Fatalf("expected only the user to be removed, got %v", group.UserIDs)
+	}
+	d.SetId(fmt.Sprintf("%d:%d", g.ID, alice.ID))
+	if diags := resourceGroupMemberRead(ctx, d, c); diags.HasError() || d.Id() != "" {
+		t.Fatalf("expected a removed member to be deleted from state, got %v", diags)
+	}
+
+	for _, id := range []string{"3", "3:", "a:1", "3:1:2", "0:1"} {
+		d := resourceGroupMember().Data(nil)
+		d.SetId(id)
+		if _, err := resourceGroupMemberImport(ctx, d, c); err == nil {
+			t.Errorf("%q: expected an invalid id error", id)
+		}
+	}
+}
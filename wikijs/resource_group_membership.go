@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	"sort"
+	"time"
+)
+
+func resourceGroupMembership() *schema.Resource {
+	return &schema.Resource{
+		Description: "Manages all the members of a Wiki.js group. Users added to the group outside of this resource are " +
+			"removed from it. To add users to a group whose other members are managed elsewhere, use `wikijs_group_member` " +
+			"instead. Both must not be used for the same group.",
+
+		CreateContext: resourceGroupMembershipCreate,
+		ReadContext:   resourceGroupMembershipRead,
+		UpdateContext: resourceGroupMembershipUpdate,
+		DeleteContext: resourceGroupMembershipDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceGroupMembershipImport,
+		},
+
+		// Wiki.js assigns and unassigns users one mutation at a time, which adds up for large groups.
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Read:   schema.DefaultTimeout(2 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+			Delete: schema.DefaultTimeout(10 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"group_id": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Id of the group.",
+			},
+			"user_ids": {
+				Type:        schema.TypeSet,
+				Required:    true,
+				Description: "Ids of all the users of the group.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeInt,
+					ValidateFunc: validation.IntAtLeast(1),
+				},
+			},
+		},
+	}
+}
+
+func resourceGroupMembershipRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	members, err := c.GetGroupMembers(ctx, id)
+	if apierror.Is(err, apierror.NotFound) {
+		d.SetId("")
+		diags = append(diags, diag.Diagnostic{Severity: diag.Warning, Summary: fmt.Sprintf("group with id %s no longer "+
+			"exists due to a change outside of terraform. its membership has been deleted from the state", id)})
+		return diags
+	}
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read the members of group %s", id), err)
+	}
+	if err := d.Set("group_id", int(id)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("user_ids", flattenUserIDs(members)); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourceGroupMembershipCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	id := GroupID(d.Get("group_id").(int))
+
+	// The id is only set once the members match, a failed creation is simply synced again by the next apply.
+	if diags := syncGroupMembers(ctx, c, id, expandUserIDs(d.Get("user_ids").(*schema.Set))); diags.HasError() {
+		return diags
+	}
+	d.SetId(id.String())
+
+	tflog.Trace(ctx, fmt.Sprintf("created the membership of group %s", id))
+
+	return resourceGroupMembershipRead(ctx, d, meta)
+}
+
+func resourceGroupMembershipUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	if diags := syncGroupMembers(ctx, c, id, expandUserIDs(d.Get("user_ids").(*schema.Set))); diags.HasError() {
+		return diags
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("Updated the membership of group %s", id))
+
+	return resourceGroupMembershipRead(ctx, d, meta)
+}
+
+func resourceGroupMembershipDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	for _, userID := range expandUserIDs(d.Get("user_ids").(*schema.Set)) {
+		err := c.UnassignUser(ctx, id, userID)
+		if err != nil && !apierror.Is(err, apierror.NotFound) {
+			diags = append(diags, apiErrorDiagnostics(fmt.Sprintf("Failed to remove user %s from group %s", userID, id), err)...)
+		}
+	}
+	if diags.HasError() {
+		return diags
+	}
+	d.SetId("")
+	tflog.Trace(ctx, fmt.Sprintf("Deleted the membership of group %s", id))
+
+	return diags
+}
+
+// resourceGroupMembershipImport imports the membership of the group with the given id.
+func resourceGroupMembershipImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, err := ParseGroupID(d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// syncGroupMembers adds and removes the users needed for the members of the group to be desired, and nothing
+// else. It carries on after a failure so that a single diagnostic run reports every user that could not be
+// added or removed.
+func syncGroupMembers(ctx context.Context, c *Client, id GroupID, desired []UserID) diag.Diagnostics {
+	var diags diag.Diagnostics
+	current, err := c.GetGroupMembers(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read the members of group %s", id), err)
+	}
+	add, remove := membershipChanges(current, desired)
+	for _, userID := range add {
+		// A user added concurrently is already where it should be.
+		if err := c.AssignUser(ctx, id, userID); err != nil && !apierror.Is(err, apierror.Conflict) {
+			diags = append(diags, apiErrorDiagnostics(fmt.Sprintf("Failed to add user %s to group %s", userID, id), err)...)
+		}
+	}
+	for _, userID := range remove {
+		// A user deleted concurrently is no longer a member either.
+		if err := c.UnassignUser(ctx, id, userID); err != nil && !apierror.Is(err, apierror.NotFound) {
+			diags = append(diags, apiErrorDiagnostics(fmt.Sprintf("Failed to remove user %s from group %s", userID, id), err)...)
+		}
+	}
+	return diags
+}
+
+// membershipChanges returns the users to add to, and to remove from, a group whose members are current so that
+// they become desired. Both are in ascending order.
+func membershipChanges(current []UserID, desired []UserID) (add []UserID, remove []UserID) {
+	isCurrent := make(map[UserID]bool, len(current))
+	for _, id := range current {
+		isCurrent[id] = true
+	}
+	isDesired := make(map[UserID]bool, len(desired))
+	for _, id := range desired {
+		isDesired[id] = true
+		if !isCurrent[id] {
+			add = append(add, id)
+		}
+	}
+	for _, id := range current {
+		if !isDesired[id] {
+			remove = append(remove, id)
+		}
+	}
+	sortUserIDs(add)
+	sortUserIDs(remove)
+	return add, remove
+}
+
+func expandUserIDs(set *schema.Set) []UserID {
+	ids := make([]UserID, 0, set.Len())
+	for _, id := range set.List() {
+		ids = append(ids, UserID(id.(int)))
+	}
+	sortUserIDs(ids)
+	return ids
+}
+
+func flattenUserIDs(ids []UserID) []interface{} {
+	out := make([]interface{}, len(ids))
+	for i, id := range ids {
+		out[i] = int(id)
+	}
+	return out
+}
+
+func sortUserIDs(ids []UserID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestMembershipChanges(t *testing.T) {
+	for _, tc := range []struct {
+		current, desired, add, remove []UserID
+	}{
+		{current: nil, desired: []UserID{3, 1}, add: []UserID{1, 3}},
+		{current: []UserID{1, 2}, desired: nil, remove: []UserID{1, 2}},
+		{current: []UserID{1, 2, 5}, desired: []UserID{5, 2, 1}},
+		{current: []UserID{4, 1, 2}, desired: []UserID{2, 3}, add: []UserID{3}, remove: []UserID{1, 4}},
+	} {
+		add, remove := membershipChanges(tc.current, tc.desired)
+		if !reflect.DeepEqual(add, tc.add) || !reflect.DeepEqual(remove, tc.remove) {
+			t.Errorf("%v -> %v: expected +%v -%v, got +%v -%v", tc.current, tc.desired, tc.add, tc.remove, add, remove)
+		}
+	}
+}
+
+func testMembershipData(t *testing.T, groupID int, userIDs ...int) *schema.ResourceData {
+	ids := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id
+	}
+	return schema.TestResourceDataRaw(t, resourceGroupMembership().Schema, map[string]interface{}{
+		"group_id": groupID,
+		"user_ids": ids,
+	})
+}
+
+func TestResourceGroupMembershipLifecycle(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	g := srv.AddGroup(testserver.Group{Name: "editors"})
+	alice := srv.AddUser(testserver.User{Email: "alice@example.com", Name: "Alice"})
+	bob := srv.AddUser(testserver.User{Email: "bob@example.com", Name: "Bob"})
+	carol := srv.AddUser(testserver.User{Email: "carol@example.com", Name: "Carol"})
+	srv.AssignUser(g.ID, carol.ID)
+
+	d := testMembershipData(t, g.ID, alice.ID, bob.ID)
+	if diags := resourceGroupMembershipCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if group, _ := srv.Group(g.ID); !reflect.DeepEqual(group.UserIDs, []int{alice.ID, bob.ID}) {
+		t.Fatalf("expected the membership to be authoritative, got members %v", group.UserIDs)
+	}
+
+	updated := testMembershipData(t, g.ID, bob.ID, carol.ID)
+	updated.SetId(d.Id())
+	if diags := resourceGroupMembershipUpdate(ctx, updated, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if group, _ := srv.Group(g.ID); !reflect.DeepEqual(group.UserIDs, []int{bob.ID, carol.ID}) {
+		t.Fatalf("unexpected members %v", group.UserIDs)
+	}
+	if assigned, unassigned := srv.RequestCount("groups.assignUser"), srv.RequestCount("groups.unassignUser"); assigned != 3 || unassigned != 2 {
+		t.Fatalf("expected only the changed users to be sent, got %d assignments and %d removals", assigned, unassigned)
+	}
+
+	srv.AssignUser(g.ID, alice.ID)
+	if diags := resourceGroupMembershipRead(ctx, updated, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if got := updated.Get("user_ids").(*schema.Set).Len(); got != 3 {
+		t.Fatalf("expected the user added outside of terraform to be read, got %d users", got)
+	}
+
+	if diags := resourceGroupMembershipDelete(ctx, testMembershipData(t, g.ID, bob.ID, carol.ID), c); !diags.HasError() {
+		t.Fatal("expected a membership without id to fail to delete")
+	}
+	updated = testMembershipData(t, g.ID, bob.ID, carol.ID)
+	updated.SetId(d.Id())
+	if diags := resourceGroupMembershipDelete(ctx, updated, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if group, _ := srv.Group(g.ID); !reflect.DeepEqual(group.UserIDs, []int{alice.ID}) {
+		t.Fatalf("expected only the managed users to be removed, got members %v", group.UserIDs)
+	}
+}
+
+func TestResourceGroupMembershipErrors(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	g := srv.AddGroup(testserver.Group{Name: "editors"})
+
+	d := testMembershipData(t, g.ID, 1, 404)
+	diags := resourceGroupMembershipCreate(ctx, d, c)
+	if len(diags) != 1 || diags[0].Summary != fmt.Sprintf("Failed to add user 404 to group %d", g.ID) || d.Id() != "" {
+		t.Fatalf("expected the unknown user to be reported, got %v", diags)
+	}
+
+	d = testMembershipData(t, 404, 1)
+	if diags := resourceGroupMembershipCreate(ctx, d, c); !diags.HasError() {
+		t.Fatal("expected a missing group to fail the creation")
+	}
+
+	d = testMembershipData(t, g.ID, 1)
+	d.SetId(strconv.Itoa(g.ID))
+	srv.DeleteGroup(g.ID)
+	if diags := resourceGroupMembershipRead(ctx, d, c); diags.HasError() || d.Id() != "" {
+		t.Fatalf("expected the membership of a deleted group to be removed from state, got %v", diags)
+	}
+}
+
+func TestAccResourceGroupMembership(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("the users of the group are seeded in the testserver")
+	}
+	srv := testAccServer(t)
+	alice := srv.AddUser(testserver.User{Email: "alice@example.com", Name: "Alice"})
+	bob := srv.AddUser(testserver.User{Email: "bob@example.com", Name: "Bob"})
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceGroupMembership(alice.ID, bob.ID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_group_membership.editors", "user_ids.#", "1"),
+					resource.TestCheckResourceAttr("wikijs_group_member.bob", "user_id", strconv.Itoa(bob.ID)),
+				),
+			},
+			{
+				ResourceName:      "wikijs_group_member.bob",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccResourceGroupMembership(aliceID, bobID int) string {
+	return fmt.Sprintf(`
+resource "wikijs_group_resource" "editors" {
+    name = "editors"
+    permissions = ["read:pages"]
+    redirect_on_login = "/"
+    page_rules {
+        id = "all"
+        deny = false
+        match = "START"
+        roles = ["read:pages"]
+        path = ""
+        locales = []
+    }
+}
+
+resource "wikijs_group_membership" "editors" {
+    group_id = wikijs_group_resource.editors.id
+    user_ids = [%d]
+
+    timeouts {
+        create = "15m"
+        update = "15m"
+    }
+}
+
+resource "wikijs_group_resource" "reviewers" {
+    name = "reviewers"
+    permissions = ["read:pages"]
+    redirect_on_login = "/"
+    page_rules {
+        id = "all"
+        deny = false
+        match = "START"
+        roles = ["read:pages"]
+        path = ""
+        locales = []
+    }
+}
+
+resource "wikijs_group_member" "bob" {
+    group_id = wikijs_group_resource.reviewers.id
+    user_id  = %d
+}
+`, aliceID, bobID)
+}
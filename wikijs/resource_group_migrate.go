@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// resourceGroupV0 is wikijs_group_resource as of schema version 0, when page_rules was a list whose rules all had
+// a configured id. It must not change: it describes the states written by the older versions of the provider.
+func resourceGroupV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"is_system": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"redirect_on_login": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"permissions": {
+				Type:     schema.TypeSet,
+				Required: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"created_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"updated_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"page_rules": {
+				Type:     schema.TypeList,
+				Required: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"deny": {
+							Type:     schema.TypeBool,
+							Required: true,
+						},
+						"match": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"roles": {
+							Type:     schema.TypeList,
+							Required: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"path": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"locales": {
+							Type:     schema.TypeList,
+							Required: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+			"last_updated": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// resourceGroupStateUpgradeV0 turns the page_rules list of a version 0 state into a set. Both are stored as a JSON
+// array, so every rule is kept with its id. Copies of a rule, whose id is the only difference, are left for the
+// plan to report, see checkPageRules, rather than dropped here without a word.
+func resourceGroupStateUpgradeV0(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+	rules, ok := rawState["page_rules"].([]interface{})
+	if !ok {
+		return rawState, nil
+	}
+	upgraded := make([]interface{}, 0, len(rules))
+	for _, raw := range rules {
+		if rule, ok := raw.(map[string]interface{}); ok {
+			upgraded = append(upgraded, rule)
+		}
+	}
+	rawState["page_rules"] = upgraded
+	return rawState, nil
+}
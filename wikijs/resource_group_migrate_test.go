@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestResourceGroupStateUpgradeV0(t *testing.T) {
+	var state map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"id": "3",
+		"name": "editors",
+		"page_rules": [
+			{"id": "docs", "deny": false, "match": "START", "roles": ["read:pages", "write:pages"], "path": "docs", "locales": []},
+			{"id": "frozen", "deny": true, "match": "EXACT", "roles": ["write:pages"], "path": "docs/frozen", "locales": []},
+			{"id": "copy", "deny": false, "match": "START", "roles": ["write:pages", "read:pages"], "path": "docs", "locales": []}
+		]
+	}`), &state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upgraded, err := resourceGroupStateUpgradeV0(context.Background(), state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, rule := range upgraded["page_rules"].([]interface{}) {
+		ids = append(ids, rule.(map[string]interface{})["id"].(string))
+	}
+	if !reflect.DeepEqual(ids, []string{"docs", "frozen", "copy"}) || upgraded["name"] != "editors" {
+		t.Fatalf("expected every rule to be kept with its id, got %v", upgraded)
+	}
+
+	empty := map[string]interface{}{"id": "4"}
+	if upgraded, err := resourceGroupStateUpgradeV0(context.Background(), empty, nil); err != nil || len(upgraded) != 1 {
+		t.Fatalf("expected a state without page rules to be left alone, got %v, %v", upgraded, err)
+	}
+}
@@ -0,0 +1,336 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	"golang.org/x/exp/slices"
+	"strings"
+	"time"
+)
+
+func resourceGroupPageRule() *schema.Resource {
+	return &schema.Resource{
+		Description: "Manages a single page rule of a Wiki.js group, leaving its other rules alone, so that several " +
+			"configurations can grant access to their own part of the wiki. The `wikijs_group_resource` of the group, " +
+			"if any, must set `ignore_external_page_rules`.",
+
+		CreateContext: resourceGroupPageRuleCreate,
+		ReadContext:   resourceGroupPageRuleRead,
+		UpdateContext: resourceGroupPageRuleUpdate,
+		DeleteContext: resourceGroupPageRuleDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceGroupPageRuleImport,
+		},
+
+		// The timeouts include waiting for the other rules of the group to be written.
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Read:   schema.DefaultTimeout(2 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		CustomizeDiff: customdiff.All(
+			checkVersionRequirements(versionRequirement{
+				feature: "match TAG",
+				minimum: minVersionPageRuleTag,
+				used: func(d *schema.ResourceDiff) bool {
+					return d.Get("match") == "TAG"
+				},
+			}),
+			checkGroupPageRuleDiff,
+		),
+
+		Schema: map[string]*schema.Schema{
+			"group_id": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Id of the group.",
+			},
+			"rule_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+				Description:  "Id of the rule in Wiki.js. Derived from the other attributes of the rule when not set.",
+			},
+			"deny": {
+				Type:        schema.TypeBool,
+				Required:    true,
+				Description: "Whether the rule denies the roles rather than granting them.",
+			},
+			"match": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(pageRuleMatches, false),
+				Description:  "How `path` is matched: `START`, `EXACT`, `END`, `REGEX` or `TAG`. `TAG` requires Wiki.js 2.5 or later.",
+			},
+			"roles": {
+				Type:        schema.TypeList,
+				Required:    true,
+				Description: "Permissions granted or denied, each of them must be in the permissions of the group.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"path": {
+				Type:             schema.TypeString,
+				Required:         true,
+				DiffSuppressFunc: suppressPageRulePathDiff,
+				Description: "Path, or tag, of the pages the rule applies to. A leading / is ignored, and so is a trailing / " +
+					"with `EXACT` and `END`. Wiki.js matches the paths of pages without their locale, so a leading " +
+					"installed locale, e.g. `en` in `en/docs`, moves from `START`, `EXACT` and `END` paths to " +
+					"`locales`, which must then be empty or that locale. Tags are matched in lower case.",
+			},
+			"locales": {
+				Type:        schema.TypeList,
+				Required:    true,
+				Description: "Locales of the pages the rule applies to, every locale when empty.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+// checkGroupPageRuleDiff is the CustomizeDiffFunc validating the rule like a page_rules block.
+func checkGroupPageRuleDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	return checkPageRules(ctx, meta, []plannedPageRule{plannedPageRuleFromConfig(config)})
+}
+
+// groupPageRuleID is the id of a wikijs_group_page_rule, `<group id>:<rule id>`.
+func groupPageRuleID(groupID GroupID, ruleID string) string {
+	return groupID.String() + ":" + ruleID
+}
+
+func parseGroupPageRuleID(id string) (GroupID, string, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, "", fmt.Errorf("invalid group page rule id %q: expected <group id>:<rule id>", id)
+	}
+	groupID, err := ParseGroupID(parts[0])
+	if err != nil {
+		return 0, "", err
+	}
+	return groupID, parts[1], nil
+}
+
+// expandGroupPageRule returns the rule configured by the resource, with its id and the path written to Wiki.js.
+func expandGroupPageRule(ctx context.Context, c *Client, d *schema.ResourceData) (wjSchema.PageRuleInput, diag.Diagnostics) {
+	rule := map[string]interface{}{
+		"id":      d.Get("rule_id"),
+		"deny":    d.Get("deny"),
+		"match":   d.Get("match"),
+		"roles":   d.Get("roles"),
+		"path":    d.Get("path"),
+		"locales": d.Get("locales"),
+	}
+	pageRules, diags := expandPageRules([]interface{}{rule})
+	if diags.HasError() {
+		return wjSchema.PageRuleInput{}, diags
+	}
+	if err := localizePageRuleInputs(ctx, c, pageRules); err != nil {
+		return wjSchema.PageRuleInput{}, apiErrorDiagnostics("Failed to prepare the page rule", err)
+	}
+	return pageRules[0], nil
+}
+
+// checkRulePermissions checks that the group has the permissions the rule grants or denies, as Wiki.js ignores
+// the roles of the rules that are not.
+func checkRulePermissions(group wjSchema.Group, rule wjSchema.PageRuleInput) error {
+	permissions := gqlcStringArrayToStringArray(group.Permissions)
+	for _, role := range rule.Roles {
+		if !slices.Contains(permissions, string(role)) {
+			return apierror.New(apierror.Validation, "role %q of page rule %q is not in the permissions of group %d, "+
+				"add it to wikijs_group_resource.permissions", role, rule.Id, group.Id)
+		}
+	}
+	return nil
+}
+
+func resourceGroupPageRuleRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	groupID, ruleID, err := parseGroupPageRuleID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	data, err := c.GetGroup(ctx, groupID)
+	if err != nil && !apierror.Is(err, apierror.NotFound) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read group %s", groupID), err)
+	}
+	var rule *wjSchema.PageRule
+	if err == nil {
+		for i, r := range data.Groups.Single.PageRules {
+			if string(r.Id) == ruleID {
+				rule = &data.Groups.Single.PageRules[i]
+				break
+			}
+		}
+	}
+	if rule == nil {
+		d.SetId("")
+		diags = append(diags, diag.Diagnostic{Severity: diag.Warning, Summary: fmt.Sprintf("page rule %s of group %s no "+
+			"longer exists due to a change outside of terraform. it has been deleted from the state", ruleID, groupID)})
+		return diags
+	}
+
+	if err := d.Set("group_id", int(groupID)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rule_id", ruleID); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("deny", bool(rule.Deny)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("match", string(rule.Match)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("roles", gqlcStringArrayToStringArray(rule.Roles)); err != nil {
+		return diag.FromErr(err)
+	}
+	// The configured path and locales are kept when they only differ from those of Wiki.js by an installed locale
+	// segment.
+	path, locales := string(rule.Path), gqlcStringArrayToStringArray(rule.Locales)
+	priorPath, priorLocales := d.Get("path").(string), interfaceSliceToStrings(d.Get("locales"))
+	if d.Get("match").(string) == string(rule.Match) && priorPath != path {
+		installed, err := pathLocales(ctx, c, priorPath, path)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Comparing the path of page rule %s without its locale: %s", ruleID, err))
+		} else if samePageRule(string(rule.Match), priorPath, priorLocales, path, locales, installed) {
+			path, locales = priorPath, priorLocales
+		}
+	}
+	if err := d.Set("path", path); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("locales", locales); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourceGroupPageRuleCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	groupID := GroupID(d.Get("group_id").(int))
+	rule, diags := expandGroupPageRule(ctx, c, d)
+	if diags.HasError() {
+		return diags
+	}
+
+	err := c.UpdateGroupPageRules(ctx, groupID, func(group wjSchema.Group) ([]wjSchema.PageRuleInput, error) {
+		if err := checkRulePermissions(group, rule); err != nil {
+			return nil, err
+		}
+		pageRules := make([]wjSchema.PageRuleInput, 0, len(group.PageRules)+1)
+		for _, r := range group.PageRules {
+			if r.Id == rule.Id {
+				return nil, apierror.New(apierror.Conflict, "group %s already has a page rule with id %q, import it "+
+					"with the id %s", groupID, rule.Id, groupPageRuleID(groupID, string(rule.Id)))
+			}
+			pageRules = append(pageRules, pageRuleInput(r))
+		}
+		return append(pageRules, rule), nil
+	})
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to add page rule %s to group %s", rule.Id, groupID), err)
+	}
+	d.SetId(groupPageRuleID(groupID, string(rule.Id)))
+
+	tflog.Trace(ctx, fmt.Sprintf("added page rule %s to group %s", rule.Id, groupID))
+
+	return resourceGroupPageRuleRead(ctx, d, meta)
+}
+
+func resourceGroupPageRuleUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	groupID, ruleID, err := parseGroupPageRuleID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	rule, diags := expandGroupPageRule(ctx, c, d)
+	if diags.HasError() {
+		return diags
+	}
+
+	err = c.UpdateGroupPageRules(ctx, groupID, func(group wjSchema.Group) ([]wjSchema.PageRuleInput, error) {
+		if err := checkRulePermissions(group, rule); err != nil {
+			return nil, err
+		}
+		pageRules := make([]wjSchema.PageRuleInput, len(group.PageRules))
+		found := false
+		for i, r := range group.PageRules {
+			pageRules[i] = pageRuleInput(r)
+			if string(r.Id) == ruleID {
+				pageRules[i] = rule
+				found = true
+			}
+		}
+		if !found {
+			return nil, apierror.New(apierror.NotFound, "group %s has no page rule with id %q", groupID, ruleID)
+		}
+		return pageRules, nil
+	})
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to update page rule %s of group %s", ruleID, groupID), err)
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("updated page rule %s of group %s", ruleID, groupID))
+
+	return resourceGroupPageRuleRead(ctx, d, meta)
+}
+
+func resourceGroupPageRuleDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	groupID, ruleID, err := parseGroupPageRuleID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+
+	err = c.UpdateGroupPageRules(ctx, groupID, func(group wjSchema.Group) ([]wjSchema.PageRuleInput, error) {
+		pageRules := make([]wjSchema.PageRuleInput, 0, len(group.PageRules))
+		for _, r := range group.PageRules {
+			if string(r.Id) != ruleID {
+				pageRules = append(pageRules, pageRuleInput(r))
+			}
+		}
+		return pageRules, nil
+	})
+	if err != nil && !apierror.Is(err, apierror.NotFound) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to remove page rule %s from group %s", ruleID, groupID), err)
+	}
+	d.SetId("")
+	tflog.Trace(ctx, fmt.Sprintf("Removed page rule %s from group %s", ruleID, groupID))
+
+	return diags
+}
+
+// resourceGroupPageRuleImport imports a page rule given as `<group id>:<rule id>`.
+func resourceGroupPageRuleImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, _, err := parseGroupPageRuleID(d.Id()); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
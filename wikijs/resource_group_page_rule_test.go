@@ -0,0 +1,276 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+// testGroupPageRuleData returns a wikijs_group_page_rule of the group for the path.
+func testGroupPageRuleData(t *testing.T, groupID int, path string, extra map[string]interface{}) *schema.ResourceData {
+	raw := map[string]interface{}{
+		"group_id": groupID,
+		"deny":     false,
+		"match":    "START",
+		"roles":    []interface{}{"read:pages"},
+		"path":     path,
+		"locales":  []interface{}{},
+	}
+	for k, v := range extra {
+		raw[k] = v
+	}
+	return schema.TestResourceDataRaw(t, resourceGroupPageRule().Schema, raw)
+}
+
+// pageRulePaths returns the paths of the page rules of a group of the testserver, sorted.
+func pageRulePaths(t *testing.T, srv *testserver.Server, id int) string {
+	t.Helper()
+	group, ok := srv.Group(id)
+	if !ok {
+		t.Fatalf("group %d does not exist", id)
+	}
+	var paths []string
+	for _, rule := range group.PageRules {
+		paths = append(paths, rule.Path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+func TestResourceGroupPageRule(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	g := srv.AddGroup(testserver.Group{
+		Name:        "editors",
+		Permissions: []string{"read:pages", "write:pages"},
+		PageRules:   []testserver.PageRule{{ID: "ui", Match: "START", Roles: []string{"read:pages"}, Path: "ui", Locales: []string{}}},
+	})
+
+	d := testGroupPageRuleData(t, g.ID, "docs", nil)
+	if diags := resourceGroupPageRuleCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	ruleID := d.Get("rule_id").(string)
+	if len(ruleID) != 12 || d.Id() != fmt.Sprintf("%d:%s", g.ID, ruleID) {
+		t.Fatalf("expected a derived rule id, got %q", d.Id())
+	}
+	if paths := pageRulePaths(t, srv, g.ID); paths != "docs,ui" {
+		t.Fatalf("expected the other rules to be kept, got %s", paths)
+	}
+	if group, _ := srv.Group(g.ID); group.Name != "editors" || len(group.Permissions) != 2 {
+		t.Fatalf("expected the other settings of the group to be kept, got %+v", group)
+	}
+
+	taken := testGroupPageRuleData(t, g.ID, "other", map[string]interface{}{"rule_id": "ui"})
+	if diags := resourceGroupPageRuleCreate(ctx, taken, c); !diags.HasError() || !strings.Contains(diags[0].Detail, "import it with the id") {
+		t.Fatalf("expected a rule id conflict, got %v", diags)
+	}
+	denied := testGroupPageRuleData(t, g.ID, "admin", map[string]interface{}{"roles": []interface{}{"manage:pages"}})
+	if diags := resourceGroupPageRuleCreate(ctx, denied, c); !diags.HasError() || !strings.Contains(diags[0].Detail, `role "manage:pages"`) {
+		t.Fatalf("expected the roles to be checked against the permissions of the group, got %v", diags)
+	}
+
+	updated := testGroupPageRuleData(t, g.ID, "docs/v2", map[string]interface{}{"rule_id": ruleID})
+	updated.SetId(d.Id())
+	if diags := resourceGroupPageRuleUpdate(ctx, updated, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if paths := pageRulePaths(t, srv, g.ID); paths != "docs/v2,ui" {
+		t.Fatalf("expected the rule to be updated in place, got %s", paths)
+	}
+
+	if diags := resourceGroupPageRuleDelete(ctx, updated, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if paths := pageRulePaths(t, srv, g.ID); paths != "ui" {
+		t.Fatalf("expected only the rule to be removed, got %s", paths)
+	}
+	updated.SetId(d.Id())
+	if diags := resourceGroupPageRuleRead(ctx, updated, c); diags.HasError() || updated.Id() != "" {
+		t.Fatalf("expected a removed rule to be deleted from state, got %v", diags)
+	}
+
+	for _, id := range []string{"3", "3:", "a:ui", "0:ui"} {
+		d := resourceGroupPageRule().Data(nil)
+		d.SetId(id)
+		if _, err := resourceGroupPageRuleImport(ctx, d, c); err == nil {
+			t.Errorf("%q: expected an invalid id error", id)
+		}
+	}
+	d = resourceGroupPageRule().Data(nil)
+	d.SetId(fmt.Sprintf("%d:ui", g.ID))
+	if diags := resourceGroupPageRuleRead(ctx, d, c); diags.HasError() || d.Get("path") != "ui" || d.Get("rule_id") != "ui" {
+		t.Fatalf("expected the rule to be imported, got %v", d.State())
+	}
+}
+
+func TestResourceGroupPageRuleConcurrent(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{Name: "editors", Permissions: []string{"read:pages"}})
+
+	var wg sync.WaitGroup
+	var expected []string
+	for i := 0; i < 8; i++ {
+		path := fmt.Sprintf("section-%d", i)
+		expected = append(expected, path)
+		d := testGroupPageRuleData(t, g.ID, path, nil)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if diags := resourceGroupPageRuleCreate(context.Background(), d, c); diags.HasError() {
+				t.Errorf("unexpected diagnostics: %v", diags)
+			}
+		}()
+	}
+	wg.Wait()
+	if paths := pageRulePaths(t, srv, g.ID); paths != strings.Join(expected, ",") {
+		t.Fatalf("expected no update to be lost, got %s", paths)
+	}
+}
+
+func TestResourceGroupPageRuleLockTimeout(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{Name: "editors", Permissions: []string{"read:pages"}})
+
+	// A long update of the same group holds the lock.
+	unlock, err := c.LockGroup(context.Background(), GroupID(g.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	diags := resourceGroupPageRuleCreate(ctx, testGroupPageRuleData(t, g.ID, "docs", nil), c)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected waiting for the lock to stop at the deadline, got %v", diags)
+	}
+
+	unlock()
+	if diags := resourceGroupPageRuleCreate(context.Background(), testGroupPageRuleData(t, g.ID, "docs", nil), c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics once the group is unlocked: %v", diags)
+	}
+}
+
+func TestResourceGroupIgnoreExternalPageRules(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	raw := testGroupConfig(map[string]interface{}{})
+	raw["ignore_external_page_rules"] = true
+	d := schema.TestResourceDataRaw(t, resourceGroup().Schema, raw)
+	d.MarkNewResource()
+	if diags := resourceGroupCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	groupID, _ := strconv.Atoi(d.Id())
+
+	rule := testGroupPageRuleData(t, groupID, "external", nil)
+	if diags := resourceGroupPageRuleCreate(ctx, rule, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if paths := pageRulePaths(t, srv, groupID); paths != "docs,external" {
+		t.Fatalf("expected the default rule of the new group to be replaced, got %s", paths)
+	}
+	if diags := resourceGroupRead(ctx, d, c); diags.HasError() || d.Get("page_rules").(*schema.Set).Len() != 1 {
+		t.Fatalf("expected the external rule to be left out of the state, got %v", d.Get("page_rules"))
+	}
+
+	state := d.State()
+	raw["name"] = "renamed"
+	raw["page_rules"] = []interface{}{}
+	diff, err := resourceGroup().Diff(ctx, state, terraform.NewResourceConfigRaw(raw), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := schema.InternalMap(resourceGroup().Schema).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceGroupUpdate(ctx, updated, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if paths := pageRulePaths(t, srv, groupID); paths != "external" {
+		t.Fatalf("expected the managed rule to be removed and the external one kept, got %s", paths)
+	}
+}
+
+func TestAccResourceGroupPageRule(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("the rules are checked in the testserver")
+	}
+	srv := testAccServer(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceGroupPageRule,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_group_resource.platform", "page_rules.#", "1"),
+					resource.TestCheckResourceAttr("wikijs_group_page_rule.docs", "rule_id", "docs"),
+					resource.TestCheckResourceAttrSet("wikijs_group_page_rule.blog", "rule_id"),
+					func(*terraform.State) error {
+						groups := srv.Groups()
+						if paths := pageRulePaths(t, srv, groups[len(groups)-1].ID); paths != "blog,docs,platform" {
+							return fmt.Errorf("expected the rules of every resource, got %s", paths)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:      "wikijs_group_page_rule.docs",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+const testAccResourceGroupPageRule = `
+resource "wikijs_group_resource" "platform" {
+    name = "product"
+    permissions = ["read:pages", "write:pages"]
+    redirect_on_login = "/"
+    ignore_external_page_rules = true
+    page_rules {
+        deny = false
+        match = "START"
+        roles = ["read:pages"]
+        path = "platform"
+        locales = []
+    }
+}
+
+resource "wikijs_group_page_rule" "docs" {
+    group_id = wikijs_group_resource.platform.id
+    rule_id = "docs"
+    deny = false
+    match = "START"
+    roles = ["read:pages", "write:pages"]
+    path = "docs"
+    locales = []
+}
+
+resource "wikijs_group_page_rule" "blog" {
+    group_id = wikijs_group_resource.platform.id
+    deny = true
+    match = "EXACT"
+    roles = ["write:pages"]
+    path = "blog"
+    locales = ["en"]
+}
+`
@@ -5,10 +5,18 @@
 package wikijs
 
 import (
+	"context"
+	"os"
 	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
 )
 
 func TestAccResourceGroup(t *testing.T) {
@@ -25,8 +33,8 @@ func TestAccResourceGroup(t *testing.T) {
 						"wikijs_group_resource.foo", "name", regexp.MustCompile("test-group")),
 					resource.TestMatchResourceAttr(
 						"wikijs_group_resource.foo", "page_rules.#", regexp.MustCompile("1")),
-					resource.TestMatchResourceAttr(
-						"wikijs_group_resource.foo", "page_rules.0.id", regexp.MustCompile("page_rules_dummy_id")),
+					resource.TestCheckTypeSetElemNestedAttrs(
+						"wikijs_group_resource.foo", "page_rules.*", map[string]string{"id": "page_rules_dummy_id", "path": "test"}),
 				),
 			},
 			{
@@ -36,14 +44,112 @@ func TestAccResourceGroup(t *testing.T) {
 						"wikijs_group_resource.foo", "name", regexp.MustCompile("test-group-updated")),
 					resource.TestMatchResourceAttr(
 						"wikijs_group_resource.foo", "page_rules.#", regexp.MustCompile("2")),
-					resource.TestMatchResourceAttr(
-						"wikijs_group_resource.foo", "page_rules.1.id", regexp.MustCompile("page_rules_dummy_id_2")),
+					resource.TestCheckTypeSetElemNestedAttrs(
+						"wikijs_group_resource.foo", "page_rules.*", map[string]string{"id": "page_rules_dummy_id", "path": "test"}),
+					resource.TestCheckTypeSetElemNestedAttrs(
+						"wikijs_group_resource.foo", "page_rules.*", map[string]string{"id": privateRuleID, "path": "test/private"}),
 				),
 			},
+			{
+				Config:   testAccResourceGroupReordered,
+				PlanOnly: true,
+			},
+			{
+				ResourceName:            "wikijs_group_resource.foo",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_updated"},
+			},
+			{
+				ResourceName:            "wikijs_group_resource.foo",
+				ImportState:             true,
+				ImportStateId:           "name:test-group-updated",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_updated"},
+			},
 		},
 	})
 }
 
+func TestAccResourceGroupPreset(t *testing.T) {
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceGroupPreset,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_group_resource.commenters", "permissions.#", "1"),
+					resource.TestCheckResourceAttr("wikijs_group_resource.commenters", "effective_permissions.#", "5"),
+					resource.TestCheckTypeSetElemAttr("wikijs_group_resource.commenters", "effective_permissions.*", "write:comments"),
+					resource.TestCheckTypeSetElemAttr("wikijs_group_resource.commenters", "effective_permissions.*", "read:history"),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceGroupPreset = `
+resource "wikijs_group_resource" "commenters" {
+  name              = "commenters"
+  preset            = "commenter"
+  permissions       = ["read:history"]
+  redirect_on_login = "/"
+
+  timeouts {
+    create = "1m"
+    read   = "30s"
+  }
+}
+`
+
+func TestAccResourceGroupImportBlock(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("the group to import is seeded in the testserver")
+	}
+	testAccSkipTerraformBefore(t, Version{Major: 1, Minor: 5})
+	srv := testAccServer(t)
+	srv.AddGroup(testserver.Group{
+		Name:        "existing-group",
+		Permissions: []string{"read:pages"},
+		PageRules:   []testserver.PageRule{{ID: "docs", Match: "START", Roles: []string{"read:pages"}, Path: "docs", Locales: []string{}}},
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceGroupImportBlock,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_group_resource.existing", "id", "3"),
+					resource.TestCheckResourceAttr("wikijs_group_resource.existing", "page_rules.0.path", "docs"),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceGroupImportBlock = `
+import {
+    to = wikijs_group_resource.existing
+    id = "name:existing-group"
+}
+
+resource "wikijs_group_resource" "existing" {
+    name = "existing-group"
+    permissions = ["read:pages"]
+    redirect_on_login = "/"
+    page_rules {
+        id = "docs"
+        deny = false
+        match = "START"
+        roles = ["read:pages"]
+        path = "docs"
+        locales = []
+    }
+}
+`
+
 const testAccResourceGroup = `
 resource "wikijs_group_resource" "foo" {
     name = "test-group"
@@ -74,12 +180,289 @@ resource "wikijs_group_resource" "foo" {
         locales = []
     }
     page_rules {
-        id = "page_rules_dummy_id_2"
+        deny = true
+        match = "START"
+        roles = ["write:pages"]
+        path = "test/private"
+        locales = []
+    }
+}
+`
+
+// testAccResourceGroupReordered is testAccResourceGroupUpdated with the page rules and their roles in another order.
+const testAccResourceGroupReordered = `
+resource "wikijs_group_resource" "foo" {
+    name = "test-group-updated"
+    permissions = ["read:pages", "write:pages"]
+    redirect_on_login = "/"
+    page_rules {
+        deny = true
+        match = "START"
+        roles = ["write:pages"]
+        path = "test/private"
+        locales = []
+    }
+    page_rules {
+        id = "page_rules_dummy_id"
         deny = false
         match = "START"
-        roles = ["read:pages","write:pages"]
+        roles = ["write:pages","read:pages"]
         path = "test"
         locales = []
     }
 }
 `
+
+// privateRuleID is the id derived for the page rule of testAccResourceGroupUpdated that has none.
+var privateRuleID = pageRuleID(pageRuleKey(map[string]interface{}{
+	"deny":    true,
+	"match":   "START",
+	"roles":   []interface{}{"write:pages"},
+	"path":    "test/private",
+	"locales": []interface{}{},
+}))
+
+func testGroupData(t *testing.T) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceGroup().Schema, map[string]interface{}{
+		"name":              "test-group",
+		"permissions":       []interface{}{"read:pages", "write:pages"},
+		"redirect_on_login": "/",
+		"page_rules": []interface{}{map[string]interface{}{
+			"id":      "rule",
+			"deny":    false,
+			"match":   "START",
+			"roles":   []interface{}{"read:pages"},
+			"path":    "docs",
+			"locales": []interface{}{},
+		}},
+	})
+}
+
+func TestResourceGroupLifecycle(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	d := testGroupData(t)
+
+	if diags := resourceGroupCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	id, _ := strconv.Atoi(d.Id())
+	g, ok := srv.Group(id)
+	if !ok || g.Name != "test-group" || len(g.PageRules) != 1 || g.PageRules[0].Path != "docs" {
+		t.Fatalf("unexpected group %+v", g)
+	}
+
+	srv.UpdateGroup(id, func(g *testserver.Group) { g.Name = "renamed" })
+	if diags := resourceGroupRead(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if d.Get("name") != "renamed" {
+		t.Fatalf("expected the change made outside of terraform to be read, got %q", d.Get("name"))
+	}
+
+	if diags := resourceGroupDelete(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if _, ok := srv.Group(id); ok {
+		t.Fatal("expected the group to be deleted")
+	}
+}
+
+func TestResourceGroupPreset(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	d := schema.TestResourceDataRaw(t, resourceGroup().Schema, map[string]interface{}{
+		"name":              "editors",
+		"preset":            "editor",
+		"permissions":       []interface{}{"read:pages", "manage:navigation"},
+		"redirect_on_login": "/",
+	})
+
+	if diags := resourceGroupCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	id, _ := strconv.Atoi(d.Id())
+	g, _ := srv.Group(id)
+	want := groupPermissions("editor", []string{"manage:navigation"})
+	if strings.Join(g.Permissions, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected the permissions of the preset to be granted, got %v", g.Permissions)
+	}
+	if got := d.Get("effective_permissions").(*schema.Set).Len(); got != len(want) {
+		t.Fatalf("expected %d effective permissions, got %d", len(want), got)
+	}
+	// read:pages is configured even though the preset grants it.
+	if got := d.Get("permissions").(*schema.Set); got.Len() != 2 || !got.Contains("read:pages") || !got.Contains("manage:navigation") {
+		t.Fatalf("expected the configured permissions to be kept, got %v", got.List())
+	}
+
+	srv.UpdateGroup(id, func(g *testserver.Group) { g.Permissions = append(g.Permissions, "manage:theme") })
+	if diags := resourceGroupRead(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !d.Get("permissions").(*schema.Set).Contains("manage:theme") {
+		t.Fatal("expected a permission granted outside of terraform to show up in permissions")
+	}
+}
+
+func TestResourceGroupPermissionValidation(t *testing.T) {
+	diags := resourceGroup().Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":              "typo",
+		"permissions":       []interface{}{"read:pages", "wirte:pages"},
+		"redirect_on_login": "/",
+	}))
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, `Did you mean "write:pages"?`) {
+		t.Fatalf("expected a suggestion for the misspelt permission, got %v", diags)
+	}
+
+	diags = resourceGroup().Validate(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"name":              "nothing",
+		"redirect_on_login": "/",
+	}))
+	if !diags.HasError() {
+		t.Fatal("expected a preset or permissions to be required")
+	}
+}
+
+func TestResourceGroupSystemGroups(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	groups := len(srv.Groups())
+
+	d := schema.TestResourceDataRaw(t, resourceGroup().Schema, map[string]interface{}{
+		"name":              "guests",
+		"preset":            "reader",
+		"redirect_on_login": "/",
+	})
+	diags := resourceGroupCreate(ctx, d, c)
+	if !diags.HasError() || !strings.Contains(diags[0].Detail, "wikijs_system_group") || len(srv.Groups()) != groups {
+		t.Fatalf("expected a duplicate of the Guests group to be refused, got %v", diags)
+	}
+
+	d = testGroupData(t)
+	d.SetId("1")
+	if diags := resourceGroupRead(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	diags = resourceGroupDelete(ctx, d, c)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "Administrators is a Wiki.js system group") {
+		t.Fatalf("expected the deletion of the Administrators group to be refused, got %v", diags)
+	}
+	if _, ok := srv.Group(1); !ok || d.Id() != "1" {
+		t.Fatal("expected the Administrators group to be kept")
+	}
+}
+
+func TestResourceGroupReadDeleted(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{Name: "gone"})
+	srv.DeleteGroup(g.ID)
+
+	d := testGroupData(t)
+	d.SetId(strconv.Itoa(g.ID))
+	diags := resourceGroupRead(context.Background(), d, c)
+	if diags.HasError() || d.Id() != "" {
+		t.Fatalf("expected the group to be removed from state, got id %q and %v", d.Id(), diags)
+	}
+}
+
+func TestResourceGroupCreateRollback(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.InjectFault(testserver.Forbidden("groups.update"))
+
+	d := testGroupData(t)
+	if diags := resourceGroupCreate(context.Background(), d, c); !diags.HasError() {
+		t.Fatal("expected the forbidden update to fail the creation")
+	}
+	if groups := srv.Groups(); len(groups) != 2 {
+		t.Fatalf("expected the partially created group to be deleted, got %+v", groups)
+	}
+}
+
+func TestResourceGroupInvalidID(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	operations := map[string]func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics{
+		"read":   resourceGroupRead,
+		"update": resourceGroupUpdate,
+		"delete": resourceGroupDelete,
+	}
+	for name, operation := range operations {
+		d := testGroupData(t)
+		d.SetId("not-a-number")
+		diags := operation(ctx, d, c)
+		if !diags.HasError() || diags[0].Summary != "Invalid id" {
+			t.Errorf("%s: expected an invalid id diagnostic, got %v", name, diags)
+		}
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Fatalf("expected nothing to be sent for an invalid id, got %d requests", n)
+	}
+}
+
+func TestExpandPageRulesInvalid(t *testing.T) {
+	_, diags := expandPageRules([]interface{}{
+		map[string]interface{}{"id": "ok", "match": "START", "roles": []interface{}{"read:pages"}},
+		map[string]interface{}{"id": "bad", "deny": "sometimes"},
+	})
+	if len(diags) != 1 || diags[0].Summary != "Invalid page_rules block 1" {
+		t.Fatalf("expected a diagnostic for the second block, got %v", diags)
+	}
+}
+
+func TestResourceGroupImport(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{Name: "editors"})
+	srv.AddGroup(testserver.Group{Name: "twin"})
+	srv.AddGroup(testserver.Group{Name: "twin"})
+
+	for importID, expected := range map[string]string{
+		strconv.Itoa(g.ID):     strconv.Itoa(g.ID),
+		"name:editors":         strconv.Itoa(g.ID),
+		"name:Administrators":  "1",
+		"name:missing":         "no group is named",
+		"name:twin":            "2 groups are named",
+		"editors":              "import a group by id or by name",
+		"name:":                "no group is named",
+		strconv.Itoa(g.ID + 9): strconv.Itoa(g.ID + 9),
+	} {
+		d := resourceGroup().Data(nil)
+		d.SetId(importID)
+		imported, err := resourceGroupImport(context.Background(), d, c)
+		switch {
+		case err != nil && !strings.Contains(err.Error(), expected):
+			t.Errorf("%s: expected an error containing %q, got %v", importID, expected, err)
+		case err == nil && (len(imported) != 1 || imported[0].Id() != expected):
+			t.Errorf("%s: expected id %s, got %v", importID, expected, imported)
+		}
+	}
+}
+
+func TestResourceGroupImportRead(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{
+		Name:        "editors",
+		Permissions: []string{"read:pages", "write:pages"},
+		PageRules: []testserver.PageRule{
+			{ID: "a", Match: "START", Roles: []string{"read:pages"}, Path: "docs", Locales: []string{"en"}},
+			{ID: "b", Deny: true, Match: "EXACT", Roles: []string{"write:pages"}, Path: "docs/frozen", Locales: []string{}},
+		},
+	})
+
+	d := resourceGroup().Data(nil)
+	d.SetId("name:editors")
+	if _, err := resourceGroupImport(context.Background(), d, c); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceGroupRead(context.Background(), d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	rules := map[string]map[string]interface{}{}
+	for _, rule := range d.Get("page_rules").(*schema.Set).List() {
+		rules[rule.(map[string]interface{})["id"].(string)] = rule.(map[string]interface{})
+	}
+	if d.Id() != strconv.Itoa(g.ID) || d.Get("name") != "editors" || d.Get("redirect_on_login") != "/" ||
+		d.Get("permissions").(*schema.Set).Len() != 2 || len(rules) != 2 ||
+		rules["b"]["deny"] != true || rules["a"]["locales"].([]interface{})[0] != "en" {
+		t.Fatalf("expected every attribute to be imported, got %v", d.State())
+	}
+}
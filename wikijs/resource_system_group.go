@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+	"golang.org/x/exp/slices"
+	"strings"
+)
+
+// The ids of the system groups, which Wiki.js hardcodes.
+const (
+	administratorsGroupID GroupID = 1
+	guestsGroupID         GroupID = 2
+)
+
+// systemGroup is a group created by the installation of Wiki.js, which cannot be deleted.
+type systemGroup struct {
+	id   GroupID
+	name string
+	// permissions and pageRules are those of a fresh Wiki.js 2.x installation.
+	permissions []string
+	pageRules   []wjSchema.PageRuleInput
+}
+
+// systemGroups are the Administrators and Guests groups.
+var systemGroups = []systemGroup{
+	{
+		id:          administratorsGroupID,
+		name:        "Administrators",
+		permissions: []string{"manage:system"},
+	},
+	{
+		id:          guestsGroupID,
+		name:        "Guests",
+		permissions: []string{"read:pages", "read:assets", "read:comments"},
+		pageRules: []wjSchema.PageRuleInput{{
+			Id:      "guest",
+			Match:   wjSchema.PageRuleMatchStart,
+			Roles:   stringArrayToGqlcStringArray([]string{"read:pages", "read:assets", "read:comments"}),
+			Path:    "",
+			Locales: []gqlc.String{},
+		}},
+	},
+}
+
+// findSystemGroup returns the system group with the id.
+func findSystemGroup(id GroupID) (systemGroup, bool) {
+	for _, g := range systemGroups {
+		if g.id == id {
+			return g, true
+		}
+	}
+	return systemGroup{}, false
+}
+
+// systemGroupIDs returns the ids of the system groups.
+func systemGroupIDs() []int {
+	ids := make([]int, len(systemGroups))
+	for i, g := range systemGroups {
+		ids[i] = int(g.id)
+	}
+	return ids
+}
+
+func resourceSystemGroup() *schema.Resource {
+	// The attributes are those of wikijs_group_resource, but the name, which Wiki.js and its users rely on.
+	attributes := resourceGroup().Schema
+	attributes["group_id"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Required:     true,
+		ForceNew:     true,
+		ValidateFunc: validation.IntInSlice(systemGroupIDs()),
+		Description:  "Id of the system group: 1 for Administrators, 2 for Guests.",
+	}
+	attributes["name"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "Name of the group, which cannot be changed.",
+	}
+	attributes["redirect_on_login"].Required = false
+	attributes["redirect_on_login"].Optional = true
+	attributes["redirect_on_login"].Default = "/"
+
+	return &schema.Resource{
+		Description: "Adopts a Wiki.js system group, Administrators or Guests, which Wiki.js creates on installation " +
+			"and does not let be deleted. Creating the resource updates the existing group, and destroying it resets " +
+			"the group to the defaults of a fresh installation, keeping the page rules it does not manage when " +
+			"`ignore_external_page_rules` is set.",
+
+		CreateContext: resourceSystemGroupCreate,
+		ReadContext:   resourceGroupRead,
+		UpdateContext: resourceGroupUpdate,
+		DeleteContext: resourceSystemGroupDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceSystemGroupImport,
+		},
+
+		Timeouts: groupTimeouts(),
+
+		CustomizeDiff: customdiff.All(append(groupCustomizeDiff(),
+			func(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+				return checkSystemGroupPermissionsDiff(d, GroupID(d.Get("group_id").(int)))
+			})...),
+
+		Schema: attributes,
+	}
+}
+
+// checkSystemGroupPermissionsDiff refuses the planned permissions of a system group that would lock the
+// administrators out, or let anonymous visitors administer Wiki.js. They are checked once known.
+func checkSystemGroupPermissionsDiff(d *schema.ResourceDiff, id GroupID) error {
+	if !d.NewValueKnown("preset") || !d.NewValueKnown("permissions") {
+		return nil
+	}
+	permissions := groupPermissions(d.Get("preset").(string),
+		interfaceSliceToStrings(d.Get("permissions").(*schema.Set).List()))
+	return errorList(checkSystemGroupPermissions(id, permissions))
+}
+
+// checkSystemGroupPermissions returns the problems with the permissions of a system group: Administrators must
+// keep manage:system, and Guests must not get the permissions that page rules do not apply to, which administer
+// users, groups and the site.
+func checkSystemGroupPermissions(id GroupID, permissions []string) []string {
+	var problems []string
+	switch id {
+	case administratorsGroupID:
+		if !slices.Contains(permissions, "manage:system") {
+			problems = append(problems, "the Administrators group must keep the manage:system permission, without it "+
+				"nobody may be left to administer Wiki.js")
+		}
+	case guestsGroupID:
+		pageRuleRoles := pageRuleRoles()
+		var administrative []string
+		for _, p := range permissions {
+			if !slices.Contains(pageRuleRoles, p) {
+				administrative = append(administrative, p)
+			}
+		}
+		if len(administrative) > 0 {
+			problems = append(problems, fmt.Sprintf("the Guests group must not have the %s permissions, which would "+
+				"let anonymous visitors administer Wiki.js", strings.Join(administrative, ", ")))
+		}
+	}
+	return problems
+}
+
+// resourceSystemGroupCreate adopts the system group: it checks that the group is one, and updates it.
+func resourceSystemGroupCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	id := GroupID(d.Get("group_id").(int))
+	data, err := c.GetGroup(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read system group %s", id), err)
+	}
+	group := data.Groups.Single
+	if !bool(group.IsSystem) {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Group %s (%s) is not a system group", id, group.Name),
+			Detail:   "Manage it with wikijs_group_resource instead.",
+		}}
+	}
+	d.SetId(id.String())
+	if err := d.Set("name", string(group.Name)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("is_system", true); err != nil {
+		return diag.FromErr(err)
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("adopted system group %s", group.Name))
+
+	return resourceGroupUpdate(ctx, d, meta)
+}
+
+// resourceSystemGroupDelete resets the system group to the defaults of a fresh Wiki.js installation, as it cannot
+// be deleted. With ignore_external_page_rules, the rules the resource does not manage are kept alongside the
+// default ones.
+func resourceSystemGroupDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	defaults, ok := findSystemGroup(id)
+	if !ok {
+		return diag.Errorf("group %s is not a system group", id)
+	}
+
+	unlock, err := c.LockGroup(ctx, id)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to reset system group %s", defaults.name), err)
+	}
+	defer unlock()
+	pageRules := defaults.pageRules
+	if d.Get("ignore_external_page_rules").(bool) {
+		external, err := externalPageRules(ctx, c, d, id)
+		if err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to read the page rules of system group %s", defaults.name), err)
+		}
+		pageRules = external
+		for _, rule := range defaults.pageRules {
+			if slices.IndexFunc(external, func(r wjSchema.PageRuleInput) bool { return r.Id == rule.Id }) < 0 {
+				pageRules = append(pageRules, rule)
+			}
+		}
+	}
+	_, err = c.UpdateGroup(ctx, id, defaults.name, "/", defaults.permissions, pageRules)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to reset system group %s", defaults.name), err)
+	}
+	d.SetId("")
+	tflog.Trace(ctx, fmt.Sprintf("Reset system group %s", defaults.name))
+
+	return diags
+}
+
+// resourceSystemGroupImport imports a system group by id.
+func resourceSystemGroupImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	id, err := ParseGroupID(d.Id())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := findSystemGroup(id); !ok {
+		return nil, fmt.Errorf("group %s is not a system group, import it as a wikijs_group_resource", id)
+	}
+	if err := d.Set("group_id", int(id)); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{d}, nil
+}
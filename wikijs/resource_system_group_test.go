@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestResourceSystemGroupLifecycle(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	groups := len(srv.Groups())
+	srv.UpdateGroup(2, func(g *testserver.Group) {
+		g.PageRules = append(g.PageRules, testserver.PageRule{ID: "external", Match: "EXACT", Path: "faq",
+			Roles: []string{"read:pages"}, Locales: []string{}})
+	})
+	d := schema.TestResourceDataRaw(t, resourceSystemGroup().Schema, map[string]interface{}{
+		"group_id":                   2,
+		"preset":                     "reader",
+		"ignore_external_page_rules": true,
+		"page_rules": []interface{}{map[string]interface{}{
+			"id":      "docs",
+			"deny":    false,
+			"match":   "START",
+			"roles":   []interface{}{"read:pages"},
+			"path":    "docs",
+			"locales": []interface{}{},
+		}},
+	})
+	d.MarkNewResource()
+
+	if diags := resourceSystemGroupCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	g, _ := srv.Group(2)
+	if d.Id() != "2" || len(srv.Groups()) != groups || g.Name != "Guests" || d.Get("name") != "Guests" {
+		t.Fatalf("expected the Guests group to be adopted, got %s and %+v", d.Id(), g)
+	}
+	if strings.Join(g.Permissions, ",") != "read:assets,read:comments,read:pages" || pageRulePaths(t, srv, 2) != ",docs,faq" {
+		t.Fatalf("expected the group to be updated and keep its external rules, got %+v", g)
+	}
+
+	if diags := resourceSystemGroupDelete(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	g, ok := srv.Group(2)
+	var ids []string
+	for _, rule := range g.PageRules {
+		ids = append(ids, rule.ID)
+	}
+	// The rules the resource did not manage, the rule of the installation and faq, are kept.
+	if !ok || d.Id() != "" || strings.Join(g.Permissions, ",") != "read:pages,read:assets,read:comments" ||
+		strings.Join(ids, ",") != "default,external,guest" {
+		t.Fatalf("expected the group to be reset to the Wiki.js defaults but for its external rules, got %+v", g)
+	}
+
+	// Without ignore_external_page_rules, the resource manages every rule of the group.
+	d = schema.TestResourceDataRaw(t, resourceSystemGroup().Schema, map[string]interface{}{
+		"group_id": 2,
+		"preset":   "reader",
+	})
+	d.MarkNewResource()
+	if diags := resourceSystemGroupCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if diags := resourceSystemGroupDelete(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if g, _ := srv.Group(2); len(g.PageRules) != 1 || g.PageRules[0].ID != "guest" {
+		t.Fatalf("expected the rules to be reset to the Wiki.js defaults, got %+v", g.PageRules)
+	}
+}
+
+func TestResourceSystemGroupCreateNotSystem(t *testing.T) {
+	srv, c := testServerClient(t)
+	g := srv.AddGroup(testserver.Group{Name: "editors"})
+	d := schema.TestResourceDataRaw(t, resourceSystemGroup().Schema, map[string]interface{}{
+		"group_id": g.ID,
+		"preset":   "reader",
+	})
+	diags := resourceSystemGroupCreate(context.Background(), d, c)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "is not a system group") || d.Id() != "" {
+		t.Fatalf("expected a group that is not a system group to be refused, got %v", diags)
+	}
+}
+
+func TestResourceSystemGroupImport(t *testing.T) {
+	for id, want := range map[string]string{"1": "", "2": "", "3": "not a system group", "x": "invalid"} {
+		d := resourceSystemGroup().TestResourceData()
+		d.SetId(id)
+		_, err := resourceSystemGroupImport(context.Background(), d, nil)
+		if want == "" && (err != nil || d.Get("group_id").(int) == 0) {
+			t.Errorf("expected group %s to be imported, got %v", id, err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("expected the import of %s to fail with %q, got %v", id, want, err)
+		}
+	}
+}
+
+func TestCheckSystemGroupPermissions(t *testing.T) {
+	for _, tc := range []struct {
+		id          GroupID
+		permissions []string
+		// problem is a part of the expected problem, empty when there is none.
+		problem string
+	}{
+		{id: administratorsGroupID, permissions: []string{"manage:system"}},
+		{id: administratorsGroupID, permissions: presetPermissions("editor"), problem: "must keep the manage:system permission"},
+		{id: guestsGroupID, permissions: presetPermissions("moderator")},
+		{id: guestsGroupID, permissions: []string{"read:pages", "manage:users", "manage:system"},
+			problem: "the Guests group must not have the manage:users, manage:system permissions"},
+		{id: 3, permissions: nil},
+	} {
+		problems := checkSystemGroupPermissions(tc.id, tc.permissions)
+		if tc.problem == "" && len(problems) > 0 {
+			t.Errorf("group %s with %v: unexpected problems %v", tc.id, tc.permissions, problems)
+		}
+		if tc.problem != "" && (len(problems) != 1 || !strings.Contains(problems[0], tc.problem)) {
+			t.Errorf("group %s with %v: expected %q, got %v", tc.id, tc.permissions, tc.problem, problems)
+		}
+	}
+}
+
+func TestResourceSystemGroupDiff(t *testing.T) {
+	err := testResourceDiff(t, resourceSystemGroup(), map[string]interface{}{
+		"group_id":    2,
+		"preset":      "reader",
+		"permissions": []interface{}{"manage:users"},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "the Guests group must not have the manage:users permissions") {
+		t.Fatalf("expected administrative permissions of Guests to be refused, got %v", err)
+	}
+	if err := testResourceDiff(t, resourceSystemGroup(), map[string]interface{}{"group_id": 1, "permissions": []interface{}{"manage:system"}}, nil); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+}
+
+func TestResourceGroupSystemGroupDiff(t *testing.T) {
+	state := func() *terraform.InstanceState {
+		return &terraform.InstanceState{ID: "1", Attributes: map[string]string{
+			"id": "1", "name": "Administrators", "is_system": "true", "redirect_on_login": "/",
+			"permissions.#": "1", "permissions.0": "manage:system",
+		}}
+	}
+	config := map[string]interface{}{
+		"name":              "Administrators",
+		"permissions":       []interface{}{"manage:system"},
+		"redirect_on_login": "/",
+	}
+	if err := testResourceDiffState(t, resourceGroup(), state(), config, nil); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	config["name"] = "Admins"
+	config["permissions"] = []interface{}{"read:pages"}
+	err := testResourceDiffState(t, resourceGroup(), state(), config, nil)
+	if err == nil || !strings.Contains(err.Error(), "Administrators is a Wiki.js system group, it cannot be renamed") ||
+		!strings.Contains(err.Error(), "must keep the manage:system permission") {
+		t.Fatalf("expected the rename and the permissions to be refused, got %v", err)
+	}
+}
+
+func TestAccResourceSystemGroup(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("destroying the resource resets the Guests group of the instance")
+	}
+	srv := testAccServer(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		CheckDestroy: func(*terraform.State) error {
+			g, ok := srv.Group(2)
+			if !ok || strings.Join(g.Permissions, ",") != "read:pages,read:assets,read:comments" {
+				return fmt.Errorf("expected the Guests group to be reset, got %+v", g)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceSystemGroup,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_system_group.guests", "id", "2"),
+					resource.TestCheckResourceAttr("wikijs_system_group.guests", "name", "Guests"),
+					resource.TestCheckResourceAttr("wikijs_system_group.guests", "effective_permissions.#", "3"),
+				),
+			},
+			{
+				ResourceName:            "wikijs_system_group.guests",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"last_updated", "preset", "permissions"},
+			},
+		},
+	})
+}
+
+const testAccResourceSystemGroup = `
+resource "wikijs_system_group" "guests" {
+  group_id = 2
+  preset   = "reader"
+
+  page_rules {
+    deny    = false
+    match   = "START"
+    roles   = ["read:pages", "read:assets", "read:comments"]
+    path    = "public"
+    locales = []
+  }
+}
+`
@@ -0,0 +1,456 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+	wjSchema "github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// administratorUserID is the id of the administrator account created by the installation of Wiki.js.
+const administratorUserID UserID = 1
+
+// userImportEmailPrefix marks an import id as the email of the user rather than its id.
+const userImportEmailPrefix = "email:"
+
+// userAppearances are the themes a user can choose, the empty one following the site.
+var userAppearances = []string{"", "light", "dark"}
+
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+
+func resourceUser() *schema.Resource {
+	return &schema.Resource{
+		Description: "Manages a Wiki.js user, authenticating either with a local account or with an external " +
+			"authentication provider.",
+
+		CreateContext: resourceUserCreate,
+		ReadContext:   resourceUserRead,
+		UpdateContext: resourceUserUpdate,
+		DeleteContext: resourceUserDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceUserImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(2 * time.Minute),
+			Read:   schema.DefaultTimeout(1 * time.Minute),
+			Update: schema.DefaultTimeout(2 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		CustomizeDiff: customdiff.All(
+			checkUserPasswordDiff,
+			checkUserVerifiedDiff,
+		),
+
+		Schema: map[string]*schema.Schema{
+			"email": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ValidateFunc:     validation.StringMatch(emailPattern, "must be an email address"),
+				DiffSuppressFunc: suppressEmailDiff,
+				Description:      "Email of the user, which Wiki.js requires to be unique and stores in lower case.",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringIsNotWhiteSpace,
+				Description:  "Display name of the user.",
+			},
+			"provider_key": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      "local",
+				ValidateFunc: validation.StringIsNotWhiteSpace,
+				Description:  "Key of the authentication provider the user logs in with, `local` for a Wiki.js account.",
+			},
+			"password": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        true,
+				ValidateFunc:     validation.StringLenBetween(6, 255),
+				DiffSuppressFunc: suppressAfterCreate,
+				Description: "Initial password of a `local` account, which it requires. It is only sent when creating " +
+					"the user and is not kept in the state: changing it afterwards has no effect.",
+			},
+			"must_change_password": {
+				Type:             schema.TypeBool,
+				Optional:         true,
+				Default:          false,
+				DiffSuppressFunc: suppressAfterCreate,
+				Description:      "Whether the user must change the initial password on first login. Only used when creating the user.",
+			},
+			"send_welcome_email": {
+				Type:             schema.TypeBool,
+				Optional:         true,
+				Default:          false,
+				DiffSuppressFunc: suppressAfterCreate,
+				Description:      "Whether Wiki.js emails the user about the new account. Only used when creating the user.",
+			},
+			"group_ids": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeInt, ValidateFunc: validation.IntAtLeast(1)},
+				Description: "Ids of the groups of the user. Leave it unset when the members of the groups are managed with `wikijs_group_membership` or `wikijs_group_member`.",
+			},
+			"location": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Location shown on the profile of the user.",
+			},
+			"job_title": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Job title shown on the profile of the user.",
+			},
+			"timezone": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Timezone of the user, e.g. `Europe/London`.",
+			},
+			"date_format": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Date format of the user, e.g. `YYYY-MM-DD`. Empty to follow the site.",
+			},
+			"appearance": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice(userAppearances, false),
+				Description:  "Theme of the user: `light`, `dark`, or empty to follow the site.",
+			},
+			"is_active": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the user can log in. Deactivating a user keeps its account and content.",
+			},
+			"is_verified": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+				Description: "Whether the email of the user is verified. Users created by an administrator are, and " +
+					"Wiki.js cannot unverify one, so this can only be set to verify a user who registered.",
+			},
+			"replace_user_id": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      int(administratorUserID),
+				ValidateFunc: validation.IntAtLeast(1),
+				Description: "Id of the user the content of this one, such as its pages, is reassigned to when it is " +
+					"deleted. Defaults to the administrator account created when Wiki.js was installed.",
+			},
+			"is_system": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the user is a Wiki.js system user, such as the guest.",
+			},
+			"provider_name": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Name of the authentication provider.",
+			},
+			"created_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"updated_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"last_login_at": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Time of the last login of the user, empty if it never logged in.",
+			},
+			"tfa_active": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the user logs in with two-factor authentication.",
+			},
+		},
+	}
+}
+
+// suppressEmailDiff hides the changes of case and surrounding spaces of an email, which Wiki.js drops.
+func suppressEmailDiff(_, old, new string, _ *schema.ResourceData) bool {
+	return strings.EqualFold(strings.TrimSpace(old), strings.TrimSpace(new))
+}
+
+// suppressAfterCreate is the DiffSuppressFunc of the attributes that are only sent when creating the resource.
+func suppressAfterCreate(_, _, _ string, d *schema.ResourceData) bool {
+	return d.Id() != ""
+}
+
+// checkUserPasswordDiff requires the password of a new local account, without which Wiki.js refuses it.
+func checkUserPasswordDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if d.Id() != "" || !d.NewValueKnown("provider_key") || !d.NewValueKnown("password") {
+		return nil
+	}
+	if d.Get("provider_key").(string) == "local" && d.Get("password").(string) == "" {
+		return fmt.Errorf("password is required to create a user with the local provider")
+	}
+	return nil
+}
+
+// checkUserVerifiedDiff refuses to unverify a user, which Wiki.js cannot do. Users created by an administrator
+// are verified right away.
+func checkUserVerifiedDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	verified := config.GetAttr("is_verified")
+	if !verified.IsKnown() || verified.IsNull() || verified.True() {
+		return nil
+	}
+	if d.Id() == "" {
+		return fmt.Errorf("is_verified cannot be false: Wiki.js verifies the users created by an administrator")
+	}
+	if old, _ := d.GetChange("is_verified"); old.(bool) {
+		return fmt.Errorf("is_verified cannot be set to false: Wiki.js cannot unverify user %s", d.Id())
+	}
+	return nil
+}
+
+func resourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	c := meta.(*Client)
+	id, err := ParseUserID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	user, err := c.GetUser(ctx, id)
+	if apierror.Is(err, apierror.NotFound) {
+		d.SetId("")
+		diags = append(diags, diag.Diagnostic{Severity: diag.Warning, Summary: fmt.Sprintf("user with id %s "+
+			"and email %s no longer exists due to a change outside of terraform. it has been deleted from the state",
+			id, d.Get("email"))})
+		return diags
+	}
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to read user %s", id), err)
+	}
+	return flattenUser(d, *user)
+}
+
+// flattenUser sets the attributes shared by wikijs_user and the wikijs_user data source.
+func flattenUser(d *schema.ResourceData, user wjSchema.User) diag.Diagnostics {
+	groupIDs := make([]int, len(user.Groups))
+	for i, g := range user.Groups {
+		groupIDs[i] = int(g.Id)
+	}
+	sort.Ints(groupIDs)
+	for attribute, value := range map[string]interface{}{
+		"email":         string(user.Email),
+		"name":          string(user.Name),
+		"provider_key":  string(user.ProviderKey),
+		"provider_name": string(user.ProviderName),
+		"group_ids":     groupIDs,
+		"location":      string(user.Location),
+		"job_title":     string(user.JobTitle),
+		"timezone":      string(user.Timezone),
+		"date_format":   string(user.DateFormat),
+		"appearance":    string(user.Appearance),
+		"is_active":     bool(user.IsActive),
+		"is_verified":   bool(user.IsVerified),
+		"is_system":     bool(user.IsSystem),
+		"created_at":    string(user.CreatedAt),
+		"updated_at":    string(user.UpdatedAt),
+		"last_login_at": string(user.LastLoginAt),
+		"tfa_active":    bool(user.TfaIsActive),
+	} {
+		if err := d.Set(attribute, value); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	email := d.Get("email").(string)
+	user := wjSchema.CreateUserVariables{
+		Email:              gqlc.String(email),
+		Name:               gqlc.String(d.Get("name").(string)),
+		ProviderKey:        gqlc.String(d.Get("provider_key").(string)),
+		Groups:             expandUserGroupIDs(d),
+		MustChangePassword: gqlcBool(d.Get("must_change_password").(bool)),
+		SendWelcomeEmail:   gqlcBool(d.Get("send_welcome_email").(bool)),
+	}
+	if password := d.Get("password").(string); password != "" {
+		user.PasswordRaw = gqlcString(password)
+	}
+	id, err := c.CreateUser(ctx, user)
+	if err != nil {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to create user %s", email), err)
+	}
+	d.SetId(id.String())
+	// The password is write-only.
+	if err := d.Set("password", ""); err != nil {
+		return diag.FromErr(err)
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("created user %s with id %s", email, id))
+
+	// Wiki.js only takes the profile and status of a user once it exists.
+	profile := wjSchema.UpdateUserVariables{Id: gqlc.Int(id)}
+	if expandUserProfile(d, &profile, func(attribute string) bool {
+		_, ok := d.GetOk(attribute)
+		return ok
+	}) {
+		if err := c.UpdateUser(ctx, profile); err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to update the profile of user %s", email), err)
+		}
+	}
+	if !d.Get("is_active").(bool) {
+		if err := c.SetUserActive(ctx, id, false); err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to deactivate user %s", email), err)
+		}
+	}
+
+	return resourceUserRead(ctx, d, meta)
+}
+
+func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	c := meta.(*Client)
+	id, err := ParseUserID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	email := d.Get("email").(string)
+
+	user := wjSchema.UpdateUserVariables{Id: gqlc.Int(id)}
+	changed := expandUserProfile(d, &user, func(attribute string) bool { return d.HasChange(attribute) })
+	if d.HasChange("email") {
+		user.Email = gqlcString(email)
+		changed = true
+	}
+	if d.HasChange("name") {
+		user.Name = gqlcString(d.Get("name").(string))
+		changed = true
+	}
+	if d.HasChange("group_ids") {
+		groups := expandUserGroupIDs(d)
+		user.Groups = &groups
+		changed = true
+	}
+	if changed {
+		if err := c.UpdateUser(ctx, user); err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to update user %s", email), err)
+		}
+	}
+	if d.HasChange("is_active") {
+		active := d.Get("is_active").(bool)
+		if err := c.SetUserActive(ctx, id, active); err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to set user %s active to %t", email, active), err)
+		}
+	}
+	if d.HasChange("is_verified") && d.Get("is_verified").(bool) {
+		if err := c.VerifyUser(ctx, id); err != nil {
+			return apiErrorDiagnostics(fmt.Sprintf("Failed to verify user %s", email), err)
+		}
+	}
+
+	tflog.Trace(ctx, fmt.Sprintf("updated user %s", email))
+
+	return resourceUserRead(ctx, d, meta)
+}
+
+// expandUserProfile sets the profile settings of user whose attribute is selected, and reports whether any was.
+func expandUserProfile(d *schema.ResourceData, user *wjSchema.UpdateUserVariables, selected func(attribute string) bool) bool {
+	changed := false
+	for attribute, field := range map[string]**gqlc.String{
+		"location":    &user.Location,
+		"job_title":   &user.JobTitle,
+		"timezone":    &user.Timezone,
+		"date_format": &user.DateFormat,
+		"appearance":  &user.Appearance,
+	} {
+		if selected(attribute) {
+			*field = gqlcString(d.Get(attribute).(string))
+			changed = true
+		}
+	}
+	return changed
+}
+
+// expandUserGroupIDs returns the configured groups of the user, in ascending order.
+func expandUserGroupIDs(d *schema.ResourceData) []gqlc.Int {
+	ids := d.Get("group_ids").(*schema.Set).List()
+	groups := make([]gqlc.Int, len(ids))
+	for i, id := range ids {
+		groups[i] = gqlc.Int(id.(int))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i] < groups[j] })
+	return groups
+}
+
+func resourceUserDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	id, err := ParseUserID(d.Id())
+	if err != nil {
+		return invalidIDDiagnostics(err)
+	}
+	email := d.Get("email").(string)
+	replaceID := UserID(d.Get("replace_user_id").(int))
+	if replaceID == id {
+		return diag.Diagnostics{{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("User %s cannot be replaced by itself", email),
+			Detail:   "Set replace_user_id to the user its content is reassigned to.",
+		}}
+	}
+	err = meta.(*Client).DeleteUser(ctx, id, replaceID)
+	if err != nil && !apierror.Is(err, apierror.NotFound) {
+		return apiErrorDiagnostics(fmt.Sprintf("Failed to delete user %s", email), err)
+	}
+	d.SetId("")
+	tflog.Trace(ctx, fmt.Sprintf("Deleted user %s, its content was reassigned to user %s", email, replaceID))
+
+	return diags
+}
+
+// resourceUserImport resolves the id given to `terraform import`, or to an import block, into the id of the
+// user: either the id itself or `email:<email>`. Read then fills in every attribute.
+func resourceUserImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	importID := d.Id()
+	var id UserID
+	if email := strings.TrimPrefix(importID, userImportEmailPrefix); email != importID {
+		found, err := meta.(*Client).FindUserByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the user to import: %w", err)
+		}
+		id = found
+	} else {
+		parsed, err := ParseUserID(importID)
+		if err != nil {
+			return nil, fmt.Errorf("%w, import a user by id or by email with %s<email>", err, userImportEmailPrefix)
+		}
+		id = parsed
+	}
+	d.SetId(id.String())
+	return []*schema.ResourceData{d}, nil
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestResourceUserLifecycle(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	editors := srv.AddGroup(testserver.Group{Name: "editors"})
+	d := schema.TestResourceDataRaw(t, resourceUser().Schema, map[string]interface{}{
+		"email":      "Alice@Example.com",
+		"name":       "Alice",
+		"password":   "correct horse",
+		"group_ids":  []interface{}{editors.ID},
+		"job_title":  "Editor",
+		"appearance": "dark",
+		"is_active":  false,
+	})
+	d.MarkNewResource()
+
+	if diags := resourceUserCreate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	id, _ := ParseUserID(d.Id())
+	u, ok := srv.User(int(id))
+	if !ok || u.Email != "alice@example.com" || u.Password != "correct horse" || u.JobTitle != "Editor" ||
+		u.Appearance != "dark" || u.IsActive {
+		t.Fatalf("expected the user to be created with its profile, got %+v", u)
+	}
+	if g, _ := srv.Group(editors.ID); len(g.UserIDs) != 1 || g.UserIDs[0] != int(id) {
+		t.Fatalf("expected the user to be added to its group, got %v", g.UserIDs)
+	}
+	if d.Get("password") != "" || d.Get("timezone") != "America/New_York" || !d.Get("is_verified").(bool) {
+		t.Fatalf("expected the state to be read back without the password, got %v", d.State())
+	}
+
+	page := srv.AddPage(testserver.Page{Path: "home", Title: "Home", Content: "Hi", AuthorID: int(id), CreatorID: int(id)})
+	if diags := resourceUserDelete(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if _, ok := srv.User(int(id)); ok || d.Id() != "" {
+		t.Fatal("expected the user to be deleted")
+	}
+	if p, _ := srv.Page(page.ID); p.AuthorID != int(administratorUserID) || p.CreatorID != int(administratorUserID) {
+		t.Fatalf("expected the content of the user to be reassigned to the administrator, got %+v", p)
+	}
+}
+
+func TestResourceUserUpdate(t *testing.T) {
+	srv, c := testServerClient(t)
+	ctx := context.Background()
+	u := srv.AddUser(testserver.User{Email: "bob@example.com", Name: "Bob", IsActive: true})
+	r := resourceUser()
+	state := &terraform.InstanceState{ID: fmt.Sprint(u.ID), Attributes: map[string]string{
+		"id": fmt.Sprint(u.ID), "email": "bob@example.com", "name": "Bob", "provider_key": "local",
+		"is_active": "true", "is_verified": "false", "replace_user_id": "1", "group_ids.#": "0",
+	}}
+	diff, err := testResourcePlan(t, r, state, map[string]interface{}{
+		"email":       "robert@example.com",
+		"name":        "Robert",
+		"password":    "ignored",
+		"location":    "London",
+		"is_active":   false,
+		"is_verified": true,
+	}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := diff.Attributes["password"]; ok {
+		t.Fatal("expected the password of an existing user not to be planned")
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diags := resourceUserUpdate(ctx, d, c); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	got, _ := srv.User(u.ID)
+	if got.Email != "robert@example.com" || got.Name != "Robert" || got.Location != "London" || got.IsActive ||
+		!got.IsVerified || got.Password != "" {
+		t.Fatalf("expected the user to be updated, got %+v", got)
+	}
+}
+
+func TestResourceUserDeleteSelf(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceUser().Schema, map[string]interface{}{
+		"email":           "alice@example.com",
+		"name":            "Alice",
+		"replace_user_id": 5,
+	})
+	d.SetId("5")
+	diags := resourceUserDelete(context.Background(), d, nil)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "cannot be replaced by itself") || d.Id() != "5" {
+		t.Fatalf("expected the user not to be replaced by itself, got %v", diags)
+	}
+}
+
+func TestResourceUserDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config map[string]interface{}
+		// err is a part of the expected error, empty when the plan succeeds.
+		err string
+	}{
+		{
+			name:   "local user",
+			config: map[string]interface{}{"email": "alice@example.com", "name": "Alice", "password": "secret"},
+		},
+		{
+			name:   "local user without password",
+			config: map[string]interface{}{"email": "alice@example.com", "name": "Alice"},
+			err:    "password is required",
+		},
+		{
+			name:   "external user without password",
+			config: map[string]interface{}{"email": "alice@example.com", "name": "Alice", "provider_key": "oidc"},
+		},
+		{
+			name:   "unknown password",
+			config: map[string]interface{}{"email": "alice@example.com", "name": "Alice", "password": testUnknown},
+		},
+		{
+			name: "unverified user",
+			config: map[string]interface{}{"email": "alice@example.com", "name": "Alice", "provider_key": "oidc",
+				"is_verified": false},
+			err: "Wiki.js verifies the users created by an administrator",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := testResourceDiff(t, resourceUser(), tc.config, nil)
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expected an error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	state := &terraform.InstanceState{ID: "3", Attributes: map[string]string{
+		"id": "3", "email": "alice@example.com", "name": "Alice", "provider_key": "local", "is_verified": "true",
+	}}
+	err := testResourceDiffState(t, resourceUser(), state, map[string]interface{}{
+		"email": "alice@example.com", "name": "Alice", "is_verified": false,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "Wiki.js cannot unverify user 3") {
+		t.Fatalf("expected unverifying the user to be refused, got %v", err)
+	}
+}
+
+func TestResourceUserImport(t *testing.T) {
+	srv, c := testServerClient(t)
+	u := srv.AddUser(testserver.User{Email: "carol@example.com", Name: "Carol"})
+	for importID, want := range map[string]string{
+		"3":                        "",
+		"email:Carol@example.com":  "",
+		"email:nobody@example.com": "no user has the email",
+		"carol@example.com":        "import a user by id or by email",
+	} {
+		d := resourceUser().TestResourceData()
+		d.SetId(importID)
+		_, err := resourceUserImport(context.Background(), d, c)
+		if want == "" && (err != nil || d.Id() != fmt.Sprint(u.ID)) {
+			t.Errorf("expected %s to import user %d, got %s and %v", importID, u.ID, d.Id(), err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("expected the import of %s to fail with %q, got %v", importID, want, err)
+		}
+	}
+}
+
+func TestAccResourceUser(t *testing.T) {
+	if os.Getenv("WIKIJS_HOST") != "" {
+		t.Skip("destroying the user reassigns its content on the instance")
+	}
+	testAccServer(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceUser("Alice", "Europe/London"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_user.alice", "email", "alice@example.com"),
+					resource.TestCheckResourceAttr("wikijs_user.alice", "password", ""),
+					resource.TestCheckResourceAttr("wikijs_user.alice", "group_ids.#", "1"),
+					resource.TestCheckResourceAttr("wikijs_user.alice", "is_verified", "true"),
+				),
+			},
+			{
+				Config: testAccResourceUser("Alice Smith", "Europe/Paris"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("wikijs_user.alice", "name", "Alice Smith"),
+					resource.TestCheckResourceAttr("wikijs_user.alice", "timezone", "Europe/Paris"),
+				),
+			},
+			{
+				ResourceName:      "wikijs_user.alice",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateVerifyIgnore: []string{"password", "must_change_password", "send_welcome_email",
+					"replace_user_id"},
+			},
+		},
+	})
+}
+
+func testAccResourceUser(name, timezone string) string {
+	return fmt.Sprintf(`
+resource "wikijs_group_resource" "editors" {
+  name              = "editors"
+  preset            = "editor"
+  redirect_on_login = "/"
+}
+
+resource "wikijs_user" "alice" {
+  email                = "Alice@example.com"
+  name                 = %q
+  password             = "correct horse"
+  must_change_password = true
+  group_ids            = [wikijs_group_resource.editors.id]
+  timezone             = %q
+}
+`, name, timezone)
+}
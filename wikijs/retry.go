@@ -0,0 +1,317 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// RetryPolicy controls how requests that failed for transient reasons are retried.
+type RetryPolicy struct {
+	// RetryMax is the number of retries after the first attempt. Zero disables retrying.
+	RetryMax int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between attempts.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+}
+
+// DefaultRetryPolicy is used when the provider block does not configure retries.
+var DefaultRetryPolicy = RetryPolicy{
+	RetryMax:     4,
+	RetryWaitMin: 1 * time.Second,
+	RetryWaitMax: 30 * time.Second,
+}
+
+type operationKind int
+
+const (
+	operationQuery operationKind = iota
+	operationMutation
+)
+
+type operationKindKey struct{}
+
+// withOperationKind tags ctx so the transport knows whether the request it carries may safely be replayed.
+func withOperationKind(ctx context.Context, kind operationKind) context.Context {
+	return context.WithValue(ctx, operationKindKey{}, kind)
+}
+
+// operationKindFrom defaults to operationMutation so untagged requests are never replayed blindly.
+func operationKindFrom(ctx context.Context) operationKind {
+	if kind, ok := ctx.Value(operationKindKey{}).(operationKind); ok {
+		return kind
+	}
+	return operationMutation
+}
+
+// retryTransport retries requests that failed for transient reasons and throttles outgoing requests.
+//
+// Queries are idempotent and are retried on connection errors and on 429, 502, 503 and 504 responses.
+// Mutations are only retried when the request provably did not reach Wiki.js: a failed dial, a 429 Too Many
+// Requests or a 503 Service Unavailable. A 502 or 504 from a proxy may hide a mutation that was applied, so
+// replaying it could for instance create a group twice.
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  RetryPolicy
+	limiter *rateLimiter
+	// attemptTimeout bounds a single attempt including reading its response body. Zero leaves the attempt
+	// bounded by the deadline of the request, or by DefaultRequestTimeout when it has none.
+	attemptTimeout time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	kind := operationKindFrom(ctx)
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.roundTripAttempt(attemptReq)
+		if attempt >= t.policy.RetryMax || !shouldRetry(kind, resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait := t.policy.backoff(attempt, resp)
+		// Waiting past the deadline would only end in the context error, the failure itself says more.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			tflog.Warn(ctx, fmt.Sprintf("wikijs request failed (%s), not retrying as the next attempt in %s would "+
+				"be past the deadline of the operation", describeFailure(resp, err), wait))
+			return resp, err
+		}
+		tflog.Warn(ctx, fmt.Sprintf("wikijs request failed (%s), retrying in %s (attempt %d of %d)",
+			describeFailure(resp, err), wait, attempt+1, t.policy.RetryMax))
+		if resp != nil {
+			drainBody(resp.Body)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *retryTransport) roundTripAttempt(req *http.Request) (*http.Response, error) {
+	timeout := t.attemptTimeout
+	if timeout <= 0 {
+		if _, ok := req.Context().Deadline(); ok {
+			return t.base.RoundTrip(req)
+		}
+		timeout = DefaultRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The attempt context must outlive RoundTrip so the caller can still read the body.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// rewindRequest returns req itself for the first attempt and a copy with a fresh body for every retry.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("wikijs request body cannot be replayed for a retry")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+func shouldRetry(kind operationKind, resp *http.Response, err error) bool {
+	if err != nil {
+		if isCertificateError(err) {
+			return false
+		}
+		if kind == operationQuery {
+			return true
+		}
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return kind == operationQuery
+	}
+	return false
+}
+
+// isCertificateError reports whether err is a TLS verification failure, which retrying cannot fix.
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+// backoff returns how long to wait before the retry following attempt. A Retry-After header sent by Wiki.js or
+// its proxy takes precedence, up to RetryWaitMax so that a proxy asking for an hour does not stall the apply.
+// Otherwise the wait grows exponentially from RetryWaitMin up to RetryWaitMax, with jitter so that parallel
+// resources do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if wait > p.RetryWaitMax {
+				return p.RetryWaitMax
+			}
+			return wait
+		}
+	}
+	wait := float64(p.RetryWaitMin) * math.Pow(2, float64(attempt))
+	if wait > float64(p.RetryWaitMax) {
+		wait = float64(p.RetryWaitMax)
+	}
+	if wait <= 0 {
+		return 0
+	}
+	// Full wait at worst, half of it at best.
+	return time.Duration(wait/2 + jitter(wait/2))
+}
+
+// retryAfter parses a Retry-After header given either as delay-seconds or as an HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func jitter(max float64) float64 {
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return jitterRand.Float64() * max
+}
+
+func describeFailure(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+func drainBody(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, 4096))
+	_ = body.Close()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// rateLimiter is a token bucket shared by every request a Client makes. A nil rateLimiter does not throttle.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter allows requestsPerSecond on average, with bursts of up to one second's worth of requests.
+// It returns nil, meaning unlimited, when requestsPerSecond is not positive.
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Floor(requestsPerSecond))
+	return &rateLimiter{rate: requestsPerSecond, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	for {
+		wait := l.reserve(time.Now())
+		if wait == 0 {
+			return nil
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token and returns zero, or returns how long to wait until one is available.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	if wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second)); wait > 0 {
+		return wait
+	}
+	return time.Nanosecond
+}
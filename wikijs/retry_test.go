@@ -0,0 +1,299 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/apierror"
+)
+
+var testRetryPolicy = RetryPolicy{RetryMax: 3, RetryWaitMin: time.Millisecond, RetryWaitMax: 5 * time.Millisecond}
+
+// flakyServer fails the first failures requests with status, then answers every request with a valid site
+// query or group creation response. It returns the server and a counter of the requests it received.
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var count int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), "query") && !strings.Contains(string(body), "mutation") {
+			t.Errorf("request %d was sent without its body", atomic.LoadInt32(&count))
+		}
+		if atomic.AddInt32(&count, 1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(body), "mutation") {
+			_, _ = w.Write([]byte(`{"data":{"groups":{"create":{"group":{"id":1},"responseResult":{"succeeded":true}}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"site":{"config":{"host":"h","title":"t","description":""}}}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &count
+}
+
+func TestRetryQuery(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		srv, count := flakyServer(t, 2, status, nil)
+		c := testClient(Config{Host: srv.URL, Token: "token", Retry: testRetryPolicy})
+		if _, err := c.GetSite(context.Background()); err != nil {
+			t.Fatalf("status %d: %s", status, err)
+		}
+		if *count != 3 {
+			t.Fatalf("status %d: expected 3 requests, got %d", status, *count)
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, count := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+	c := testClient(Config{Host: srv.URL, Token: "token", Retry: testRetryPolicy})
+	if _, err := c.GetSite(context.Background()); err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if *count != 4 {
+		t.Fatalf("expected 4 requests, got %d", *count)
+	}
+}
+
+func TestRetryMutation(t *testing.T) {
+	cases := []struct {
+		status   int
+		requests int32
+	}{
+		{http.StatusTooManyRequests, 2},
+		{http.StatusServiceUnavailable, 2},
+		{http.StatusBadGateway, 1},
+		{http.StatusGatewayTimeout, 1},
+		{http.StatusInternalServerError, 1},
+	}
+	for _, tc := range cases {
+		srv, count := flakyServer(t, 1, tc.status, nil)
+		c := testClient(Config{Host: srv.URL, Token: "token", Retry: testRetryPolicy})
+		_, _ = c.CreateGroup(context.Background(), "test-group")
+		if *count != tc.requests {
+			t.Fatalf("status %d: expected %d requests, got %d", tc.status, tc.requests, *count)
+		}
+	}
+}
+
+func TestRetryMutationConnectionRefused(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	var attempts int32
+	transport := &retryTransport{
+		base: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+		policy: testRetryPolicy,
+	}
+	req, _ := http.NewRequestWithContext(withOperationKind(context.Background(), operationMutation),
+		http.MethodPost, srv.URL, strings.NewReader("mutation"))
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("expected a connection error")
+	}
+	if attempts != 4 {
+		t.Fatalf("expected a refused connection to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestRetryAttemptDeadline(t *testing.T) {
+	callerDeadline := time.Now().Add(time.Hour)
+	for _, tc := range []struct {
+		name           string
+		attemptTimeout time.Duration
+		caller         bool
+		// want is the expected deadline of the attempt, relative to now unless it is the caller's.
+		want       time.Duration
+		wantCaller bool
+	}{
+		{name: "caller deadline", caller: true, wantCaller: true},
+		{name: "no deadline", want: DefaultRequestTimeout},
+		{name: "request_timeout", attemptTimeout: time.Minute, caller: true, want: time.Minute},
+		{name: "request_timeout without deadline", attemptTimeout: time.Minute, want: time.Minute},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var deadline time.Time
+			transport := &retryTransport{
+				base: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					deadline, _ = r.Context().Deadline()
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+				}),
+				attemptTimeout: tc.attemptTimeout,
+			}
+			ctx := context.Background()
+			if tc.caller {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, callerDeadline)
+				defer cancel()
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://wiki.example.com", nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			want := time.Now().Add(tc.want)
+			if tc.wantCaller {
+				want = callerDeadline
+			}
+			if diff := deadline.Sub(want); diff > time.Second || diff < -time.Second {
+				t.Fatalf("expected the attempt deadline %s, got %s", want, deadline)
+			}
+		})
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	srv, count := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})
+	c := testClient(Config{Host: srv.URL, Token: "token", Retry: RetryPolicy{RetryMax: 3, RetryWaitMin: time.Millisecond, RetryWaitMax: 2 * time.Second}})
+	start := time.Now()
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Retry-After was not honored, retried after %s", elapsed)
+	}
+	if *count != 2 {
+		t.Fatalf("expected 2 requests, got %d", *count)
+	}
+}
+
+func TestRetryAfterCapped(t *testing.T) {
+	srv, count := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"3600"}})
+	c := testClient(Config{Host: srv.URL, Token: "token", Retry: testRetryPolicy})
+	start := time.Now()
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected Retry-After to be capped by the maximum wait, retried after %s", elapsed)
+	}
+	if *count != 2 {
+		t.Fatalf("expected 2 requests, got %d", *count)
+	}
+}
+
+func TestRetryFailsFastPastDeadline(t *testing.T) {
+	srv, count := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"30"}})
+	c := testClient(Config{Host: srv.URL, Token: "token", Retry: RetryPolicy{RetryMax: 3, RetryWaitMin: time.Second, RetryWaitMax: time.Minute}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetSite(ctx)
+	if !apierror.Is(err, apierror.ServerError) || ctx.Err() != nil {
+		t.Fatalf("expected the 429 to be returned before the deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected to fail fast, failed after %s", elapsed)
+	}
+	if *count != 1 {
+		t.Fatalf("expected 1 request, got %d", *count)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	srv, count := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+	c := testClient(Config{Host: srv.URL, Token: "token", Retry: RetryPolicy{RetryMax: 10, RetryWaitMin: time.Hour, RetryWaitMax: time.Hour}})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetSite(ctx); err == nil {
+		t.Fatal("expected an error")
+	}
+	if *count != 1 {
+		t.Fatalf("expected 1 request, got %d", *count)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{RetryWaitMin: time.Second, RetryWaitMax: 10 * time.Second}
+	cases := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{2, 2 * time.Second, 4 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{4, 5 * time.Second, 10 * time.Second},
+		{20, 5 * time.Second, 10 * time.Second},
+	}
+	for _, tc := range cases {
+		for i := 0; i < 20; i++ {
+			if wait := p.backoff(tc.attempt, nil); wait < tc.min || wait > tc.max {
+				t.Fatalf("attempt %d: wait %s not within [%s, %s]", tc.attempt, wait, tc.min, tc.max)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"Sun, 01 May 2022 12:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 01 May 2022 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range cases {
+		wait, ok := retryAfter(tc.value, now)
+		if wait != tc.wait || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %s, %t; want %s, %t", tc.value, wait, ok, tc.wait, tc.ok)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(20)
+	start := time.Now()
+	// The first 20 requests use the burst, the next 10 need half a second of refill.
+	for i := 0; i < 30; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("30 requests at 20/s with a burst of 20 took %s", elapsed)
+	}
+
+	if newRateLimiter(0) != nil {
+		t.Fatal("a zero rate should not throttle")
+	}
+
+	slow := newRateLimiter(0.1)
+	_ = slow.Wait(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx); err == nil {
+		t.Fatal("expected Wait to give up when the context is done")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -6,32 +6,25 @@ package schema
 
 import (
 	"encoding/json"
-	"github.com/hasura/go-graphql-client"
+	"fmt"
 )
 
-type ResponseStatus struct {
-	Succeeded graphql.Boolean
-	ErrorCode graphql.Int
-	Slug      graphql.String
-	Message   graphql.String
-}
-
 func (res ResponseStatus) String() string {
-	out, err := json.Marshal(res)
-	if err != nil {
-		panic(err)
-	}
-	return string(out)
+	// plain drops the String method, so that the fallback does not call it again.
+	type plain ResponseStatus
+	return jsonString(plain(res))
 }
 
-type DefaultResponse struct {
-	ResponseResult ResponseStatus
+func (res DefaultResponse) String() string {
+	type plain DefaultResponse
+	return jsonString(plain(res))
 }
 
-func (res DefaultResponse) String() string {
-	out, err := json.Marshal(res)
+// jsonString formats v as JSON, falling back to the Go syntax of v if it cannot be marshalled.
+func jsonString(v interface{}) string {
+	out, err := json.Marshal(v)
 	if err != nil {
-		panic(err)
+		return fmt.Sprintf("%+v", v)
 	}
 	return string(out)
 }
@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package schema
+
+import (
+	"testing"
+)
+
+func TestResponseString(t *testing.T) {
+	res := DefaultResponse{ResponseResult: ResponseStatus{Succeeded: false, ErrorCode: 1012, Slug: "InputInvalid", Message: "Invalid input"}}
+	expected := `{"ResponseResult":{"Succeeded":false,"ErrorCode":1012,"Slug":"InputInvalid","Message":"Invalid input"}}`
+	if got := res.String(); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,10 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+// Package schema holds the Go structs of the Wiki.js GraphQL operations sent by the provider. They are generated
+// from operations.graphql and the vendored Wiki.js schema in wikijs.graphql, run `go generate ./wikijs/schema`
+// after changing either file.
+package schema
+
+//go:generate go run ./internal/schemagen -schema wikijs.graphql -operations operations.graphql -out operations_gen.go -test-out operations_gen_test.go
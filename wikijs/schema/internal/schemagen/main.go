@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+// Command schemagen generates the Go structs sent through the hasura graphql client from the operations in
+// operations.graphql, validated against the vendored Wiki.js schema in wikijs.graphql.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hasura/go-graphql-client/ident"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "wikijs.graphql", "Wiki.js GraphQL schema")
+	operationsFile := flag.String("operations", "operations.graphql", "operations to generate structs for")
+	out := flag.String("out", "operations_gen.go", "generated operations")
+	testOut := flag.String("test-out", "operations_gen_test.go", "generated registry of the operations for tests")
+	flag.Parse()
+
+	code, testCode, err := generateFiles(*schemaFile, *operationsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*out, code, 0644); err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*testOut, testCode, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generateFiles(schemaFile, operationsFile string) ([]byte, []byte, error) {
+	schemaSource, err := ioutil.ReadFile(schemaFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	operationsSource, err := ioutil.ReadFile(operationsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return generate(string(schemaSource), string(operationsSource))
+}
+
+const header = `// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+// Code generated by schemagen from wikijs.graphql and operations.graphql. DO NOT EDIT.
+
+package schema
+`
+
+// generate returns the generated operations and the test file registering them.
+func generate(schemaSource, operationsSource string) ([]byte, []byte, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "wikijs.graphql", Input: schemaSource})
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, errs := gqlparser.LoadQuery(schema, operationsSource)
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	g := &generator{schema: schema, enums: map[string]bool{}, inputs: map[string]bool{}}
+	var types bytes.Buffer
+	for _, fragment := range doc.Fragments {
+		body, err := g.selectionStruct(fragment.SelectionSet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fragment %s: %w", fragment.Name, err)
+		}
+		fmt.Fprintf(&types, "\n// %s is generated from the %s fragment.\ntype %s %s\n", fragment.Name, fragment.Name, fragment.Name, body)
+	}
+
+	var registry bytes.Buffer
+	for _, op := range doc.Operations {
+		if op.Name == "" {
+			return nil, nil, fmt.Errorf("operations must be named")
+		}
+		body, err := g.selectionStruct(op.SelectionSet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %s: %w", op.Name, err)
+		}
+		fmt.Fprintf(&types, "\n// %sData is the result of the %s %s.\ntype %sData %s\n", op.Name, op.Name, op.Operation, op.Name, body)
+
+		variables := "nil"
+		if len(op.VariableDefinitions) > 0 {
+			code, err := g.variables(op)
+			if err != nil {
+				return nil, nil, fmt.Errorf("operation %s: %w", op.Name, err)
+			}
+			types.WriteString(code)
+			variables = op.Name + "Variables{}.Map()"
+		}
+		fmt.Fprintf(&registry, "\t%q: {&%sData{}, %s, %t},\n", op.Name+"Data", op.Name, variables, op.Operation == ast.Mutation)
+	}
+
+	var code bytes.Buffer
+	code.WriteString(header)
+	code.WriteString("\nimport (\n\tgqlc \"github.com/hasura/go-graphql-client\"\n)\n")
+	code.WriteString("\n// Date is the Date scalar of the Wiki.js schema, an ISO 8601 timestamp.\ntype Date string\n")
+	code.WriteString(g.enumTypes())
+	code.WriteString(g.inputTypes())
+	code.Write(types.Bytes())
+
+	var test bytes.Buffer
+	test.WriteString(header)
+	test.WriteString("\n// generatedOperations are the operations generated from operations.graphql.\n")
+	test.WriteString("var generatedOperations = map[string]operation{\n")
+	test.Write(registry.Bytes())
+	test.WriteString("}\n")
+
+	formatted, err := format.Source(code.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("formatting generated code: %w\n%s", err, code.String())
+	}
+	formattedTest, err := format.Source(test.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("formatting generated test: %w", err)
+	}
+	return formatted, formattedTest, nil
+}
+
+type generator struct {
+	schema *ast.Schema
+	enums  map[string]bool
+	inputs map[string]bool
+}
+
+// selectionStruct returns the struct type of a selection set.
+func (g *generator) selectionStruct(selections ast.SelectionSet) (string, error) {
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, selection := range selections {
+		field, ok := selection.(*ast.Field)
+		if !ok {
+			return "", fmt.Errorf("fragment spreads must be the only selection of a field")
+		}
+		typ, err := g.outputType(field.Definition.Type, field.SelectionSet)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", field.Name, err)
+		}
+		name := exported(field.Alias)
+		fmt.Fprintf(&b, "%s %s", name, typ)
+		if tag := fieldTag(name, field); tag != "" {
+			fmt.Fprintf(&b, " `graphql:%q`", tag)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.String(), nil
+}
+
+// fieldTag returns the graphql tag of a field, needed when the field takes arguments, is aliased or when its
+// name is not the one the graphql client derives from the Go field name.
+func fieldTag(goName string, field *ast.Field) string {
+	var tag strings.Builder
+	if field.Alias != field.Name {
+		tag.WriteString(field.Alias + ": ")
+	}
+	tag.WriteString(field.Name)
+	if len(field.Arguments) > 0 {
+		args := make([]string, len(field.Arguments))
+		for i, arg := range field.Arguments {
+			args[i] = arg.Name + ": " + arg.Value.String()
+		}
+		tag.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	if tag.String() == ident.ParseMixedCaps(goName).ToLowerCamelCase() {
+		return ""
+	}
+	return tag.String()
+}
+
+func (g *generator) outputType(t *ast.Type, selections ast.SelectionSet) (string, error) {
+	if t.Elem != nil {
+		elem, err := g.outputType(t.Elem, selections)
+		return "[]" + elem, err
+	}
+	def := g.schema.Types[t.NamedType]
+	switch def.Kind {
+	case ast.Scalar:
+		return scalarType(def.Name)
+	case ast.Enum:
+		g.enums[def.Name] = true
+		return def.Name, nil
+	case ast.Object:
+		if len(selections) == 1 {
+			if spread, ok := selections[0].(*ast.FragmentSpread); ok {
+				return spread.Name, nil
+			}
+		}
+		return g.selectionStruct(selections)
+	}
+	return "", fmt.Errorf("unsupported type %s of kind %s", def.Name, def.Kind)
+}
+
+// inputType returns the Go type of a variable or input field. Lists are typed with their non-null element type
+// because the graphql client declares every element non-null; nullable variables are pointers so that the
+// client declares them nullable.
+func (g *generator) inputType(t *ast.Type, pointer bool) (string, error) {
+	var base string
+	if t.Elem != nil {
+		elem := *t.Elem
+		elem.NonNull = true
+		typ, err := g.inputType(&elem, false)
+		if err != nil {
+			return "", err
+		}
+		base = "[]" + typ
+	} else {
+		def := g.schema.Types[t.NamedType]
+		switch def.Kind {
+		case ast.Scalar:
+			typ, err := scalarType(def.Name)
+			if err != nil {
+				return "", err
+			}
+			base = typ
+		case ast.Enum:
+			g.enums[def.Name] = true
+			base = def.Name
+		case ast.InputObject:
+			g.addInput(def)
+			base = def.Name
+		default:
+			return "", fmt.Errorf("unsupported input type %s of kind %s", def.Name, def.Kind)
+		}
+	}
+	if pointer && !t.NonNull {
+		return "*" + base, nil
+	}
+	return base, nil
+}
+
+func (g *generator) addInput(def *ast.Definition) {
+	if g.inputs[def.Name] {
+		return
+	}
+	g.inputs[def.Name] = true
+	for _, field := range def.Fields {
+		_, _ = g.inputType(field.Type, false)
+	}
+}
+
+func (g *generator) variables(op *ast.OperationDefinition) (string, error) {
+	var fields, values strings.Builder
+	for _, v := range op.VariableDefinitions {
+		typ, err := g.inputType(v.Type, true)
+		if err != nil {
+			return "", fmt.Errorf("$%s: %w", v.Variable, err)
+		}
+		fmt.Fprintf(&fields, "%s %s\n", exported(v.Variable), typ)
+		fmt.Fprintf(&values, "%q: v.%s,\n", v.Variable, exported(v.Variable))
+	}
+	return fmt.Sprintf(`
+// %[1]sVariables are the variables of the %[1]s %[2]s.
+type %[1]sVariables struct {
+%[3]s}
+
+// Map returns the variables in the form taken by the graphql client.
+func (v %[1]sVariables) Map() map[string]interface{} {
+	return map[string]interface{}{
+%[4]s	}
+}
+`, op.Name, op.Operation, fields.String(), values.String()), nil
+}
+
+func (g *generator) enumTypes() string {
+	var b strings.Builder
+	for _, name := range sortedKeys(g.enums) {
+		def := g.schema.Types[name]
+		fmt.Fprintf(&b, "\n// %s is the %s enum of the Wiki.js schema.\ntype %s string\n\nconst (\n", name, name, name)
+		for _, v := range def.EnumValues {
+			fmt.Fprintf(&b, "%s%s %s = %q\n", name, exported(strings.ToLower(v.Name)), name, v.Name)
+		}
+		b.WriteString(")\n")
+	}
+	return b.String()
+}
+
+func (g *generator) inputTypes() string {
+	var b strings.Builder
+	for _, name := range sortedKeys(g.inputs) {
+		def := g.schema.Types[name]
+		fmt.Fprintf(&b, "\n// %s is the %s input of the Wiki.js schema.\ntype %s struct {\n", name, name, name)
+		for _, field := range def.Fields {
+			typ, _ := g.inputType(field.Type, field.Type.Elem == nil)
+			tag := field.Name
+			if !field.Type.NonNull {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&b, "%s %s `json:%q`\n", exported(field.Name), typ, tag)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func scalarType(name string) (string, error) {
+	switch name {
+	case "String":
+		return "gqlc.String", nil
+	case "Int":
+		return "gqlc.Int", nil
+	case "Float":
+		return "gqlc.Float", nil
+	case "Boolean":
+		return "gqlc.Boolean", nil
+	case "ID":
+		return "gqlc.ID", nil
+	case "Date":
+		return "Date", nil
+	}
+	return "", fmt.Errorf("unsupported scalar %s", name)
+}
+
+func exported(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
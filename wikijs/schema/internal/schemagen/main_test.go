@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	code, testCode, err := generateFiles("../../wikijs.graphql", "../../operations.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string][]byte{
+		"../../operations_gen.go":      code,
+		"../../operations_gen_test.go": testCode,
+	} {
+		got, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate ./wikijs/schema", file)
+		}
+	}
+}
+
+func TestGenerateRejectsUnknownFields(t *testing.T) {
+	schema := `type Query { groups: GroupQuery } type GroupQuery { list: [Group] } type Group { id: Int! }`
+	if _, _, err := generate(schema, `query GroupList { groups { list { id name } } }`); err == nil {
+		t.Fatal("expected a field missing from the schema to be rejected")
+	}
+	if _, _, err := generate(schema, `query GroupList { groups { list { id } } }`); err != nil {
+		t.Fatal(err)
+	}
+}
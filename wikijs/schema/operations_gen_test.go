@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+// Code generated by schemagen from wikijs.graphql and operations.graphql. DO NOT EDIT.
+
+package schema
+
+// generatedOperations are the operations generated from operations.graphql.
+var generatedOperations = map[string]operation{
+	"SiteData":              {&SiteData{}, nil, false},
+	"LoginData":             {&LoginData{}, LoginVariables{}.Map(), true},
+	"QueryGroupData":        {&QueryGroupData{}, QueryGroupVariables{}.Map(), false},
+	"QueryGroupListData":    {&QueryGroupListData{}, nil, false},
+	"CreateGroupData":       {&CreateGroupData{}, CreateGroupVariables{}.Map(), true},
+	"UpdateGroupData":       {&UpdateGroupData{}, UpdateGroupVariables{}.Map(), true},
+	"DeleteGroupData":       {&DeleteGroupData{}, DeleteGroupVariables{}.Map(), true},
+	"SystemInfoData":        {&SystemInfoData{}, nil, false},
+	"QueryGroupMembersData": {&QueryGroupMembersData{}, QueryGroupMembersVariables{}.Map(), false},
+	"AssignGroupUserData":   {&AssignGroupUserData{}, AssignGroupUserVariables{}.Map(), true},
+	"UnassignGroupUserData": {&UnassignGroupUserData{}, UnassignGroupUserVariables{}.Map(), true},
+	"QueryLocalesData":      {&QueryLocalesData{}, nil, false},
+	"QueryUserGroupsData":   {&QueryUserGroupsData{}, QueryUserGroupsVariables{}.Map(), false},
+	"QueryUserData":         {&QueryUserData{}, QueryUserVariables{}.Map(), false},
+	"QueryUserListData":     {&QueryUserListData{}, nil, false},
+	"SearchUsersData":       {&SearchUsersData{}, SearchUsersVariables{}.Map(), false},
+	"CreateUserData":        {&CreateUserData{}, CreateUserVariables{}.Map(), true},
+	"UpdateUserData":        {&UpdateUserData{}, UpdateUserVariables{}.Map(), true},
+	"DeleteUserData":        {&DeleteUserData{}, DeleteUserVariables{}.Map(), true},
+	"VerifyUserData":        {&VerifyUserData{}, VerifyUserVariables{}.Map(), true},
+	"ActivateUserData":      {&ActivateUserData{}, ActivateUserVariables{}.Map(), true},
+	"DeactivateUserData":    {&DeactivateUserData{}, DeactivateUserVariables{}.Map(), true},
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package schema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	gqlc "github.com/hasura/go-graphql-client"
+	"github.com/vektah/gqlparser/v2"
+	gqlast "github.com/vektah/gqlparser/v2/ast"
+)
+
+// operation is a struct sent through the graphql client, with variables of the types the client sends.
+type operation struct {
+	data      interface{}
+	variables map[string]interface{}
+	mutation  bool
+}
+
+// handWrittenOperations registers the <Name>Data structs written by hand rather than generated, so that they
+// are validated against the Wiki.js schema too.
+var handWrittenOperations = map[string]operation{}
+
+func loadSchema(t *testing.T) *gqlast.Schema {
+	source, err := ioutil.ReadFile("wikijs.graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := gqlparser.LoadSchema(&gqlast.Source{Name: "wikijs.graphql", Input: string(source)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestOperationsMatchSchema(t *testing.T) {
+	schema := loadSchema(t)
+	for _, operations := range []map[string]operation{generatedOperations, handWrittenOperations} {
+		for name, op := range operations {
+			construct := gqlc.ConstructQuery
+			if op.mutation {
+				construct = gqlc.ConstructMutation
+			}
+			document, err := construct(op.data, op.variables)
+			if err != nil {
+				t.Errorf("%s: %s", name, err)
+				continue
+			}
+			if _, errs := gqlparser.LoadQuery(schema, document); len(errs) > 0 {
+				t.Errorf("%s does not match the Wiki.js schema: %s\n%s", name, errs, document)
+			}
+		}
+	}
+}
+
+// TestOperationsRegistered makes sure no operation escapes TestOperationsMatchSchema.
+func TestOperationsRegistered(t *testing.T) {
+	files, err := parser.ParseDir(token.NewFileSet(), ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range files {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					name := spec.(*ast.TypeSpec).Name.Name
+					if !strings.HasSuffix(name, "Data") {
+						continue
+					}
+					_, generated := generatedOperations[name]
+					_, handWritten := handWrittenOperations[name]
+					if !generated && !handWritten {
+						t.Errorf("%s is not registered in handWrittenOperations", name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestOperationsSchemaDrift(t *testing.T) {
+	type groupData struct {
+		Groups struct {
+			Single struct {
+				Id          gqlc.Int
+				Description gqlc.String
+			} `graphql:"single(id: $id)"`
+		}
+	}
+	document, err := gqlc.ConstructQuery(&groupData{}, map[string]interface{}{"id": gqlc.Int(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, errs := gqlparser.LoadQuery(loadSchema(t), document); len(errs) == 0 {
+		t.Fatal("expected a field missing from the Wiki.js schema to be rejected")
+	}
+}
@@ -0,0 +1,534 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package testserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file holds a deliberately small GraphQL implementation: enough to parse the documents built by the
+// hasura client and to execute them against the in-memory resolvers. Fragments and directives are not
+// supported.
+
+// operation is a parsed GraphQL document.
+type operation struct {
+	// kind is "query" or "mutation".
+	kind       string
+	selections []*field
+}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]value
+	selections []*field
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// value is a GraphQL input value. Variables are kept as references and resolved at execution time.
+type value interface{}
+
+type variableRef string
+
+type enumValue string
+
+// Error is a GraphQL error returned in the `errors` array of a response.
+type Error struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func errorf(format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// resolverFunc resolves a field that takes arguments, or whose value is computed on demand.
+type resolverFunc func(args map[string]interface{}) (interface{}, error)
+
+// object is a resolved GraphQL object: field names map to plain values, nested objects or resolverFuncs.
+type object map[string]interface{}
+
+// execute runs the selections against root and returns the response data and errors. Resolver errors null the
+// failing field and are reported without aborting sibling fields, like a real GraphQL server.
+func execute(root object, selections []*field, variables map[string]interface{}) (map[string]interface{}, []*Error) {
+	var errs []*Error
+	data := resolveObject(root, "", selections, variables, &errs)
+	// Validation errors repeat for every item of a list, report each once.
+	seen := map[string]bool{}
+	unique := errs[:0]
+	for _, err := range errs {
+		if !seen[err.Message] {
+			seen[err.Message] = true
+			unique = append(unique, err)
+		}
+	}
+	return data, unique
+}
+
+func resolveObject(obj object, typePath string, selections []*field, variables map[string]interface{}, errs *[]*Error) map[string]interface{} {
+	out := make(map[string]interface{}, len(selections))
+	for _, f := range selections {
+		path := f.name
+		if typePath != "" {
+			path = typePath + "." + f.name
+		}
+		raw, ok := obj[f.name]
+		if !ok {
+			*errs = append(*errs, errorf("Cannot query field %q on type %q.", f.name, typeName(typePath)))
+			out[f.responseKey()] = nil
+			continue
+		}
+		if resolve, ok := raw.(resolverFunc); ok {
+			args, err := resolveArguments(f.arguments, variables)
+			if err != nil {
+				*errs = append(*errs, toError(err))
+				out[f.responseKey()] = nil
+				continue
+			}
+			raw, err = resolve(args)
+			if err != nil {
+				*errs = append(*errs, toError(err))
+				out[f.responseKey()] = nil
+				continue
+			}
+		} else if len(f.arguments) > 0 {
+			*errs = append(*errs, errorf("Unknown arguments on field %q.", path))
+			out[f.responseKey()] = nil
+			continue
+		}
+		out[f.responseKey()] = complete(raw, path, f, variables, errs)
+	}
+	return out
+}
+
+// complete projects a resolved value onto the selection set of f.
+func complete(raw interface{}, path string, f *field, variables map[string]interface{}, errs *[]*Error) interface{} {
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case object:
+		if len(f.selections) == 0 {
+			*errs = append(*errs, errorf("Field %q of object type must have a selection of subfields.", path))
+			return nil
+		}
+		return resolveObject(v, path, f.selections, variables, errs)
+	case []object:
+		if v == nil {
+			return nil
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = complete(item, path, f, variables, errs)
+		}
+		return out
+	default:
+		if len(f.selections) > 0 {
+			*errs = append(*errs, errorf("Field %q must not have a selection since it is a scalar.", path))
+			return nil
+		}
+		return v
+	}
+}
+
+func typeName(path string) string {
+	if path == "" {
+		return "Root"
+	}
+	return path
+}
+
+func toError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Message: err.Error()}
+}
+
+func resolveArguments(args map[string]value, variables map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(args))
+	for name, v := range args {
+		resolved, err := resolveValue(v, variables)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = resolved
+	}
+	return out, nil
+}
+
+func resolveValue(v value, variables map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case variableRef:
+		resolved, ok := variables[string(v)]
+		if !ok {
+			return nil, errorf("Variable \"$%s\" is not provided.", v)
+		}
+		return resolved, nil
+	case enumValue:
+		return string(v), nil
+	case []value:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := resolveValue(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case map[string]value:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			resolved, err := resolveValue(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// parse parses a GraphQL document made of a single anonymous or named operation.
+func parse(document string) (*operation, error) {
+	p := &parser{lexer: lexer{input: document}}
+	p.next()
+	op := &operation{kind: "query"}
+	if p.tok.kind == tokenName && (p.tok.text == "query" || p.tok.text == "mutation") {
+		op.kind = p.tok.text
+		p.next()
+		if p.tok.kind == tokenName {
+			p.next()
+		}
+		if p.tok.is("(") {
+			if err := p.skipVariableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %q after the operation", p.tok.text)
+	}
+	op.selections = selections
+	return op, nil
+}
+
+type parser struct {
+	lexer lexer
+	tok   token
+	err   error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		p.tok = token{kind: tokenEOF}
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("Syntax Error: "+format, args...)
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.tok.is(punct) {
+		return p.errorf("expected %q, found %q", punct, p.tok.text)
+	}
+	p.next()
+	return nil
+}
+
+// skipVariableDefinitions skips `($a:Int!$b:[String!]!)`, variable types are not checked.
+func (p *parser) skipVariableDefinitions() error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.tok.is(")") {
+		if p.tok.kind == tokenEOF {
+			return p.errorf("unterminated variable definitions")
+		}
+		p.next()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) selectionSet() ([]*field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*field
+	for !p.tok.is("}") {
+		if p.tok.is(",") {
+			p.next()
+			continue
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.next()
+	return fields, nil
+}
+
+func (p *parser) field() (*field, error) {
+	if p.tok.kind != tokenName {
+		return nil, p.errorf("expected a field name, found %q", p.tok.text)
+	}
+	f := &field{name: p.tok.text}
+	p.next()
+	if p.tok.is(":") {
+		p.next()
+		if p.tok.kind != tokenName {
+			return nil, p.errorf("expected a field name after alias %q", f.name)
+		}
+		f.alias, f.name = f.name, p.tok.text
+		p.next()
+	}
+	if p.tok.is("(") {
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		f.arguments = args
+	}
+	if p.tok.is("{") {
+		selections, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		f.selections = selections
+	}
+	return f, nil
+}
+
+func (p *parser) arguments() (map[string]value, error) {
+	p.next()
+	args := map[string]value{}
+	for !p.tok.is(")") {
+		if p.tok.is(",") {
+			p.next()
+			continue
+		}
+		if p.tok.kind != tokenName {
+			return nil, p.errorf("expected an argument name, found %q", p.tok.text)
+		}
+		name := p.tok.text
+		p.next()
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *parser) value() (value, error) {
+	tok := p.tok
+	switch {
+	case tok.is("$"):
+		p.next()
+		if p.tok.kind != tokenName {
+			return nil, p.errorf("expected a variable name")
+		}
+		name := p.tok.text
+		p.next()
+		return variableRef(name), nil
+	case tok.is("["):
+		p.next()
+		list := []value{}
+		for !p.tok.is("]") {
+			if p.tok.is(",") {
+				p.next()
+				continue
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.next()
+		return list, nil
+	case tok.is("{"):
+		p.next()
+		obj := map[string]value{}
+		for !p.tok.is("}") {
+			if p.tok.is(",") {
+				p.next()
+				continue
+			}
+			if p.tok.kind != tokenName {
+				return nil, p.errorf("expected an object field name")
+			}
+			name := p.tok.text
+			p.next()
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = v
+		}
+		p.next()
+		return obj, nil
+	case tok.kind == tokenString:
+		p.next()
+		return tok.text, nil
+	case tok.kind == tokenNumber:
+		p.next()
+		if i, err := strconv.Atoi(tok.text); err == nil {
+			return float64(i), nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok.text)
+		}
+		return f, nil
+	case tok.kind == tokenName:
+		p.next()
+		switch tok.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enumValue(tok.text), nil
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokenPunct && t.text == punct
+}
+
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			if c == ',' {
+				l.pos++
+				return token{kind: tokenPunct, text: ","}, nil
+			}
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.input) && l.input[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.IndexByte("{}()[]:$!=@", c) >= 0:
+			l.pos++
+			return token{kind: tokenPunct, text: string(c)}, nil
+		case c == '"':
+			return l.string()
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := l.pos
+			l.pos++
+			for l.pos < len(l.input) && strings.IndexByte("0123456789.eE+-", l.input[l.pos]) >= 0 {
+				l.pos++
+			}
+			return token{kind: tokenNumber, text: l.input[start:l.pos]}, nil
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := l.pos
+			for l.pos < len(l.input) && isNameChar(l.input[l.pos]) {
+				l.pos++
+			}
+			return token{kind: tokenName, text: l.input[start:l.pos]}, nil
+		default:
+			return token{}, fmt.Errorf("Syntax Error: unexpected character %q", c)
+		}
+	}
+	return token{kind: tokenEOF}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokenString, text: b.String()}, nil
+		case '\\':
+			if l.pos+1 >= len(l.input) {
+				return token{}, fmt.Errorf("Syntax Error: unterminated string")
+			}
+			l.pos++
+			switch e := l.input[l.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(e)
+			}
+			l.pos++
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("Syntax Error: unterminated string")
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,1084 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package testserver
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The resolvers follow the behaviour of the Wiki.js 2.5 resolvers, including their quirks: groups.list ignores
+// its arguments, users.create does not return the new user and users.search returns at most 10 users. They run
+// with Server.mu held.
+
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// wikiError is a Wiki.js error as reported either in a responseResult or as a GraphQL error.
+type wikiError struct {
+	code    int
+	name    string
+	message string
+}
+
+var (
+	errLoginFailed            = wikiError{1002, "AuthLoginFailed", "Invalid email / username or password."}
+	errAccountAlreadyExists   = wikiError{1004, "AuthAccountAlreadyExists", "An account already exists using this email address."}
+	errInputInvalid           = wikiError{1012, "InputInvalid", "Input data is invalid."}
+	errUserNotFound           = wikiError{1016, "UserNotFound", "This user does not exist."}
+	errUserDeleteForeignKey   = wikiError{1017, "UserDeleteForeignConstraint", "Cannot delete user because of content relational constraints."}
+	errUserDeleteProtected    = wikiError{1018, "UserDeleteProtected", "Cannot delete a protected system account."}
+	errAuthRequired           = wikiError{1019, "AuthRequired", "You must be authenticated to access this resource."}
+	errPageDuplicateCreate    = wikiError{6002, "PageDuplicateCreate", "Cannot create this page because an entry already exists at the same path."}
+	errPageNotFound           = wikiError{6003, "PageNotFound", "This page does not exist."}
+	errPageEmptyContent       = wikiError{6004, "PageEmptyContent", "Page content cannot be empty."}
+	errPageIllegalPath        = wikiError{6005, "PageIllegalPath", "Page path cannot contains illegal characters."}
+	errUnsafeRegexPageRule    = &Error{Message: "Some Page Rules contains unsafe or exponential time regex."}
+	errInvalidGroupID         = &Error{Message: "Invalid Group ID"}
+	errInvalidUserID          = &Error{Message: "Invalid User ID"}
+	errUserAlreadyAssigned    = &Error{Message: "User is already assigned to group."}
+	errCannotUnassignAdmin    = &Error{Message: "Cannot unassign Administrator user from Administrators group."}
+	errCannotDeleteGroup      = &Error{Message: "Cannot delete this group."}
+	pageRuleMatches           = []string{"START", "EXACT", "END", "REGEX", "TAG"}
+	navigationModes           = []string{"NONE", "TREE", "MIXED", "STATIC"}
+	nestedQuantifierPattern   = regexp.MustCompile(`\([^()]*[*+][^()]*\)[*+{]`)
+	pagePathIllegalCharacters = regexp.MustCompile(`\.\.|[\s#?%\\]`)
+)
+
+// graphQLError is the error Apollo reports when a resolver throws a Wiki.js error.
+func (e wikiError) graphQLError() *Error {
+	return &Error{Message: e.message, Extensions: map[string]interface{}{
+		"code":      "INTERNAL_SERVER_ERROR",
+		"exception": map[string]interface{}{"code": e.code, "name": e.name},
+	}}
+}
+
+func (e wikiError) response() object {
+	return object{"responseResult": object{
+		"succeeded": false,
+		"errorCode": e.code,
+		"slug":      e.name,
+		"message":   e.message,
+	}}
+}
+
+func success(message string) object {
+	return object{"responseResult": object{
+		"succeeded": true,
+		"errorCode": 0,
+		"slug":      "ok",
+		"message":   message,
+	}}
+}
+
+func (s *Server) queryRoot() object {
+	return object{
+		"site":         object{"config": object(copyMap(s.state.site))},
+		"system":       object{"info": s.systemInfo()},
+		"localization": s.localization(),
+		"groups": object{
+			"list":   resolverFunc(s.listGroups),
+			"single": resolverFunc(s.singleGroup),
+		},
+		"users": object{
+			"list":   resolverFunc(s.listUsers),
+			"search": resolverFunc(s.searchUsers),
+			"single": resolverFunc(s.singleUser),
+		},
+		"pages": object{
+			"list":         resolverFunc(s.listPages),
+			"single":       resolverFunc(s.singlePage),
+			"singleByPath": resolverFunc(s.singlePageByPath),
+		},
+		"navigation": object{
+			"tree":   s.navigationTree(),
+			"config": object{"mode": s.state.navigationMode},
+		},
+	}
+}
+
+func (s *Server) mutationRoot() object {
+	return object{
+		"authentication": object{"login": resolverFunc(s.login)},
+		"site":           object{"updateConfig": resolverFunc(s.updateSiteConfig)},
+		"groups": object{
+			"create":       resolverFunc(s.createGroup),
+			"update":       resolverFunc(s.updateGroup),
+			"delete":       resolverFunc(s.deleteGroup),
+			"assignUser":   resolverFunc(s.assignUser),
+			"unassignUser": resolverFunc(s.unassignUser),
+		},
+		"users": object{
+			"create":     resolverFunc(s.createUser),
+			"update":     resolverFunc(s.updateUser),
+			"delete":     resolverFunc(s.deleteUser),
+			"verify":     resolverFunc(s.patchUser(func(u *User) { u.IsVerified = true }, "User verified successfully")),
+			"activate":   resolverFunc(s.patchUser(func(u *User) { u.IsActive = true }, "User activated successfully")),
+			"deactivate": resolverFunc(s.patchUser(func(u *User) { u.IsActive = false }, "User deactivated successfully")),
+		},
+		"pages": object{
+			"create": resolverFunc(s.createPage),
+			"update": resolverFunc(s.updatePage),
+			"delete": resolverFunc(s.deletePage),
+		},
+		"navigation": object{
+			"updateTree":   resolverFunc(s.updateNavigationTree),
+			"updateConfig": resolverFunc(s.updateNavigationConfig),
+		},
+	}
+}
+
+func (s *Server) systemInfo() object {
+	return object{
+		"currentVersion":  s.state.version,
+		"latestVersion":   s.state.version,
+		"dbType":          "postgres",
+		"dbVersion":       "14.0",
+		"hostname":        "testserver",
+		"nodeVersion":     "16.0.0",
+		"operatingSystem": "Linux",
+		"platform":        "linux",
+		"groupsTotal":     len(s.state.groups),
+		"usersTotal":      len(s.state.users),
+		"pagesTotal":      len(s.state.pages),
+		"tagsTotal":       len(s.tags()),
+	}
+}
+
+func (s *Server) localization() object {
+	locales := make([]object, len(s.state.locales))
+	for i, l := range s.state.locales {
+		locales[i] = object{
+			"code":         l.Code,
+			"name":         l.Name,
+			"nativeName":   l.NativeName,
+			"isRTL":        l.IsRTL,
+			"isInstalled":  l.IsInstalled,
+			"availability": 100,
+			"createdAt":    formatTime(time.Time{}),
+			"updatedAt":    formatTime(time.Time{}),
+		}
+	}
+	namespaces := []string{}
+	for _, l := range s.state.locales {
+		if l.IsInstalled {
+			namespaces = append(namespaces, l.Code)
+		}
+	}
+	return object{
+		"locales": locales,
+		"config": object{
+			"locale":      "en",
+			"autoUpdate":  false,
+			"namespacing": len(namespaces) > 1,
+			"namespaces":  namespaces,
+		},
+	}
+}
+
+func (s *Server) tags() []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, p := range s.state.pages {
+		for _, tag := range p.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (s *Server) login(args map[string]interface{}) (interface{}, error) {
+	username, password := stringArg(args, "username"), stringArg(args, "password")
+	strategy := stringArg(args, "strategy")
+	for _, u := range s.state.sortedUsers() {
+		if strategy != u.ProviderKey || !strings.EqualFold(u.Email, username) {
+			continue
+		}
+		if u.Password == "" || u.Password != password || !u.IsActive {
+			break
+		}
+		now := s.now()
+		u.LastLoginAt = &now
+		s.issued++
+		token := fmt.Sprintf("jwt-%d-%d", u.ID, s.issued)
+		s.tokens[token] = u.ID
+		result := success("Login success")
+		result["jwt"] = token
+		result["mustChangePwd"] = false
+		result["mustProvideTFA"] = false
+		result["mustSetupTFA"] = false
+		return result, nil
+	}
+	result := errLoginFailed.response()
+	result["jwt"] = nil
+	result["mustChangePwd"] = false
+	result["mustProvideTFA"] = false
+	result["mustSetupTFA"] = false
+	return result, nil
+}
+
+func (s *Server) updateSiteConfig(args map[string]interface{}) (interface{}, error) {
+	for name := range args {
+		if _, ok := s.state.site[name]; !ok {
+			return nil, errorf("Unknown argument %q on field \"site.updateConfig\".", name)
+		}
+	}
+	for name, v := range args {
+		if v != nil {
+			s.state.site[name] = v
+		}
+	}
+	return success("Site config updated successfully"), nil
+}
+
+func groupMinimal(g *Group) object {
+	return object{
+		"id":        g.ID,
+		"name":      g.Name,
+		"isSystem":  g.IsSystem,
+		"userCount": len(g.UserIDs),
+		"createdAt": formatTime(g.CreatedAt),
+		"updatedAt": formatTime(g.UpdatedAt),
+	}
+}
+
+func groupObject(s *state, g *Group) object {
+	rules := make([]object, len(g.PageRules))
+	for i, r := range g.PageRules {
+		rules[i] = object{
+			"id":      r.ID,
+			"deny":    r.Deny,
+			"match":   r.Match,
+			"roles":   nonNilStrings(r.Roles),
+			"path":    r.Path,
+			"locales": nonNilStrings(r.Locales),
+		}
+	}
+	users := []object{}
+	for _, id := range g.UserIDs {
+		if u, ok := s.users[id]; ok {
+			users = append(users, userMinimal(u))
+		}
+	}
+	return object{
+		"id":              g.ID,
+		"name":            g.Name,
+		"isSystem":        g.IsSystem,
+		"redirectOnLogin": g.RedirectOnLogin,
+		"permissions":     nonNilStrings(g.Permissions),
+		"pageRules":       rules,
+		"users":           users,
+		"createdAt":       formatTime(g.CreatedAt),
+		"updatedAt":       formatTime(g.UpdatedAt),
+	}
+}
+
+func (s *Server) listGroups(map[string]interface{}) (interface{}, error) {
+	groups := []object{}
+	for _, g := range s.state.sortedGroups() {
+		groups = append(groups, groupMinimal(g))
+	}
+	return groups, nil
+}
+
+func (s *Server) singleGroup(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	g, ok := s.state.groups[id]
+	if !ok {
+		return nil, nil
+	}
+	return groupObject(s.state, g), nil
+}
+
+func (s *Server) createGroup(args map[string]interface{}) (interface{}, error) {
+	name, err := requiredStringArg(args, "name")
+	if err != nil {
+		return nil, err
+	}
+	g := s.state.addGroup(Group{Name: name, Permissions: defaultPermissions, PageRules: defaultPageRules()}, s.now())
+	result := success("Group created successfully.")
+	result["group"] = groupObject(s.state, g)
+	return result, nil
+}
+
+func (s *Server) updateGroup(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	name, err := requiredStringArg(args, "name")
+	if err != nil {
+		return nil, err
+	}
+	rules, err := pageRulesArg(args, "pageRules")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if r.Match == "REGEX" && !isSafeRegex(r.Path) {
+			return nil, errUnsafeRegexPageRule
+		}
+	}
+	redirectOnLogin := stringArg(args, "redirectOnLogin")
+	if redirectOnLogin == "" {
+		redirectOnLogin = "/"
+	}
+	// Like Wiki.js, updating a group that does not exist is not an error.
+	if g, ok := s.state.groups[id]; ok {
+		g.Name = name
+		g.RedirectOnLogin = redirectOnLogin
+		g.Permissions = stringListArg(args, "permissions")
+		g.PageRules = rules
+		g.UpdatedAt = s.now()
+	}
+	return success("Group has been updated."), nil
+}
+
+func (s *Server) deleteGroup(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	// Like Wiki.js, the Administrators and Guests groups cannot be deleted.
+	if id == 1 || id == 2 {
+		return nil, errCannotDeleteGroup
+	}
+	delete(s.state.groups, id)
+	return success("Group has been deleted."), nil
+}
+
+func (s *Server) membership(args map[string]interface{}) (*Group, *User, error) {
+	groupID, err := intArg(args, "groupId")
+	if err != nil {
+		return nil, nil, err
+	}
+	userID, err := intArg(args, "userId")
+	if err != nil {
+		return nil, nil, err
+	}
+	g, ok := s.state.groups[groupID]
+	if !ok {
+		return nil, nil, errInvalidGroupID
+	}
+	u, ok := s.state.users[userID]
+	if !ok {
+		return nil, nil, errInvalidUserID
+	}
+	return g, u, nil
+}
+
+func (s *Server) assignUser(args map[string]interface{}) (interface{}, error) {
+	g, u, err := s.membership(args)
+	if err != nil {
+		return nil, err
+	}
+	if containsInt(g.UserIDs, u.ID) {
+		return nil, errUserAlreadyAssigned
+	}
+	g.UserIDs = append(g.UserIDs, u.ID)
+	sort.Ints(g.UserIDs)
+	return success("User has been assigned to group."), nil
+}
+
+func (s *Server) unassignUser(args map[string]interface{}) (interface{}, error) {
+	g, u, err := s.membership(args)
+	if err != nil {
+		return nil, err
+	}
+	if g.ID == 1 && u.ID == 1 {
+		return nil, errCannotUnassignAdmin
+	}
+	g.UserIDs = removeInt(g.UserIDs, u.ID)
+	return success("User has been unassigned from group."), nil
+}
+
+func userMinimal(u *User) object {
+	return object{
+		"id":          u.ID,
+		"name":        u.Name,
+		"email":       u.Email,
+		"providerKey": u.ProviderKey,
+		"isSystem":    u.IsSystem,
+		"isActive":    u.IsActive,
+		"createdAt":   formatTime(u.CreatedAt),
+		"lastLoginAt": formatOptionalTime(u.LastLoginAt),
+	}
+}
+
+func userObject(s *state, u *User) object {
+	groups := []object{}
+	for _, g := range s.userGroups(u.ID) {
+		groups = append(groups, groupObject(s, g))
+	}
+	return object{
+		"id":                   u.ID,
+		"name":                 u.Name,
+		"email":                u.Email,
+		"providerKey":          u.ProviderKey,
+		"providerName":         "Local",
+		"providerId":           nil,
+		"providerIs2FACapable": true,
+		"isSystem":             u.IsSystem,
+		"isActive":             u.IsActive,
+		"isVerified":           u.IsVerified,
+		"location":             u.Location,
+		"jobTitle":             u.JobTitle,
+		"timezone":             u.Timezone,
+		"dateFormat":           u.DateFormat,
+		"appearance":           u.Appearance,
+		"createdAt":            formatTime(u.CreatedAt),
+		"updatedAt":            formatTime(u.UpdatedAt),
+		"lastLoginAt":          formatOptionalTime(u.LastLoginAt),
+		"tfaIsActive":          false,
+		"groups":               groups,
+	}
+}
+
+func (s *Server) listUsers(map[string]interface{}) (interface{}, error) {
+	users := []object{}
+	for _, u := range s.state.sortedUsers() {
+		users = append(users, userMinimal(u))
+	}
+	return users, nil
+}
+
+func (s *Server) searchUsers(args map[string]interface{}) (interface{}, error) {
+	q, err := requiredStringArg(args, "query")
+	if err != nil {
+		return nil, err
+	}
+	q = strings.ToLower(q)
+	users := []object{}
+	for _, u := range s.state.sortedUsers() {
+		if len(users) == 10 {
+			break
+		}
+		if strings.Contains(strings.ToLower(u.Email), q) || strings.Contains(strings.ToLower(u.Name), q) {
+			users = append(users, userMinimal(u))
+		}
+	}
+	return users, nil
+}
+
+func (s *Server) singleUser(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	u, ok := s.state.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return userObject(s.state, u), nil
+}
+
+func (s *Server) emailTaken(email string, exceptID int) bool {
+	for _, u := range s.state.users {
+		if u.ID != exceptID && strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// setUserGroups replaces the groups of u, ignoring unknown groups like Wiki.js does.
+func (s *Server) setUserGroups(u *User, groupIDs []int) {
+	for _, g := range s.state.groups {
+		if containsInt(groupIDs, g.ID) {
+			if !containsInt(g.UserIDs, u.ID) {
+				g.UserIDs = append(g.UserIDs, u.ID)
+				sort.Ints(g.UserIDs)
+			}
+		} else {
+			g.UserIDs = removeInt(g.UserIDs, u.ID)
+		}
+	}
+}
+
+func (s *Server) createUser(args map[string]interface{}) (interface{}, error) {
+	email := strings.ToLower(strings.TrimSpace(stringArg(args, "email")))
+	name := strings.TrimSpace(stringArg(args, "name"))
+	providerKey := stringArg(args, "providerKey")
+	password := stringArg(args, "passwordRaw")
+	if !strings.Contains(email, "@") || name == "" || providerKey == "" {
+		return errInputInvalid.response(), nil
+	}
+	if providerKey == "local" && len(password) < 6 {
+		return errInputInvalid.response(), nil
+	}
+	if s.emailTaken(email, 0) {
+		return errAccountAlreadyExists.response(), nil
+	}
+	u := s.state.addUser(User{
+		Email:       email,
+		Name:        name,
+		Password:    password,
+		ProviderKey: providerKey,
+		IsActive:    true,
+		IsVerified:  true,
+	}, s.now())
+	s.setUserGroups(u, intListArg(args, "groups"))
+	result := success("User created successfully")
+	result["user"] = nil
+	return result, nil
+}
+
+func (s *Server) updateUser(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	u, ok := s.state.users[id]
+	if !ok {
+		return errUserNotFound.response(), nil
+	}
+	if email, ok := optionalStringArg(args, "email"); ok {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if !strings.Contains(email, "@") {
+			return errInputInvalid.response(), nil
+		}
+		if s.emailTaken(email, u.ID) {
+			return errAccountAlreadyExists.response(), nil
+		}
+		u.Email = email
+	}
+	if password, ok := optionalStringArg(args, "newPassword"); ok && password != "" {
+		if len(password) < 6 {
+			return errInputInvalid.response(), nil
+		}
+		u.Password = password
+	}
+	if name, ok := optionalStringArg(args, "name"); ok && strings.TrimSpace(name) != "" {
+		u.Name = strings.TrimSpace(name)
+	}
+	for arg, field := range map[string]*string{
+		"location":   &u.Location,
+		"jobTitle":   &u.JobTitle,
+		"timezone":   &u.Timezone,
+		"dateFormat": &u.DateFormat,
+		"appearance": &u.Appearance,
+	} {
+		if v, ok := optionalStringArg(args, arg); ok {
+			*field = v
+		}
+	}
+	if v, ok := args["groups"]; ok && v != nil {
+		s.setUserGroups(u, intListArg(args, "groups"))
+	}
+	u.UpdatedAt = s.now()
+	return success("User updated successfully"), nil
+}
+
+func (s *Server) deleteUser(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	if id <= 2 {
+		return errUserDeleteProtected.response(), nil
+	}
+	u, ok := s.state.users[id]
+	if !ok {
+		return errUserNotFound.response(), nil
+	}
+	replaceID, hasReplacement := 0, false
+	if v, ok := args["replaceId"]; ok && v != nil {
+		replaceID, err = intArg(args, "replaceId")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := s.state.users[replaceID]; !ok {
+			return errUserNotFound.response(), nil
+		}
+		hasReplacement = true
+	}
+	for _, p := range s.state.pages {
+		if p.AuthorID != u.ID && p.CreatorID != u.ID {
+			continue
+		}
+		if !hasReplacement {
+			return errUserDeleteForeignKey.response(), nil
+		}
+	}
+	for _, p := range s.state.pages {
+		if p.AuthorID == u.ID {
+			p.AuthorID = replaceID
+		}
+		if p.CreatorID == u.ID {
+			p.CreatorID = replaceID
+		}
+	}
+	s.setUserGroups(u, nil)
+	delete(s.state.users, id)
+	return success("User deleted successfully"), nil
+}
+
+// patchUser returns a resolver applying patch to a user. Like Wiki.js, patching an unknown user succeeds.
+func (s *Server) patchUser(patch func(*User), message string) resolverFunc {
+	return func(args map[string]interface{}) (interface{}, error) {
+		id, err := intArg(args, "id")
+		if err != nil {
+			return nil, err
+		}
+		if u, ok := s.state.users[id]; ok {
+			patch(u)
+			u.UpdatedAt = s.now()
+		}
+		return success(message), nil
+	}
+}
+
+func pageObject(s *state, p *Page) object {
+	tags := make([]object, len(p.Tags))
+	for i, tag := range p.Tags {
+		tags[i] = object{"id": i + 1, "tag": tag, "title": tag}
+	}
+	author, creator := s.users[p.AuthorID], s.users[p.CreatorID]
+	return object{
+		"id":               p.ID,
+		"path":             p.Path,
+		"hash":             fmt.Sprintf("%x", p.Locale+"/"+p.Path),
+		"title":            p.Title,
+		"description":      p.Description,
+		"isPrivate":        p.IsPrivate,
+		"isPublished":      p.IsPublished,
+		"privateNS":        nil,
+		"publishStartDate": "",
+		"publishEndDate":   "",
+		"tags":             tags,
+		"content":          p.Content,
+		"render":           p.Content,
+		"toc":              "[]",
+		"contentType":      "markdown",
+		"createdAt":        formatTime(p.CreatedAt),
+		"updatedAt":        formatTime(p.UpdatedAt),
+		"editor":           p.Editor,
+		"locale":           p.Locale,
+		"scriptCss":        p.ScriptCSS,
+		"scriptJs":         p.ScriptJS,
+		"authorId":         p.AuthorID,
+		"authorName":       userName(author),
+		"authorEmail":      userEmail(author),
+		"creatorId":        p.CreatorID,
+		"creatorName":      userName(creator),
+		"creatorEmail":     userEmail(creator),
+	}
+}
+
+func pageListItem(p *Page) object {
+	return object{
+		"id":          p.ID,
+		"path":        p.Path,
+		"locale":      p.Locale,
+		"title":       p.Title,
+		"description": p.Description,
+		"contentType": "markdown",
+		"isPublished": p.IsPublished,
+		"isPrivate":   p.IsPrivate,
+		"privateNS":   nil,
+		"createdAt":   formatTime(p.CreatedAt),
+		"updatedAt":   formatTime(p.UpdatedAt),
+		"tags":        nonNilStrings(p.Tags),
+	}
+}
+
+func (s *Server) listPages(args map[string]interface{}) (interface{}, error) {
+	locale := stringArg(args, "locale")
+	tags := stringListArg(args, "tags")
+	creatorID, hasCreator := args["creatorId"].(float64)
+	authorID, hasAuthor := args["authorId"].(float64)
+	limit, hasLimit := args["limit"].(float64)
+	pages := []object{}
+	for _, p := range s.state.sortedPages() {
+		if hasLimit && len(pages) >= int(limit) {
+			break
+		}
+		if (locale != "" && p.Locale != locale) || (hasCreator && p.CreatorID != int(creatorID)) || (hasAuthor && p.AuthorID != int(authorID)) {
+			continue
+		}
+		if !containsAll(p.Tags, tags) {
+			continue
+		}
+		pages = append(pages, pageListItem(p))
+	}
+	return pages, nil
+}
+
+func (s *Server) singlePage(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	p, ok := s.state.pages[id]
+	if !ok {
+		return nil, errPageNotFound.graphQLError()
+	}
+	return pageObject(s.state, p), nil
+}
+
+func (s *Server) singlePageByPath(args map[string]interface{}) (interface{}, error) {
+	path, err := requiredStringArg(args, "path")
+	if err != nil {
+		return nil, err
+	}
+	locale, err := requiredStringArg(args, "locale")
+	if err != nil {
+		return nil, err
+	}
+	if p := s.pageAt(normalizePagePath(path), locale); p != nil {
+		return pageObject(s.state, p), nil
+	}
+	return nil, errPageNotFound.graphQLError()
+}
+
+func (s *Server) pageAt(path, locale string) *Page {
+	for _, p := range s.state.pages {
+		if p.Path == path && p.Locale == locale {
+			return p
+		}
+	}
+	return nil
+}
+
+func normalizePagePath(path string) string {
+	return strings.Trim(strings.TrimSpace(path), "/")
+}
+
+func (s *Server) createPage(args map[string]interface{}) (interface{}, error) {
+	path := normalizePagePath(stringArg(args, "path"))
+	locale := stringArg(args, "locale")
+	if locale == "" {
+		locale = "en"
+	}
+	if path == "" || pagePathIllegalCharacters.MatchString(path) {
+		return withPage(errPageIllegalPath.response()), nil
+	}
+	if strings.TrimSpace(stringArg(args, "content")) == "" {
+		return withPage(errPageEmptyContent.response()), nil
+	}
+	if s.pageAt(path, locale) != nil {
+		return withPage(errPageDuplicateCreate.response()), nil
+	}
+	isPublished, _ := args["isPublished"].(bool)
+	isPrivate, _ := args["isPrivate"].(bool)
+	p := s.state.addPage(Page{
+		Path:        path,
+		Locale:      locale,
+		Title:       stringArg(args, "title"),
+		Description: stringArg(args, "description"),
+		Content:     stringArg(args, "content"),
+		Editor:      stringArg(args, "editor"),
+		IsPublished: isPublished,
+		IsPrivate:   isPrivate,
+		Tags:        stringListArg(args, "tags"),
+		ScriptCSS:   stringArg(args, "scriptCss"),
+		ScriptJS:    stringArg(args, "scriptJs"),
+		AuthorID:    1,
+		CreatorID:   1,
+	}, s.now())
+	result := success("Page created successfully.")
+	result["page"] = pageObject(s.state, p)
+	return result, nil
+}
+
+func (s *Server) updatePage(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	p, ok := s.state.pages[id]
+	if !ok {
+		return withPage(errPageNotFound.response()), nil
+	}
+	updated := copyPage(*p)
+	if path, ok := optionalStringArg(args, "path"); ok {
+		updated.Path = normalizePagePath(path)
+	}
+	if locale, ok := optionalStringArg(args, "locale"); ok && locale != "" {
+		updated.Locale = locale
+	}
+	if updated.Path == "" || pagePathIllegalCharacters.MatchString(updated.Path) {
+		return withPage(errPageIllegalPath.response()), nil
+	}
+	if other := s.pageAt(updated.Path, updated.Locale); other != nil && other.ID != p.ID {
+		return withPage(errPageDuplicateCreate.response()), nil
+	}
+	if content, ok := optionalStringArg(args, "content"); ok {
+		if strings.TrimSpace(content) == "" {
+			return withPage(errPageEmptyContent.response()), nil
+		}
+		updated.Content = content
+	}
+	for arg, field := range map[string]*string{
+		"title":       &updated.Title,
+		"description": &updated.Description,
+		"editor":      &updated.Editor,
+		"scriptCss":   &updated.ScriptCSS,
+		"scriptJs":    &updated.ScriptJS,
+	} {
+		if v, ok := optionalStringArg(args, arg); ok {
+			*field = v
+		}
+	}
+	if v, ok := args["isPublished"].(bool); ok {
+		updated.IsPublished = v
+	}
+	if v, ok := args["isPrivate"].(bool); ok {
+		updated.IsPrivate = v
+	}
+	if v, ok := args["tags"]; ok && v != nil {
+		updated.Tags = stringListArg(args, "tags")
+	}
+	updated.UpdatedAt = s.now()
+	*p = updated
+	result := success("Page has been updated.")
+	result["page"] = pageObject(s.state, p)
+	return result, nil
+}
+
+func withPage(result object) object {
+	result["page"] = nil
+	return result
+}
+
+func (s *Server) deletePage(args map[string]interface{}) (interface{}, error) {
+	id, err := intArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.state.pages[id]; !ok {
+		return errPageNotFound.response(), nil
+	}
+	delete(s.state.pages, id)
+	return success("Page has been deleted."), nil
+}
+
+func (s *Server) navigationTree() []object {
+	trees := make([]object, len(s.state.navigation))
+	for i, tree := range s.state.navigation {
+		items := make([]object, len(tree.Items))
+		for j, item := range tree.Items {
+			groups := item.VisibilityGroups
+			if groups == nil {
+				groups = []int{}
+			}
+			items[j] = object{
+				"id":               item.ID,
+				"kind":             item.Kind,
+				"label":            item.Label,
+				"icon":             item.Icon,
+				"targetType":       item.TargetType,
+				"target":           item.Target,
+				"visibilityMode":   item.VisibilityMode,
+				"visibilityGroups": groups,
+			}
+		}
+		trees[i] = object{"locale": tree.Locale, "items": items}
+	}
+	return trees
+}
+
+func (s *Server) updateNavigationTree(args map[string]interface{}) (interface{}, error) {
+	raw, ok := args["tree"].([]interface{})
+	if !ok {
+		return nil, errorf("Argument \"tree\" of type \"[NavigationTreeInput]!\" is required.")
+	}
+	trees := make([]NavigationTree, 0, len(raw))
+	for _, t := range raw {
+		tree, _ := t.(map[string]interface{})
+		rawItems, _ := tree["items"].([]interface{})
+		items := make([]NavigationItem, 0, len(rawItems))
+		for _, i := range rawItems {
+			item, _ := i.(map[string]interface{})
+			items = append(items, NavigationItem{
+				ID:               stringArg(item, "id"),
+				Kind:             stringArg(item, "kind"),
+				Label:            stringArg(item, "label"),
+				Icon:             stringArg(item, "icon"),
+				TargetType:       stringArg(item, "targetType"),
+				Target:           stringArg(item, "target"),
+				VisibilityMode:   stringArg(item, "visibilityMode"),
+				VisibilityGroups: intListArg(item, "visibilityGroups"),
+			})
+		}
+		trees = append(trees, NavigationTree{Locale: stringArg(tree, "locale"), Items: items})
+	}
+	s.state.navigation = trees
+	return success("Navigation updated successfully"), nil
+}
+
+func (s *Server) updateNavigationConfig(args map[string]interface{}) (interface{}, error) {
+	mode := stringArg(args, "mode")
+	if !containsString(navigationModes, mode) {
+		return nil, badUserInput("mode", fmt.Sprintf("Value %q does not exist in \"NavigationMode\" enum.", mode))
+	}
+	s.state.navigationMode = mode
+	return success("Navigation config updated successfully"), nil
+}
+
+// isSafeRegex approximates the safe-regex check Wiki.js applies to REGEX page rules: the pattern must compile and
+// must not nest quantifiers.
+func isSafeRegex(pattern string) bool {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return false
+	}
+	return !nestedQuantifierPattern.MatchString(pattern)
+}
+
+func pageRulesArg(args map[string]interface{}, name string) ([]PageRule, error) {
+	raw, ok := args[name].([]interface{})
+	if !ok {
+		return nil, badUserInput(name, "Expected non-nullable type \"[PageRuleInput]!\" not to be null.")
+	}
+	rules := make([]PageRule, 0, len(raw))
+	for i, r := range raw {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, badUserInput(name, fmt.Sprintf("Expected type \"PageRuleInput\" at \"%s[%d]\".", name, i))
+		}
+		match := stringArg(rule, "match")
+		if !containsString(pageRuleMatches, match) {
+			return nil, badUserInput(name, fmt.Sprintf("Value %q does not exist in \"PageRuleMatch\" enum at \"%s[%d].match\".", match, name, i))
+		}
+		deny, _ := rule["deny"].(bool)
+		rules = append(rules, PageRule{
+			ID:      stringArg(rule, "id"),
+			Deny:    deny,
+			Match:   match,
+			Roles:   stringListArg(rule, "roles"),
+			Path:    stringArg(rule, "path"),
+			Locales: stringListArg(rule, "locales"),
+		})
+	}
+	return rules, nil
+}
+
+func badUserInput(variable, message string) *Error {
+	return &Error{
+		Message:    fmt.Sprintf("Variable \"$%s\" got invalid value; %s", variable, message),
+		Extensions: map[string]interface{}{"code": "BAD_USER_INPUT"},
+	}
+}
+
+func intArg(args map[string]interface{}, name string) (int, error) {
+	switch v := args[name].(type) {
+	case float64:
+		if v != float64(int(v)) {
+			return 0, badUserInput(name, fmt.Sprintf("Int cannot represent non-integer value: %v", v))
+		}
+		return int(v), nil
+	case nil:
+		return 0, badUserInput(name, fmt.Sprintf("Argument %q of type \"Int!\" is required.", name))
+	default:
+		return 0, badUserInput(name, fmt.Sprintf("Int cannot represent non-integer value: %v", v))
+	}
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	v, _ := args[name].(string)
+	return v
+}
+
+func optionalStringArg(args map[string]interface{}, name string) (string, bool) {
+	v, ok := args[name].(string)
+	return v, ok
+}
+
+func requiredStringArg(args map[string]interface{}, name string) (string, error) {
+	v, ok := args[name].(string)
+	if !ok {
+		return "", badUserInput(name, fmt.Sprintf("Argument %q of type \"String!\" is required.", name))
+	}
+	return v, nil
+}
+
+func stringListArg(args map[string]interface{}, name string) []string {
+	raw, _ := args[name].([]interface{})
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func intListArg(args map[string]interface{}, name string) []int {
+	raw, _ := args[name].([]interface{})
+	out := make([]int, 0, len(raw))
+	for _, v := range raw {
+		if f, ok := v.(float64); ok {
+			out = append(out, int(f))
+		}
+	}
+	return out
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(values, wanted []string) bool {
+	for _, w := range wanted {
+		if !containsString(values, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func formatOptionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func userName(u *User) string {
+	if u == nil {
+		return ""
+	}
+	return u.Name
+}
+
+func userEmail(u *User) string {
+	if u == nil {
+		return ""
+	}
+	return u.Email
+}
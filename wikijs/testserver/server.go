@@ -0,0 +1,483 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+// Package testserver provides an in-memory implementation of the subset of the Wiki.js 2.x GraphQL API used by
+// the provider, so that its tests run without a Wiki.js installation or network access.
+//
+// A Server starts with the data of a fresh installation: the Administrators (1) and Guests (2) system groups,
+// the administrator (1) and guest (2) users and the English locale. Requests authenticate with Token or with a
+// JWT issued by authentication.login. Faults such as latency, HTTP errors and GraphQL errors can be injected
+// per operation with InjectFault.
+package testserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// Token is the API key accepted by every Server, it authenticates as the administrator.
+	Token = "testserver-api-key"
+	// AdminEmail and AdminPassword are the credentials of the administrator on the local strategy.
+	AdminEmail    = "admin@example.com"
+	AdminPassword = "administrator"
+)
+
+// Server is a fake Wiki.js listening on a local httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	state    *state
+	tokens   map[string]int
+	issued   int
+	expired  map[string]bool
+	faults   []*Fault
+	requests []Request
+	now      func() time.Time
+}
+
+// Request is a GraphQL request received by the Server.
+type Request struct {
+	// Operations are the operations of the request, as dotted root field paths such as "groups.update".
+	Operations    []string
+	Query         string
+	Variables     map[string]interface{}
+	Authorization string
+}
+
+// Fault describes a failure injected into the requests containing an operation.
+type Fault struct {
+	// Operation selects the requests the fault applies to, by the dotted path of an operation such as
+	// "groups.update", or by its namespace such as "groups". An empty Operation matches every request.
+	Operation string
+	// Count limits the fault to the first Count matching requests. Zero applies it until ClearFaults.
+	Count int
+	// Latency delays the response to matching requests.
+	Latency time.Duration
+	// Status answers matching requests with this HTTP status and an empty body.
+	Status int
+	// Header is added to the response, for instance a Retry-After header alongside Status 429.
+	Header http.Header
+	// Error fails the matching operations with this GraphQL error message while the other operations of the
+	// request succeed. "Forbidden" is the message Wiki.js returns when the caller lacks a permission.
+	Error string
+	// AfterApply executes the request before failing it, mimicking a mutation that succeeded on the server but
+	// whose response was lost.
+	AfterApply bool
+	// Before runs before the matching requests are executed, e.g. to delete an object between the requests of an
+	// operation. It may call the methods of the Server.
+	Before func()
+}
+
+// Forbidden returns a fault rejecting operation the way Wiki.js rejects callers lacking a permission.
+func Forbidden(operation string) Fault {
+	return Fault{Operation: operation, Error: "Forbidden"}
+}
+
+func (f *Fault) matches(operation string) bool {
+	return f.Operation == "" || f.Operation == operation || strings.HasPrefix(operation, f.Operation+".")
+}
+
+// New starts a Server, which is closed when the test ends.
+func New(t testing.TB) *Server {
+	s := &Server{
+		tokens:  map[string]int{Token: 1},
+		expired: map[string]bool{},
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.state = newState(s.URL, s.now())
+	t.Cleanup(s.Close)
+	return s
+}
+
+// InjectFault adds a fault, faults apply in the order they were injected.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestCount returns how many received requests contain operation, matched as in Fault.Operation.
+func (s *Server) RequestCount(operation string) int {
+	matcher := Fault{Operation: operation}
+	count := 0
+	for _, r := range s.Requests() {
+		for _, op := range r.Operations {
+			if matcher.matches(op) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphqlResponse struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	var in graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, graphqlResponse{Errors: []*Error{errorf("POST body sent invalid JSON: %s", err)}})
+		return
+	}
+	op, err := parse(in.Query)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, graphqlResponse{Errors: []*Error{{Message: err.Error()}}})
+		return
+	}
+	operations := operationPaths(op)
+	faults := s.record(Request{
+		Operations:    operations,
+		Query:         in.Query,
+		Variables:     in.Variables,
+		Authorization: r.Header.Get("Authorization"),
+	})
+
+	for _, f := range faults {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+	for _, f := range faults {
+		if f.Before != nil {
+			f.Before()
+		}
+	}
+	for _, f := range faults {
+		if f.Status != 0 && !f.AfterApply {
+			writeStatus(w, f)
+			return
+		}
+	}
+
+	data, errs := s.execute(op, in.Variables, r.Header.Get("Authorization"), faults)
+
+	for _, f := range faults {
+		if f.Status != 0 {
+			writeStatus(w, f)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, graphqlResponse{Data: data, Errors: errs})
+}
+
+// record logs the request and returns the faults applying to it, consuming one use of each.
+func (s *Server) record(r Request) []Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+
+	var faults []Fault
+	remaining := s.faults[:0]
+	for _, f := range s.faults {
+		matched := false
+		for _, op := range r.Operations {
+			if f.matches(op) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			faults = append(faults, *f)
+			if f.Count > 0 {
+				f.Count--
+				if f.Count == 0 {
+					continue
+				}
+			}
+		}
+		remaining = append(remaining, f)
+	}
+	s.faults = remaining
+	return faults
+}
+
+func (s *Server) execute(op *operation, variables map[string]interface{}, authorization string, faults []Fault) (map[string]interface{}, []*Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	userID, authenticated := s.tokens[token]
+	var root object
+	if op.kind == "mutation" {
+		root = s.mutationRoot()
+	} else {
+		root = s.queryRoot()
+	}
+	root = intercept(root, func(path string, resolve func() (interface{}, error)) (interface{}, error) {
+		if s.expired[token] && path != "authentication.login" {
+			return nil, errAuthRequired.graphQLError()
+		}
+		if !authenticated && path != "authentication.login" {
+			return nil, &Error{Message: "Forbidden"}
+		}
+		if user, ok := s.state.users[userID]; authenticated && (!ok || !user.IsActive) {
+			return nil, &Error{Message: "Forbidden"}
+		}
+		for _, f := range faults {
+			if f.Error == "" || !f.matches(path) {
+				continue
+			}
+			if f.AfterApply {
+				if _, err := resolve(); err != nil {
+					return nil, err
+				}
+			}
+			return nil, &Error{Message: f.Error}
+		}
+		return resolve()
+	})
+	return execute(root, op.selections, variables)
+}
+
+// intercept wraps the fields of the namespaces of root, such as groups.update, with hook.
+func intercept(root object, hook func(path string, resolve func() (interface{}, error)) (interface{}, error)) object {
+	wrapped := make(object, len(root))
+	for namespace, value := range root {
+		fields, ok := value.(object)
+		if !ok {
+			wrapped[namespace] = value
+			continue
+		}
+		wrappedFields := make(object, len(fields))
+		for name, field := range fields {
+			path, field := namespace+"."+name, field
+			wrappedFields[name] = resolverFunc(func(args map[string]interface{}) (interface{}, error) {
+				return hook(path, func() (interface{}, error) {
+					if resolve, ok := field.(resolverFunc); ok {
+						return resolve(args)
+					}
+					if len(args) > 0 {
+						return nil, errorf("Unknown arguments on field %q.", path)
+					}
+					return field, nil
+				})
+			})
+		}
+		wrapped[namespace] = wrappedFields
+	}
+	return wrapped
+}
+
+// operationPaths returns the operations of op, made of each root field and the fields selected under it.
+func operationPaths(op *operation) []string {
+	var paths []string
+	for _, root := range op.selections {
+		if len(root.selections) == 0 {
+			paths = append(paths, root.name)
+			continue
+		}
+		for _, f := range root.selections {
+			paths = append(paths, root.name+"."+f.name)
+		}
+	}
+	return paths
+}
+
+func writeStatus(w http.ResponseWriter, f Fault) {
+	for name, values := range f.Header {
+		for _, v := range values {
+			w.Header().Add(name, v)
+		}
+	}
+	w.WriteHeader(f.Status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// ExpireSessions invalidates every JWT issued by authentication.login, the way Wiki.js rejects a session past its
+// renewal period: with an AuthRequired error. Token remains valid.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token := range s.tokens {
+		if token != Token {
+			delete(s.tokens, token)
+			s.expired[token] = true
+		}
+	}
+}
+
+// SetVersion changes the Wiki.js version reported by system.info.
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.version = version
+}
+
+// SetLocales replaces the locales known to the server.
+func (s *Server) SetLocales(locales []Locale) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.locales = append([]Locale(nil), locales...)
+}
+
+// SiteConfig returns the value of a site configuration field, such as "title".
+func (s *Server) SiteConfig(name string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.site[name]
+}
+
+// AddGroup stores g with the next group id and returns it.
+func (s *Server) AddGroup(g Group) Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyGroup(*s.state.addGroup(g, s.now()))
+}
+
+// Group returns the group with the given id.
+func (s *Server) Group(id int) (Group, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.state.groups[id]
+	if !ok {
+		return Group{}, false
+	}
+	return copyGroup(*g), true
+}
+
+// Groups returns every group, ordered by id.
+func (s *Server) Groups() []Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var groups []Group
+	for _, g := range s.state.sortedGroups() {
+		groups = append(groups, copyGroup(*g))
+	}
+	return groups
+}
+
+// UpdateGroup applies update to the group with the given id, mimicking a change made outside of Terraform.
+func (s *Server) UpdateGroup(id int, update func(*Group)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.state.groups[id]
+	if !ok {
+		return false
+	}
+	updated := copyGroup(*g)
+	update(&updated)
+	updated.ID = id
+	updated.UpdatedAt = s.now()
+	*g = updated
+	return true
+}
+
+// DeleteGroup removes the group with the given id.
+func (s *Server) DeleteGroup(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state.groups, id)
+}
+
+// AddUser stores u with the next user id and returns it. The user can log in when it has a Password.
+func (s *Server) AddUser(u User) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.state.addUser(u, s.now())
+}
+
+// User returns the user with the given id.
+func (s *Server) User(id int) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.state.users[id]
+	if !ok {
+		return User{}, false
+	}
+	return *u, true
+}
+
+// DeleteUser removes the user with the given id and its group memberships.
+func (s *Server) DeleteUser(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.state.groups {
+		g.UserIDs = removeInt(g.UserIDs, id)
+	}
+	delete(s.state.users, id)
+}
+
+// AssignUser adds the user to the group, it reports false if either does not exist.
+func (s *Server) AssignUser(groupID, userID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.state.groups[groupID]
+	if _, exists := s.state.users[userID]; !ok || !exists {
+		return false
+	}
+	if !containsInt(g.UserIDs, userID) {
+		g.UserIDs = append(g.UserIDs, userID)
+		sort.Ints(g.UserIDs)
+	}
+	return true
+}
+
+// AddPage stores p with the next page id and returns it.
+func (s *Server) AddPage(p Page) Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyPage(*s.state.addPage(p, s.now()))
+}
+
+// Page returns the page with the given id.
+func (s *Server) Page(id int) (Page, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.state.pages[id]
+	if !ok {
+		return Page{}, false
+	}
+	return copyPage(*p), true
+}
+
+// Navigation returns the navigation menus and the navigation mode.
+func (s *Server) Navigation() ([]NavigationTree, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyNavigation(s.state.navigation), s.state.navigationMode
+}
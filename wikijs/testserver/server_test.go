@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/schema"
+	gqlc "github.com/hasura/go-graphql-client"
+	"golang.org/x/oauth2"
+)
+
+func client(s *Server, token string) *gqlc.Client {
+	httpClient := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	return gqlc.NewClient(s.URL+"/graphql", httpClient)
+}
+
+// post sends a raw GraphQL document and returns the decoded response.
+func post(t *testing.T, s *Server, document string, variables map[string]interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"query": document, "variables": variables})
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/graphql", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestParse(t *testing.T) {
+	op, err := parse(`mutation ($id:Int!$rules:[PageRuleInput!]!){groups{update(id: $id, pageRules: $rules, name: "a \"b\"", n: [1, 2.5, true, null, START], o: {k: $id}){responseResult{succeeded}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.kind != "mutation" || len(op.selections) != 1 {
+		t.Fatalf("unexpected operation %#v", op)
+	}
+	update := op.selections[0].selections[0]
+	if update.name != "update" || len(update.arguments) != 5 || update.selections[0].name != "responseResult" {
+		t.Fatalf("unexpected field %#v", update)
+	}
+	args, err := resolveArguments(update.arguments, map[string]interface{}{"id": float64(3), "rules": []interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["name"] != `a "b"` || args["o"].(map[string]interface{})["k"] != float64(3) || args["n"].([]interface{})[4] != "START" {
+		t.Fatalf("unexpected arguments %#v", args)
+	}
+
+	for _, document := range []string{`{groups{list}`, `{groups(id: ){list}}`, `mutation {a b c`, `{a} b`, `{"x"}`} {
+		if _, err := parse(document); err == nil {
+			t.Errorf("expected %q not to parse", document)
+		}
+	}
+}
+
+func TestUnknownField(t *testing.T) {
+	s := New(t)
+	_, out := post(t, s, `{groups{list{id colour}}}`, nil)
+	errs, _ := out["errors"].([]interface{})
+	if len(errs) != 1 || !strings.Contains(errs[0].(map[string]interface{})["message"].(string), `"colour"`) {
+		t.Fatalf("expected an error for the unknown field, got %v", out)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := New(t)
+	c := client(s, Token)
+	ctx := context.Background()
+
+	var created schema.CreateGroupData
+	if err := c.Mutate(ctx, &created, map[string]interface{}{"name": gqlc.String("editors")}); err != nil {
+		t.Fatal(err)
+	}
+	id := created.Groups.Create.Group.Id
+	if !created.Groups.Create.ResponseResult.Succeeded || id != 3 {
+		t.Fatalf("unexpected response %+v", created.Groups.Create)
+	}
+
+	var updated schema.UpdateGroupData
+	if err := c.Mutate(ctx, &updated, map[string]interface{}{
+		"id":              id,
+		"name":            gqlc.String("writers"),
+		"redirectOnLogin": gqlc.String(""),
+		"permissions":     []gqlc.String{"read:pages", "write:pages"},
+		"pageRules": []schema.PageRuleInput{{
+			Id: "r1", Match: "START", Path: "docs", Roles: []gqlc.String{"write:pages"}, Locales: []gqlc.String{},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var read schema.QueryGroupData
+	if err := c.Query(ctx, &read, map[string]interface{}{"id": id}); err != nil {
+		t.Fatal(err)
+	}
+	g := read.Groups.Single
+	if g.Name != "writers" || g.RedirectOnLogin != "/" || len(g.Permissions) != 2 || len(g.PageRules) != 1 || g.PageRules[0].Path != "docs" {
+		t.Fatalf("unexpected group %+v", g)
+	}
+
+	if err := c.Mutate(ctx, &updated, map[string]interface{}{
+		"id":              id,
+		"name":            gqlc.String("writers"),
+		"redirectOnLogin": gqlc.String("/"),
+		"permissions":     []gqlc.String{},
+		"pageRules":       []schema.PageRuleInput{{Id: "r1", Match: "SOMETIMES", Roles: []gqlc.String{}, Locales: []gqlc.String{}}},
+	}); err == nil || !strings.Contains(err.Error(), "PageRuleMatch") {
+		t.Fatalf("expected an invalid match to be rejected, got %v", err)
+	}
+
+	var deleted schema.DeleteGroupData
+	if err := c.Mutate(ctx, &deleted, map[string]interface{}{"id": id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Group(int(id)); ok {
+		t.Fatal("expected the group to be deleted")
+	}
+	read = schema.QueryGroupData{}
+	if err := c.Query(ctx, &read, map[string]interface{}{"id": id}); err != nil || read.Groups.Single.Id != 0 {
+		t.Fatalf("expected a deleted group to read as null, got %+v, %v", read, err)
+	}
+
+	if err := c.Mutate(ctx, &deleted, map[string]interface{}{"id": gqlc.Int(2)}); err == nil ||
+		!strings.Contains(err.Error(), "Cannot delete this group.") {
+		t.Fatalf("expected the Guests group not to be deleted, got %v", err)
+	}
+}
+
+func TestGroupMembership(t *testing.T) {
+	s := New(t)
+	u := s.AddUser(User{Email: "jane@example.com", Name: "Jane", IsActive: true})
+	document := `mutation ($groupId:Int!$userId:Int!){groups{assignUser(groupId: $groupId, userId: $userId){responseResult{succeeded}}}}`
+
+	_, out := post(t, s, document, map[string]interface{}{"groupId": 2, "userId": u.ID})
+	if out["errors"] != nil {
+		t.Fatalf("unexpected errors %v", out["errors"])
+	}
+	if g, _ := s.Group(2); len(g.UserIDs) != 2 || g.UserIDs[1] != u.ID {
+		t.Fatalf("expected the user to be a member, got %v", g.UserIDs)
+	}
+	for vars, message := range map[[2]int]string{
+		{2, u.ID}: "User is already assigned to group.",
+		{9, u.ID}: "Invalid Group ID",
+		{2, 99}:   "Invalid User ID",
+	} {
+		_, out := post(t, s, document, map[string]interface{}{"groupId": vars[0], "userId": vars[1]})
+		errs, _ := out["errors"].([]interface{})
+		if len(errs) != 1 || errs[0].(map[string]interface{})["message"] != message {
+			t.Errorf("%v: expected %q, got %v", vars, message, out)
+		}
+	}
+}
+
+func TestUsers(t *testing.T) {
+	s := New(t)
+	create := `mutation ($email:String!$name:String!$passwordRaw:String!$groups:[Int]!){users{create(email: $email, name: $name, passwordRaw: $passwordRaw, providerKey: "local", groups: $groups){responseResult{succeeded slug} user{id}}}}`
+	vars := map[string]interface{}{"email": "Jane@Example.com", "name": "Jane", "passwordRaw": "secret123", "groups": []int{2}}
+
+	_, out := post(t, s, create, vars)
+	result := out["data"].(map[string]interface{})["users"].(map[string]interface{})["create"].(map[string]interface{})
+	if result["responseResult"].(map[string]interface{})["succeeded"] != true || result["user"] != nil {
+		t.Fatalf("unexpected response %v", result)
+	}
+	u, ok := s.User(3)
+	if !ok || u.Email != "jane@example.com" {
+		t.Fatalf("unexpected user %+v", u)
+	}
+
+	_, out = post(t, s, create, vars)
+	result = out["data"].(map[string]interface{})["users"].(map[string]interface{})["create"].(map[string]interface{})
+	if result["responseResult"].(map[string]interface{})["slug"] != "AuthAccountAlreadyExists" {
+		t.Fatalf("expected a duplicate email to be rejected, got %v", result)
+	}
+
+	_, out = post(t, s, `{users{search(query: "jane"){id email} single(id: 3){groups{id}}}}`, nil)
+	users := out["data"].(map[string]interface{})["users"].(map[string]interface{})
+	if len(users["search"].([]interface{})) != 1 || len(users["single"].(map[string]interface{})["groups"].([]interface{})) != 1 {
+		t.Fatalf("unexpected users %v", users)
+	}
+
+	s.AddPage(Page{Path: "home", Content: "# Home", AuthorID: 3, CreatorID: 3})
+	del := `mutation ($id:Int!){users{delete(id: $id){responseResult{slug}}}}`
+	for id, slug := range map[int]string{1: "UserDeleteProtected", 3: "UserDeleteForeignConstraint", 42: "UserNotFound"} {
+		_, out := post(t, s, del, map[string]interface{}{"id": id})
+		got := out["data"].(map[string]interface{})["users"].(map[string]interface{})["delete"].(map[string]interface{})["responseResult"].(map[string]interface{})["slug"]
+		if got != slug {
+			t.Errorf("deleting user %d: expected %s, got %v", id, slug, got)
+		}
+	}
+	_, out = post(t, s, `mutation {users{delete(id: 3, replaceId: 1){responseResult{succeeded}}}}`, nil)
+	if _, ok := s.User(3); ok || out["errors"] != nil {
+		t.Fatalf("expected the user to be deleted, got %v", out)
+	}
+	if p, _ := s.Page(1); p.AuthorID != 1 || p.CreatorID != 1 {
+		t.Fatalf("expected the page to be reassigned, got %+v", p)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	s := New(t)
+	var site schema.SiteData
+	if err := client(s, "wrong").Query(context.Background(), &site, nil); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Fatalf("expected an unknown token to be forbidden, got %v", err)
+	}
+
+	var login schema.LoginData
+	vars := map[string]interface{}{"username": gqlc.String(AdminEmail), "password": gqlc.String(AdminPassword), "strategy": gqlc.String("local")}
+	if err := client(s, "").Mutate(context.Background(), &login, vars); err != nil {
+		t.Fatal(err)
+	}
+	if !login.Authentication.Login.ResponseResult.Succeeded || login.Authentication.Login.Jwt == "" {
+		t.Fatalf("unexpected login response %+v", login)
+	}
+	if err := client(s, string(login.Authentication.Login.Jwt)).Query(context.Background(), &site, nil); err != nil {
+		t.Fatal(err)
+	}
+	if string(site.Site.Config.Host) != s.URL {
+		t.Fatalf("expected the site host to be the server URL, got %q", site.Site.Config.Host)
+	}
+
+	vars["password"] = gqlc.String("wrong")
+	login = schema.LoginData{}
+	if err := client(s, "").Mutate(context.Background(), &login, vars); err != nil {
+		t.Fatal(err)
+	}
+	if login.Authentication.Login.ResponseResult.Slug != "AuthLoginFailed" {
+		t.Fatalf("expected the login to fail, got %+v", login)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := New(t)
+	c := client(s, Token)
+	ctx := context.Background()
+
+	s.InjectFault(Fault{Operation: "site", Status: http.StatusServiceUnavailable, Count: 1})
+	var site schema.SiteData
+	if err := c.Query(ctx, &site, nil); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected a 503, got %v", err)
+	}
+	if err := c.Query(ctx, &site, nil); err != nil {
+		t.Fatalf("expected the fault to apply once, got %v", err)
+	}
+
+	s.InjectFault(Forbidden("groups.update"))
+	_, out := post(t, s, `mutation {groups{create(name: "a"){responseResult{succeeded}} update(id: 1, name: "b", permissions: [], pageRules: []){responseResult{succeeded}}}}`, nil)
+	groups := out["data"].(map[string]interface{})["groups"].(map[string]interface{})
+	if groups["create"] == nil || groups["update"] != nil || len(out["errors"].([]interface{})) != 1 {
+		t.Fatalf("expected only the update to fail, got %v", out)
+	}
+	if g, _ := s.Group(1); g.Name != "Administrators" {
+		t.Fatalf("a forbidden update must not be applied, got %q", g.Name)
+	}
+	s.ClearFaults()
+
+	s.InjectFault(Fault{Operation: "groups.create", Status: http.StatusBadGateway, AfterApply: true, Count: 1})
+	if status, _ := post(t, s, `mutation {groups{create(name: "lost"){responseResult{succeeded}}}}`, nil); status != http.StatusBadGateway {
+		t.Fatalf("expected a 502, got %d", status)
+	}
+	if groups := s.Groups(); groups[len(groups)-1].Name != "lost" {
+		t.Fatal("expected the group to be created despite the lost response")
+	}
+
+	s.InjectFault(Fault{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := c.Query(ctx, &site, nil); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+	if n := s.RequestCount("site"); n != 3 {
+		t.Fatalf("expected 3 site requests, got %d", n)
+	}
+}
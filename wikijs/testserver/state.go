@@ -0,0 +1,349 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package testserver
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultVersion is the Wiki.js version reported by system.info unless changed with SetVersion.
+const DefaultVersion = "2.5.300"
+
+// Group is a Wiki.js group as stored by the server.
+type Group struct {
+	ID              int
+	Name            string
+	IsSystem        bool
+	RedirectOnLogin string
+	Permissions     []string
+	PageRules       []PageRule
+	// UserIDs are the members of the group, in ascending order.
+	UserIDs   []int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PageRule is a page rule of a group.
+type PageRule struct {
+	ID      string
+	Deny    bool
+	Match   string
+	Roles   []string
+	Path    string
+	Locales []string
+}
+
+// User is a Wiki.js user as stored by the server.
+type User struct {
+	ID          int
+	Email       string
+	Name        string
+	Password    string
+	ProviderKey string
+	IsSystem    bool
+	IsActive    bool
+	IsVerified  bool
+	Location    string
+	JobTitle    string
+	Timezone    string
+	DateFormat  string
+	Appearance  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	LastLoginAt *time.Time
+}
+
+// Page is a Wiki.js page as stored by the server.
+type Page struct {
+	ID          int
+	Path        string
+	Locale      string
+	Title       string
+	Description string
+	Content     string
+	Editor      string
+	IsPublished bool
+	IsPrivate   bool
+	Tags        []string
+	ScriptCSS   string
+	ScriptJS    string
+	AuthorID    int
+	CreatorID   int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Locale is a locale known to the server.
+type Locale struct {
+	Code        string
+	Name        string
+	NativeName  string
+	IsRTL       bool
+	IsInstalled bool
+}
+
+// NavigationTree is the navigation menu of a locale.
+type NavigationTree struct {
+	Locale string
+	Items  []NavigationItem
+}
+
+// NavigationItem is an entry of the navigation menu.
+type NavigationItem struct {
+	ID               string
+	Kind             string
+	Label            string
+	Icon             string
+	TargetType       string
+	Target           string
+	VisibilityMode   string
+	VisibilityGroups []int
+}
+
+// defaultPermissions and defaultPageRules are given to new groups, as Wiki.js does.
+var defaultPermissions = []string{"read:pages", "read:assets", "read:comments", "write:comments"}
+
+func defaultPageRules() []PageRule {
+	return []PageRule{{ID: "default", Match: "START", Roles: append([]string(nil), defaultPermissions...), Locales: []string{}}}
+}
+
+// state is the data held by a Server. Every access goes through Server.mu.
+type state struct {
+	version        string
+	site           map[string]interface{}
+	groups         map[int]*Group
+	users          map[int]*User
+	pages          map[int]*Page
+	locales        []Locale
+	navigation     []NavigationTree
+	navigationMode string
+	nextGroupID    int
+	nextUserID     int
+	nextPageID     int
+}
+
+// newState returns the data of a fresh Wiki.js installation: the Administrators and Guests groups, the
+// administrator and guest users, and the English locale.
+func newState(host string, now time.Time) *state {
+	s := &state{
+		version: DefaultVersion,
+		site: map[string]interface{}{
+			"host":                    host,
+			"title":                   "Wiki.js",
+			"description":             "",
+			"robots":                  []interface{}{"index", "follow"},
+			"analyticsService":        "",
+			"analyticsId":             "",
+			"company":                 "",
+			"contentLicense":          "",
+			"logoUrl":                 "https://static.requarks.io/logo/wikijs-butterfly.svg",
+			"authAutoLogin":           false,
+			"authEnforce2FA":          false,
+			"authHideLocal":           false,
+			"authLoginBgUrl":          "",
+			"authJwtAudience":         "urn:wiki.js",
+			"authJwtExpiration":       "30m",
+			"authJwtRenewablePeriod":  "14d",
+			"featurePageRatings":      true,
+			"featurePageComments":     true,
+			"featurePersonalWikis":    true,
+			"securityOpenRedirect":    true,
+			"securityIframe":          true,
+			"securityReferrerPolicy":  true,
+			"securityTrustXForwarded": false,
+			"securitySRI":             true,
+			"securityHSTS":            false,
+			"securityHSTSDuration":    300,
+			"securityCSP":             false,
+			"securityCSPDirectives":   "",
+			"uploadMaxFileSize":       5242880,
+			"uploadMaxFiles":          10,
+			"uploadScanSVG":           true,
+			"uploadForceDownload":     true,
+		},
+		groups:         map[int]*Group{},
+		users:          map[int]*User{},
+		pages:          map[int]*Page{},
+		locales:        []Locale{{Code: "en", Name: "English", NativeName: "English", IsInstalled: true}},
+		navigationMode: "TREE",
+		navigation:     []NavigationTree{{Locale: "en", Items: []NavigationItem{}}},
+		nextGroupID:    1,
+		nextUserID:     1,
+		nextPageID:     1,
+	}
+	s.addGroup(Group{
+		Name:        "Administrators",
+		IsSystem:    true,
+		Permissions: []string{"manage:system"},
+		PageRules: []PageRule{{ID: "default", Match: "START", Locales: []string{}, Roles: []string{
+			"read:pages", "read:assets", "read:comments", "write:comments",
+		}}},
+	}, now)
+	s.addGroup(Group{Name: "Guests", IsSystem: true, Permissions: defaultPermissions, PageRules: defaultPageRules()}, now)
+	s.addUser(User{Email: AdminEmail, Name: "Administrator", Password: AdminPassword, IsActive: true, IsVerified: true}, now)
+	s.addUser(User{Email: "guest@example.com", Name: "Guest", IsSystem: true, IsActive: true, IsVerified: true}, now)
+	s.groups[1].UserIDs = []int{1}
+	s.groups[2].UserIDs = []int{2}
+	return s
+}
+
+func (s *state) addGroup(g Group, now time.Time) *Group {
+	g = copyGroup(g)
+	g.ID = s.nextGroupID
+	s.nextGroupID++
+	if g.RedirectOnLogin == "" {
+		g.RedirectOnLogin = "/"
+	}
+	if g.Permissions == nil {
+		g.Permissions = []string{}
+	}
+	if g.PageRules == nil {
+		g.PageRules = []PageRule{}
+	}
+	if g.UserIDs == nil {
+		g.UserIDs = []int{}
+	}
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = now
+	}
+	if g.UpdatedAt.IsZero() {
+		g.UpdatedAt = g.CreatedAt
+	}
+	s.groups[g.ID] = &g
+	return &g
+}
+
+func (s *state) addUser(u User, now time.Time) *User {
+	u.ID = s.nextUserID
+	s.nextUserID++
+	if u.ProviderKey == "" {
+		u.ProviderKey = "local"
+	}
+	if u.Timezone == "" {
+		u.Timezone = "America/New_York"
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = now
+	}
+	if u.UpdatedAt.IsZero() {
+		u.UpdatedAt = u.CreatedAt
+	}
+	s.users[u.ID] = &u
+	return &u
+}
+
+func (s *state) addPage(p Page, now time.Time) *Page {
+	p = copyPage(p)
+	p.ID = s.nextPageID
+	s.nextPageID++
+	if p.Locale == "" {
+		p.Locale = "en"
+	}
+	if p.Editor == "" {
+		p.Editor = "markdown"
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = p.CreatedAt
+	}
+	s.pages[p.ID] = &p
+	return &p
+}
+
+// userGroups returns the groups userID belongs to, ordered by id.
+func (s *state) userGroups(userID int) []*Group {
+	var groups []*Group
+	for _, g := range s.sortedGroups() {
+		if containsInt(g.UserIDs, userID) {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+func (s *state) sortedGroups() []*Group {
+	groups := make([]*Group, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups
+}
+
+func (s *state) sortedUsers() []*User {
+	users := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (s *state) sortedPages() []*Page {
+	pages := make([]*Page, 0, len(s.pages))
+	for _, p := range s.pages {
+		pages = append(pages, p)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].ID < pages[j].ID })
+	return pages
+}
+
+func copyGroup(g Group) Group {
+	g.Permissions = append([]string(nil), g.Permissions...)
+	g.UserIDs = append([]int(nil), g.UserIDs...)
+	rules := make([]PageRule, len(g.PageRules))
+	for i, r := range g.PageRules {
+		r.Roles = append([]string(nil), r.Roles...)
+		r.Locales = append([]string(nil), r.Locales...)
+		rules[i] = r
+	}
+	if g.PageRules != nil {
+		g.PageRules = rules
+	}
+	return g
+}
+
+func copyPage(p Page) Page {
+	p.Tags = append([]string(nil), p.Tags...)
+	return p
+}
+
+func copyNavigation(trees []NavigationTree) []NavigationTree {
+	out := make([]NavigationTree, len(trees))
+	for i, tree := range trees {
+		items := make([]NavigationItem, len(tree.Items))
+		for j, item := range tree.Items {
+			item.VisibilityGroups = append([]int(nil), item.VisibilityGroups...)
+			items[j] = item
+		}
+		out[i] = NavigationTree{Locale: tree.Locale, Items: items}
+	}
+	return out
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func removeInt(values []int, v int) []int {
+	out := values[:0]
+	for _, value := range values {
+		if value != v {
+			out = append(out, value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// TransportConfig holds the TLS, proxy and header settings of the connection to Wiki.js.
+type TransportConfig struct {
+	// CACertPEM and CACertFile add PEM encoded certificate authorities to the system pool.
+	CACertPEM  string
+	CACertFile string
+	// ClientCert and ClientKey are a PEM encoded certificate and key presented to Wiki.js, for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipVerify disables the verification of the Wiki.js certificate.
+	InsecureSkipVerify bool
+	// ProxyURL overrides the proxy otherwise taken from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables.
+	ProxyURL string
+	// Headers are added to every request, except where the provider sets the header itself.
+	Headers map[string]string
+}
+
+// newBaseTransport builds the transport that carries requests to Wiki.js once they are authenticated.
+func newBaseTransport(config TransportConfig) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_url %q: %w", config.ProxyURL, err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy_url %q: expected a URL such as http://proxy.example.com:3128", config.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if len(config.Headers) == 0 {
+		return transport, nil
+	}
+	return &headerTransport{base: transport, headers: config.Headers}, nil
+}
+
+func (config TransportConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Opting out of verification is an explicit choice of the provider configuration.
+		InsecureSkipVerify: config.InsecureSkipVerify, // #nosec G402
+	}
+
+	if config.CACertPEM != "" || config.CACertFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if config.CACertPEM != "" && !pool.AppendCertsFromPEM([]byte(config.CACertPEM)) {
+			return nil, fmt.Errorf("ca_cert_pem does not contain any PEM encoded certificate")
+		}
+		if config.CACertFile != "" {
+			pem, err := ioutil.ReadFile(config.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ca_cert_file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("ca_cert_file %s does not contain any PEM encoded certificate", config.CACertFile)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.X509KeyPair([]byte(config.ClientCert), []byte(config.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client_cert or client_key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// headerTransport adds static headers, such as those required by an SSO gateway, to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	for name, value := range t.headers {
+		// Never replace the Authorization or Content-Type headers set by the provider.
+		if r.Header.Get(name) == "" {
+			r.Header.Set(name, value)
+		}
+	}
+	return t.base.RoundTrip(r)
+}
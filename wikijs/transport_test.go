@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const siteResponse = `{"data":{"site":{"config":{"host":"h","title":"t","description":""}}}}`
+
+func siteHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(siteResponse))
+}
+
+func certificatePEM(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func TestTransportPrivateCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(siteHandler))
+	defer srv.Close()
+	caPEM := certificatePEM(srv.Certificate())
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(caFile, []byte(caPEM), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	untrusted := testClient(Config{Host: srv.URL, Token: "token"})
+	if _, err := untrusted.GetSite(context.Background()); err == nil {
+		t.Fatal("expected the self-signed certificate to be rejected")
+	}
+
+	for name, transport := range map[string]TransportConfig{
+		"ca_cert_pem":          {CACertPEM: caPEM},
+		"ca_cert_file":         {CACertFile: caFile},
+		"insecure_skip_verify": {InsecureSkipVerify: true},
+	} {
+		c := testClient(Config{Host: srv.URL, Token: "token", Transport: transport})
+		if _, err := c.GetSite(context.Background()); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestTransportClientCertificate(t *testing.T) {
+	certPEM, keyPEM, cert := selfSignedClientCertificate(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(siteHandler))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+	caPEM := certificatePEM(srv.Certificate())
+
+	without := testClient(Config{Host: srv.URL, Token: "token", Transport: TransportConfig{CACertPEM: caPEM}})
+	if _, err := without.GetSite(context.Background()); err == nil {
+		t.Fatal("expected the server to require a client certificate")
+	}
+
+	with := testClient(Config{Host: srv.URL, Token: "token", Transport: TransportConfig{
+		CACertPEM:  caPEM,
+		ClientCert: certPEM,
+		ClientKey:  keyPEM,
+	}})
+	if _, err := with.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransportProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		siteHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	c := testClient(Config{Host: "http://wiki.example.invalid", Token: "token", Transport: TransportConfig{ProxyURL: proxy.URL}})
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://wiki.example.invalid/graphql" {
+		t.Fatalf("expected the request to go through the proxy, got %q", proxied)
+	}
+}
+
+func TestTransportHeaders(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		siteHandler(w, r)
+	}))
+	defer srv.Close()
+
+	c := testClient(Config{Host: srv.URL, Token: "token", Transport: TransportConfig{Headers: map[string]string{
+		"X-Gateway-Key": "gateway",
+		"Authorization": "Basic overridden",
+	}}})
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Gateway-Key") != "gateway" {
+		t.Errorf("expected the static header to be sent, got %v", header)
+	}
+	if header.Get("Authorization") != "Bearer token" {
+		t.Errorf("static headers must not replace the provider's Authorization header, got %q", header.Get("Authorization"))
+	}
+}
+
+func TestTransportRequestTimeout(t *testing.T) {
+	srv := hangingServer(t)
+	c := testClient(Config{Host: srv.URL, Token: "token", RequestTimeout: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := c.GetSite(context.Background()); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("request_timeout was not applied, request took %s", elapsed)
+	}
+}
+
+func TestTransportInvalidConfig(t *testing.T) {
+	for name, transport := range map[string]TransportConfig{
+		"ca_cert_pem":     {CACertPEM: "not a certificate"},
+		"ca_cert_file":    {CACertFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"client_key only": {ClientKey: "key"},
+		"client_cert":     {ClientCert: "cert", ClientKey: "key"},
+		"proxy_url":       {ProxyURL: "proxy.example.com"},
+	} {
+		c := testClient(Config{Host: "https://wiki.example.com", Token: "token", Transport: transport})
+		if _, err := c.GetSite(context.Background()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func selfSignedClientCertificate(t *testing.T) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "terraform"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certificatePEM(cert), keyPEM, cert
+}
@@ -21,3 +21,23 @@ func stringArrayToGqlcStringArray(in []string) []gqlc.String {
 	}
 	return o
 }
+
+func expandStringMap(in map[string]interface{}) map[string]string {
+	o := make(map[string]string, len(in))
+	for k, v := range in {
+		o[k] = v.(string)
+	}
+	return o
+}
+
+// gqlcString returns a nullable String variable set to s.
+func gqlcString(s string) *gqlc.String {
+	v := gqlc.String(s)
+	return &v
+}
+
+// gqlcBool returns a nullable Boolean variable set to b.
+func gqlcBool(b bool) *gqlc.Boolean {
+	v := gqlc.Boolean(b)
+	return &v
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strconv"
+	"strings"
+)
+
+// Version is a Wiki.js release, e.g. 2.5.300.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses the version reported by Wiki.js. A leading "v", a missing patch number and pre-release or
+// build suffixes such as "-beta.1" are accepted.
+func ParseVersion(s string) (Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	parts := strings.Split(trimmed, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid Wiki.js version %q", s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid Wiki.js version %q", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is the same release as minimum or a later one.
+func (v Version) AtLeast(minimum Version) bool {
+	if v.Major != minimum.Major {
+		return v.Major > minimum.Major
+	}
+	if v.Minor != minimum.Minor {
+		return v.Minor > minimum.Minor
+	}
+	return v.Patch >= minimum.Patch
+}
+
+// minVersionPageRuleTag is the first release matching page rules against page tags.
+var minVersionPageRuleTag = Version{Major: 2, Minor: 5}
+
+// versionRequirement is an argument, or a value of an argument, that Wiki.js only supports from a given version.
+type versionRequirement struct {
+	// feature describes what needs the version, e.g. "page_rules with match TAG".
+	feature string
+	minimum Version
+	// used reports whether the planned configuration relies on the feature.
+	used func(d *schema.ResourceDiff) bool
+}
+
+// checkVersionRequirements returns a CustomizeDiffFunc failing the plan when the configuration uses a feature
+// the Wiki.js server is too old for. The version is only queried when a feature is used, and the check is
+// skipped when it cannot be detected, e.g. because the credentials are not allowed to read it: Wiki.js then
+// reports the problem itself when the change is applied.
+func checkVersionRequirements(requirements ...versionRequirement) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		var used []versionRequirement
+		for _, requirement := range requirements {
+			if requirement.used(d) {
+				used = append(used, requirement)
+			}
+		}
+		c, ok := meta.(*Client)
+		if len(used) == 0 || !ok {
+			return nil
+		}
+		version, err := c.ServerVersion(ctx)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Skipping the Wiki.js version check: %s", err))
+			return nil
+		}
+		var problems []string
+		for _, requirement := range used {
+			if !version.AtLeast(requirement.minimum) {
+				problems = append(problems, fmt.Sprintf("%s requires Wiki.js %s or later, but %s runs Wiki.js %s. "+
+					"Upgrade Wiki.js or remove it from the configuration.", requirement.feature, requirement.minimum, c.Host, version))
+			}
+		}
+		return errorList(problems)
+	}
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2022 2022 Marshall Wace <opensource@mwam.com>
+//
+// SPDX-License-Identifier: GPL3
+
+package wikijs
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-provider-wikijs/wikijs/testserver"
+)
+
+func TestParseVersion(t *testing.T) {
+	for input, expected := range map[string]Version{
+		"2.5.300":        {2, 5, 300},
+		"v2.5.170":       {2, 5, 170},
+		"2.4":            {2, 4, 0},
+		"2.5.0-beta.1":   {2, 5, 0},
+		" 2.3.81+build ": {2, 3, 81},
+	} {
+		got, err := ParseVersion(input)
+		if err != nil || got != expected {
+			t.Errorf("%q: expected %s, got %s (%v)", input, expected, got, err)
+		}
+	}
+	for _, input := range []string{"", "2", "2.x.1", "2.5.1.4", "-1.0.0", "dev"} {
+		if _, err := ParseVersion(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	minimum := Version{2, 5, 0}
+	for v, expected := range map[Version]bool{
+		{2, 5, 0}:   true,
+		{2, 5, 300}: true,
+		{3, 0, 0}:   true,
+		{2, 4, 999}: false,
+		{1, 9, 0}:   false,
+	} {
+		if got := v.AtLeast(minimum); got != expected {
+			t.Errorf("%s.AtLeast(%s): expected %t", v, minimum, expected)
+		}
+	}
+}
+
+func TestClientServerVersion(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.SetVersion("2.4.107")
+
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if c.Version == nil || *c.Version != (Version{2, 4, 107}) {
+		t.Fatalf("expected the version to be detected on the first request, got %v", c.Version)
+	}
+	if _, err := c.ServerVersion(context.Background()); err != nil || srv.RequestCount("system.info") != 1 {
+		t.Fatalf("expected the detected version to be reused, got %v after %d queries", err, srv.RequestCount("system.info"))
+	}
+}
+
+func TestClientServerVersionForbidden(t *testing.T) {
+	srv, c := testServerClient(t)
+	srv.InjectFault(testserver.Forbidden("system.info"))
+
+	if _, err := c.GetSite(context.Background()); err != nil {
+		t.Fatalf("expected requests to succeed without the version, got %v", err)
+	}
+	if version, err := c.ServerVersion(context.Background()); version != nil || err == nil {
+		t.Fatalf("expected the version to be unknown, got %v", version)
+	}
+}
+
+func TestResourceGroupVersionRequirements(t *testing.T) {
+	config := func(match string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":              "tagged",
+			"permissions":       []interface{}{"read:pages"},
+			"redirect_on_login": "/",
+			"page_rules": []interface{}{map[string]interface{}{
+				"id":      "rule",
+				"deny":    false,
+				"match":   match,
+				"roles":   []interface{}{"read:pages"},
+				"path":    "docs",
+				"locales": []interface{}{},
+			}},
+		}
+	}
+
+	srv, c := testServerClient(t)
+	srv.SetVersion("2.4.107")
+	if err := testResourceDiff(t, resourceGroup(), config("START"), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := srv.RequestCount("system.info"); n != 0 {
+		t.Fatalf("expected no version query without a versioned feature, got %d", n)
+	}
+	err := testResourceDiff(t, resourceGroup(), config("TAG"), c)
+	if err == nil || !strings.Contains(err.Error(), "page_rules with match TAG requires Wiki.js 2.5.0 or later") {
+		t.Fatalf("expected a version error, got %v", err)
+	}
+
+	srv, c = testServerClient(t)
+	if err := testResourceDiff(t, resourceGroup(), config("TAG"), c); err != nil {
+		t.Fatalf("unexpected error with Wiki.js %s: %v", testserver.DefaultVersion, err)
+	}
+
+	srv, c = testServerClient(t)
+	srv.SetVersion("2.4.107")
+	srv.InjectFault(testserver.Forbidden("system.info"))
+	if err := testResourceDiff(t, resourceGroup(), config("TAG"), c); err != nil {
+		t.Fatalf("expected the check to be skipped when the version is unknown, got %v", err)
+	}
+}